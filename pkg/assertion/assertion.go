@@ -0,0 +1,168 @@
+package assertion
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/hbagdi/hit/pkg/model"
+	"github.com/hbagdi/hit/pkg/parser"
+	"github.com/tidwall/gjson"
+)
+
+// Result is the outcome of checking an assertion.
+type Result struct {
+	// Assertion describes the assertion such as 'status == 200'.
+	Assertion string
+	Passed    bool
+	// Message explains why the assertion failed.
+	Message string
+}
+
+// Validate returns an error if assertions can't be checked, such as an
+// invalid regular expression.
+func Validate(assertions parser.Assertions) error {
+	for _, a := range assertions.Body {
+		if a.Path == "" {
+			return fmt.Errorf("body assertion requires 'path'")
+		}
+		if a.Equals == nil && a.Matches == "" && a.Exists == nil {
+			return fmt.Errorf("body assertion of '%v' requires 'equals', "+
+				"'matches' or 'exists'", a.Path)
+		}
+		if a.Matches != "" {
+			if _, err := regexp.Compile(a.Matches); err != nil {
+				return fmt.Errorf("invalid regular expression of '%v': %v",
+					a.Path, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Check checks assertions against hit. Assertions are checked in the order
+// status, headers, body and latency.
+func Check(assertions parser.Assertions, hit model.Hit) []Result {
+	var res []Result
+	if assertions.Status != nil {
+		res = append(res, check(fmt.Sprintf("status == %d", *assertions.Status),
+			hit.Response.Code == *assertions.Status,
+			"got %d", hit.Response.Code))
+	}
+
+	names := make([]string, 0, len(assertions.Headers))
+	for name := range assertions.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		expected := assertions.Headers[name]
+		actual := hit.Response.Header.Get(name)
+		res = append(res, check(fmt.Sprintf("header %v == %q", name, expected),
+			actual == expected, "got %q", actual))
+	}
+
+	if len(assertions.Body) > 0 {
+		var body interface{}
+		if err := json.Unmarshal(hit.Response.Body, &body); err != nil {
+			for _, a := range assertions.Body {
+				res = append(res, Result{
+					Assertion: bodyAssertion(a),
+					Message:   "response body is not JSON",
+				})
+			}
+		} else {
+			for _, a := range assertions.Body {
+				res = append(res, checkBody(a, hit.Response.Body))
+			}
+		}
+	}
+
+	if assertions.MaxLatency != nil {
+		maxLatency := time.Duration(*assertions.MaxLatency)
+		res = append(res, check(fmt.Sprintf("latency < %v", maxLatency),
+			hit.Latency.Total < maxLatency, "took %v",
+			hit.Latency.Total.Round(time.Millisecond)))
+	}
+	return res
+}
+
+// Failed returns the number of results which did not pass.
+func Failed(results []Result) int {
+	n := 0
+	for _, r := range results {
+		if !r.Passed {
+			n++
+		}
+	}
+	return n
+}
+
+func check(assertion string, passed bool, format string, a ...interface{}) Result {
+	res := Result{Assertion: assertion, Passed: passed}
+	if !passed {
+		res.Message = fmt.Sprintf(format, a...)
+	}
+	return res
+}
+
+// bodyAssertion describes a.
+func bodyAssertion(a parser.BodyAssertion) string {
+	switch {
+	case a.Equals != nil:
+		return fmt.Sprintf("body %v == %s", a.Path, a.Equals)
+	case a.Matches != "":
+		return fmt.Sprintf("body %v matches %v", a.Path, strconv.Quote(a.Matches))
+	case a.Exists != nil && !*a.Exists:
+		return fmt.Sprintf("body %v does not exist", a.Path)
+	default:
+		return fmt.Sprintf("body %v exists", a.Path)
+	}
+}
+
+// checkBody checks a against the JSON body.
+func checkBody(a parser.BodyAssertion, body []byte) Result {
+	res := Result{Assertion: bodyAssertion(a)}
+	value := gjson.GetBytes(body, a.Path)
+	if err := Validate(parser.Assertions{Body: []parser.BodyAssertion{a}}); err != nil {
+		res.Message = err.Error()
+		return res
+	}
+	switch {
+	case a.Exists != nil && *a.Exists != value.Exists():
+		if value.Exists() {
+			res.Message = "got " + value.Raw
+		} else {
+			res.Message = "not found"
+		}
+		return res
+	case a.Exists != nil && !*a.Exists:
+		res.Passed = true
+		return res
+	case !value.Exists():
+		res.Message = "not found"
+		return res
+	}
+	if a.Equals != nil {
+		var expected, actual interface{}
+		if err := json.Unmarshal(a.Equals, &expected); err != nil {
+			res.Message = fmt.Sprintf("invalid expected value: %v", err)
+			return res
+		}
+		if err := json.Unmarshal([]byte(value.Raw), &actual); err != nil ||
+			!reflect.DeepEqual(expected, actual) {
+			res.Message = "got " + value.Raw
+			return res
+		}
+	}
+	if a.Matches != "" && !regexp.MustCompile(a.Matches).MatchString(value.String()) {
+		res.Message = "got " + value.Raw
+		return res
+	}
+	res.Passed = true
+	return res
+}
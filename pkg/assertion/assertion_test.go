@@ -0,0 +1,85 @@
+package assertion
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hbagdi/hit/pkg/model"
+	"github.com/hbagdi/hit/pkg/parser"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheck(t *testing.T) {
+	hit := model.Hit{
+		Response: model.Response{
+			Code:   200,
+			Header: http.Header{"Content-Type": {"application/json"}},
+			Body: []byte(`{"id": 42, "name": "node-1", ` +
+				`"tags": [{"name": "x"}], "deleted": null}`),
+		},
+		Latency: model.Latency{Total: 120 * time.Millisecond},
+	}
+	status := 200
+	wrongStatus := 201
+	yes, no := true, false
+	maxLatency := parser.Duration(100 * time.Millisecond)
+	results := Check(parser.Assertions{
+		Status: &status,
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+			"X-Request-Id": "1",
+		},
+		Body: []parser.BodyAssertion{
+			{Path: "id", Equals: []byte("42")},
+			{Path: "tags.0", Equals: []byte(`{"name":"x"}`)},
+			{Path: "name", Matches: "^node-"},
+			{Path: "name", Equals: []byte(`"node-2"`)},
+			{Path: "deleted", Exists: &yes},
+			{Path: "missing", Exists: &no},
+			{Path: "missing", Matches: "."},
+			{Path: "id", Exists: &no},
+		},
+		MaxLatency: &maxLatency,
+	}, hit)
+	require.Equal(t, []Result{
+		{Assertion: "status == 200", Passed: true},
+		{Assertion: `header Content-Type == "application/json"`, Passed: true},
+		{Assertion: `header X-Request-Id == "1"`, Message: `got ""`},
+		{Assertion: "body id == 42", Passed: true},
+		{Assertion: `body tags.0 == {"name":"x"}`, Passed: true},
+		{Assertion: `body name matches "^node-"`, Passed: true},
+		{Assertion: `body name == "node-2"`, Message: `got "node-1"`},
+		{Assertion: "body deleted exists", Passed: true},
+		{Assertion: "body missing does not exist", Passed: true},
+		{Assertion: `body missing matches "."`, Message: "not found"},
+		{Assertion: "body id does not exist", Message: "got 42"},
+		{Assertion: "latency < 100ms", Message: "took 120ms"},
+	}, results)
+	require.Equal(t, 5, Failed(results))
+
+	results = Check(parser.Assertions{
+		Status: &wrongStatus,
+		Body:   []parser.BodyAssertion{{Path: "id", Exists: &yes}},
+	}, model.Hit{Response: model.Response{Code: 200, Body: []byte("ok")}})
+	require.Equal(t, []Result{
+		{Assertion: "status == 201", Message: "got 200"},
+		{Assertion: "body id exists", Message: "response body is not JSON"},
+	}, results)
+}
+
+func TestValidate(t *testing.T) {
+	require.NoError(t, Validate(parser.Assertions{
+		Body: []parser.BodyAssertion{{Path: "id", Matches: "^[0-9]+$"}},
+	}))
+	require.EqualError(t, Validate(parser.Assertions{
+		Body: []parser.BodyAssertion{{Path: "id", Matches: "("}},
+	}), "invalid regular expression of 'id': error parsing regexp: "+
+		"missing closing ): `(`")
+	require.EqualError(t, Validate(parser.Assertions{
+		Body: []parser.BodyAssertion{{Path: "id"}},
+	}), "body assertion of 'id' requires 'equals', 'matches' or 'exists'")
+	require.EqualError(t, Validate(parser.Assertions{
+		Body: []parser.BodyAssertion{{Exists: new(bool)}},
+	}), "body assertion requires 'path'")
+}
@@ -4,6 +4,21 @@ import "github.com/hbagdi/hit/pkg/model"
 
 type Cache interface {
 	Get(key string) (interface{}, error)
+	// Hit returns the latest hit of request id, false if there is none.
+	Hit(id string) (model.Hit, bool, error)
 	Save(hit model.Hit) error
+	// Token returns the OAuth2 token cached under key, false if there is
+	// none.
+	Token(key string) (model.Token, bool, error)
+	SaveToken(key string, token model.Token) error
+	// Cookies returns the cookies of the cookie jar of scope.
+	Cookies(scope model.Scope) ([]model.Cookie, error)
+	// SaveCookie stores cookie in the cookie jar of scope, DeleteCookie
+	// removes it.
+	SaveCookie(scope model.Scope, cookie model.Cookie) error
+	DeleteCookie(scope model.Scope, cookie model.Cookie) error
+	// Variables returns the variables captured in scope by name.
+	Variables(scope model.Scope) (map[string]interface{}, error)
+	SaveVariable(scope model.Scope, name string, value interface{}) error
 	Flush() error
 }
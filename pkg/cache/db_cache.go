@@ -2,6 +2,8 @@ package cache
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
 
@@ -29,10 +31,14 @@ func (c *DBCache) Get(key string) (interface{}, error) {
 	}
 	id := splits[0]
 	path := splits[1]
-	hit, err := c.store.LoadLatestHitForID(context.Background(), id)
+	hit, ok, err := c.Hit(id)
 	if err != nil {
 		return nil, err
 	}
+	if !ok {
+		return nil, fmt.Errorf("no response of '@%v' to resolve '@%v': "+
+			"execute '@%v' first", id, key, id)
+	}
 	js := gjson.ParseBytes(hit.Response.Body)
 	res := js.Get(path)
 	switch res.Type {
@@ -54,10 +60,49 @@ func (c *DBCache) Get(key string) (interface{}, error) {
 	}
 }
 
+func (c *DBCache) Hit(id string) (model.Hit, bool, error) {
+	hit, err := c.store.LoadLatestHitForID(context.Background(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.Hit{}, false, nil
+	}
+	if err != nil {
+		return model.Hit{}, false, err
+	}
+	return hit, true, nil
+}
+
 func (c *DBCache) Save(hit model.Hit) error {
 	return c.store.Save(context.Background(), hit)
 }
 
+func (c *DBCache) Token(key string) (model.Token, bool, error) {
+	return c.store.LoadToken(context.Background(), key)
+}
+
+func (c *DBCache) SaveToken(key string, token model.Token) error {
+	return c.store.SaveToken(context.Background(), key, token)
+}
+
+func (c *DBCache) Cookies(scope model.Scope) ([]model.Cookie, error) {
+	return c.store.LoadCookies(context.Background(), scope)
+}
+
+func (c *DBCache) SaveCookie(scope model.Scope, cookie model.Cookie) error {
+	return c.store.SaveCookie(context.Background(), scope, cookie)
+}
+
+func (c *DBCache) DeleteCookie(scope model.Scope, cookie model.Cookie) error {
+	return c.store.DeleteCookie(context.Background(), scope, cookie)
+}
+
+func (c *DBCache) Variables(scope model.Scope) (map[string]interface{}, error) {
+	return c.store.LoadVariables(context.Background(), scope)
+}
+
+func (c *DBCache) SaveVariable(scope model.Scope, name string, value interface{}) error {
+	return c.store.SaveVariable(context.Background(), scope, name, value)
+}
+
 func (c *DBCache) Flush() error {
 	return nil
 }
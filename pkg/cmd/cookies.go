@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/hbagdi/hit/pkg/db"
+	"github.com/hbagdi/hit/pkg/executor"
+	"github.com/hbagdi/hit/pkg/log"
+)
+
+// executeCookies lists or clears the cookie jar of the working directory.
+func executeCookies(ctx context.Context, args []string) (err error) {
+	if len(args) == 0 {
+		return fmt.Errorf("need a cookies command: 'list' or 'clear'")
+	}
+	command := args[0]
+	fs := flag.NewFlagSet("cookies", flag.ContinueOnError)
+	environment := fs.String("env", os.Getenv("HIT_ENV"),
+		"environment of the cookie jar")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected argument '%v'", fs.Arg(0))
+	}
+	scope, err := executor.Scope(*environment)
+	if err != nil {
+		return err
+	}
+
+	store, err := db.NewStore(ctx, db.StoreOpts{Logger: log.Logger})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err := store.Close()
+		if err != nil {
+			log.Logger.Sugar().Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	switch command {
+	case "list":
+		cookies, err := store.LoadCookies(ctx, scope)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0) //nolint:gomnd
+		fmt.Fprintln(w, "DOMAIN\tPATH\tNAME\tVALUE\tEXPIRES")
+		now := time.Now()
+		for _, cookie := range cookies {
+			expires := "session"
+			if !cookie.Expires.IsZero() {
+				if !cookie.Expires.After(now) {
+					continue
+				}
+				expires = cookie.Expires.Local().Format(time.RFC3339)
+			}
+			domain := cookie.Domain
+			if !cookie.HostOnly {
+				domain = "." + domain
+			}
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\n", domain, cookie.Path,
+				cookie.Name, cookie.Value, expires)
+		}
+		return w.Flush()
+	case "clear":
+		n, err := store.ClearCookies(ctx, scope)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("cleared %d cookie(s)\n", n)
+		return nil
+	default:
+		return fmt.Errorf("invalid cookies command '%v': expected 'list' "+
+			"or 'clear'", command)
+	}
+}
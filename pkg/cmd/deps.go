@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/hbagdi/hit/pkg/cache"
+	"github.com/hbagdi/hit/pkg/db"
+	executorPkg "github.com/hbagdi/hit/pkg/executor"
+	"github.com/hbagdi/hit/pkg/log"
+)
+
+// executeDeps prints the dependency graph of a request as a tree. Each
+// dependency is annotated with the reference it is inferred from and
+// whether it is executed before the request.
+func executeDeps(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("deps", flag.ContinueOnError)
+	environment := fs.String("env", os.Getenv("HIT_ENV"),
+		"environment of the @_global section to use")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("need a single request to print the dependencies of")
+	}
+	if !strings.HasPrefix(fs.Arg(0), "@") {
+		return fmt.Errorf("request must begin with '@' character")
+	}
+	id := fs.Arg(0)[1:]
+
+	store, err := db.NewStore(ctx, db.StoreOpts{Logger: log.Logger})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err := store.Close()
+		if err != nil {
+			log.Logger.Sugar().Errorf("failed to close store: %v", err)
+		}
+	}()
+	executor, err := executorPkg.NewExecutor(&executorPkg.Opts{
+		Cache:       cache.GetDBCache(store),
+		Environment: *environment,
+	})
+	if err != nil {
+		return fmt.Errorf("initialize executor: %v", err)
+	}
+	defer executor.Close()
+	if err := executor.LoadFiles(); err != nil {
+		return fmt.Errorf("read hit files: %v", err)
+	}
+
+	// cycles are reported before printing as the tree would be infinite
+	if _, err := executor.DependencyOrder(id); err != nil {
+		return err
+	}
+	fmt.Printf("@%v\n", id)
+	return printDependencies(os.Stdout, executor, id, 1)
+}
+
+func printDependencies(w io.Writer, executor *executorPkg.Executor, id string,
+	depth int,
+) error {
+	deps, err := executor.Dependencies(id)
+	if err != nil {
+		return err
+	}
+	for _, d := range deps {
+		reference := d.Reference
+		if reference == "" {
+			reference = "dependsOn"
+		}
+		ok, err := executor.Satisfied(d)
+		if err != nil {
+			return err
+		}
+		state := "missing"
+		if ok {
+			state = "cached"
+		}
+		fmt.Fprintf(w, "%v@%v (%v, %v)\n", strings.Repeat("  ", depth), d.ID,
+			reference, state)
+		if err := printDependencies(w, executor, d.ID, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	executorPkg "github.com/hbagdi/hit/pkg/executor"
+	"github.com/hbagdi/hit/pkg/parser"
+	"github.com/hbagdi/hit/pkg/report"
+)
+
+// executeEach executes request id once per item of source with up to
+// concurrency items at once. Each item is bound to the positional
+// arguments from '@1' onwards as values, never references, followed by
+// args. The outcome of each item
+// is printed as it completes, followed by a summary.
+func executeEach(ctx context.Context, executor *executorPkg.Executor, id string,
+	args []string, source string, concurrency int,
+) error {
+	items, err := executor.Items(source)
+	if err != nil {
+		return err
+	}
+
+	var (
+		mu    sync.Mutex
+		cases = make([]*report.Case, len(items))
+		wg    sync.WaitGroup
+		next  = make(chan int)
+	)
+	for n := 0; n < concurrency; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				step := parser.Step{
+					ID:       id,
+					Args:     append(append([]string{}, items[i]...), args...),
+					Literals: len(items[i]),
+				}
+				c, _ := executeStep(ctx, executor, step)
+				mu.Lock()
+				cases[i] = &c
+				printTestCase(os.Stdout, c)
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := range items {
+		if ctx.Err() != nil {
+			break
+		}
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return printEachSummary(os.Stdout, cases)
+}
+
+// printEachSummary prints the number of items passed, failed and skipped.
+// Items without a case were skipped. It returns an error if an item failed.
+func printEachSummary(w io.Writer, cases []*report.Case) error {
+	passed, failed, skipped := 0, 0, 0
+	for _, c := range cases {
+		switch {
+		case c == nil:
+			skipped++
+		case c.Passed():
+			passed++
+		default:
+			failed++
+		}
+	}
+	fmt.Fprintf(w, "\n%d passed, %d failed, %d skipped\n", passed, failed, skipped)
+	if failed > 0 {
+		return fmt.Errorf("each: %d of %d item(s) failed", failed, len(cases))
+	}
+	if skipped > 0 {
+		return fmt.Errorf("each: interrupted, %d item(s) skipped", skipped)
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hbagdi/hit/pkg/parser"
+)
+
+// optionalBool is a boolean flag that is nil unless set.
+type optionalBool struct {
+	v **bool
+}
+
+func (b optionalBool) String() string {
+	if b.v == nil || *b.v == nil {
+		return ""
+	}
+	return strconv.FormatBool(**b.v)
+}
+
+func (b optionalBool) Set(s string) error {
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	*b.v = &v
+	return nil
+}
+
+func (b optionalBool) IsBoolFlag() bool {
+	return true
+}
+
+// resolveParts is the number of parts of a --resolve flag,
+// 'host:port:address'.
+const resolveParts = 3
+
+// requestFlags are the flags of a request execution.
+type requestFlags struct {
+	// options override the options of the request.
+	options parser.Options
+	// environment is the environment to use, it defaults to the value of
+	// the HIT_ENV environment variable.
+	environment string
+	// refreshDeps executes the dependencies of the request even if they
+	// have cached responses.
+	refreshDeps bool
+	// each is the source of the items the request is executed for once
+	// each, if set.
+	each string
+	// concurrency is the number of items executed at once.
+	concurrency int
+}
+
+// parseRequestFlags parses the flags preceding the request ID. It returns
+// the remaining arguments.
+func parseRequestFlags(args []string) (requestFlags, []string, error) {
+	var res requestFlags
+	fs := flag.NewFlagSet("hit", flag.ContinueOnError)
+	parsed := addRequestFlags(fs, &res)
+	fs.BoolVar(&res.refreshDeps, "refresh-deps", false, "execute the "+
+		"dependencies of the request even if they have cached responses")
+	fs.StringVar(&res.each, "each", "", "execute the request once per item "+
+		"of an array bound to '@1' onwards: '@request-id.path' of a cached "+
+		"response, a .json or a .csv file")
+	fs.IntVar(&res.concurrency, "concurrency", 1, "number of items of "+
+		"--each executed at once")
+	if err := fs.Parse(args); err != nil {
+		return requestFlags{}, nil, err
+	}
+	parsed()
+	if res.concurrency < 1 {
+		return requestFlags{}, nil, fmt.Errorf("--concurrency must be at least 1")
+	}
+	if res.concurrency > 1 && res.each == "" {
+		return requestFlags{}, nil, fmt.Errorf("--concurrency requires --each")
+	}
+	return res, fs.Args(), nil
+}
+
+// addRequestFlags defines the flags of a request execution in fs, they are
+// stored in res. The returned function must be called once fs is parsed.
+func addRequestFlags(fs *flag.FlagSet, res *requestFlags) func() {
+	var insecure *bool
+	opts := &res.options
+	fs.StringVar(&res.environment, "env", os.Getenv("HIT_ENV"),
+		"environment of the @_global section to use")
+	fs.Func("timeout", "time limit of the request such as '30s', "+
+		"'0' disables the limit", func(s string) error {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		timeout := parser.Duration(d)
+		opts.Timeout = &timeout
+		return nil
+	})
+	fs.Var(optionalBool{&opts.FollowRedirects}, "follow-redirects",
+		"follow redirect responses")
+	fs.Func("max-redirects", "maximum number of redirects to follow",
+		func(s string) error {
+			n, err := strconv.Atoi(s)
+			if err != nil {
+				return err
+			}
+			if n < 0 {
+				return fmt.Errorf("must not be negative")
+			}
+			opts.MaxRedirects = &n
+			return nil
+		})
+	fs.Func("max-attempts", "maximum number of attempts of the request "+
+		"including retries", func(s string) error {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return err
+		}
+		if n < 1 {
+			return fmt.Errorf("must be at least 1")
+		}
+		opts.Retry = &parser.RetryOptions{MaxAttempts: &n}
+		return nil
+	})
+	fs.Var(optionalBool{&opts.Stream}, "stream",
+		"print the response as it arrives")
+	fs.Func("max-events", "close a streamed response after this many "+
+		"events or chunks", func(s string) error {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return err
+		}
+		if n < 0 {
+			return fmt.Errorf("must not be negative")
+		}
+		opts.MaxEvents = &n
+		return nil
+	})
+	fs.StringVar(&opts.Output, "output", "", "write the response body to a "+
+		"file, or to a file in a directory ending with '/'")
+	fs.StringVar(&opts.Output, "o", "", "shorthand for --output")
+	fs.Func("max-body-size", "fail if the response body is larger than a "+
+		"size such as '10MB'", func(s string) error {
+		size, err := parser.ParseSize(s)
+		if err != nil {
+			return err
+		}
+		opts.MaxBodySize = &size
+		return nil
+	})
+	fs.StringVar(&opts.Protocol, "protocol", "", "HTTP protocol to use: "+
+		"'auto', 'http1', 'http2' or 'h2c'")
+	fs.Var(optionalBool{&opts.Cookies}, "cookies", "send and store cookies "+
+		"of the cookie jar, use --cookies=false to disable it")
+	fs.Var(optionalBool{&insecure}, "insecure",
+		"skip verification of the TLS certificate of the server")
+	fs.StringVar(&opts.Proxy, "proxy", "", "URL of the HTTP, HTTPS or "+
+		"SOCKS5 proxy to use such as 'socks5://localhost:1080'")
+	fs.StringVar(&opts.NoProxy, "no-proxy", "", "comma-separated list of "+
+		"hosts not to proxy")
+	fs.Func("resolve", "connect to an address instead of resolving a host "+
+		"in the form 'host:port:address', can be repeated", func(s string) error {
+		parts := strings.SplitN(s, ":", resolveParts)
+		if len(parts) != resolveParts || parts[0] == "" || parts[2] == "" {
+			return fmt.Errorf("expected 'host:port:address'")
+		}
+		if opts.Resolve == nil {
+			opts.Resolve = map[string]string{}
+		}
+		host := parts[0]
+		if parts[1] != "" {
+			host = net.JoinHostPort(parts[0], parts[1])
+		}
+		opts.Resolve[host] = parts[2]
+		return nil
+	})
+
+	return func() {
+		if insecure != nil {
+			opts.TLS = &parser.TLSOptions{InsecureSkipVerify: insecure}
+		}
+	}
+}
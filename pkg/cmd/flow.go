@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/hbagdi/hit/pkg/cache"
+	"github.com/hbagdi/hit/pkg/db"
+	executorPkg "github.com/hbagdi/hit/pkg/executor"
+	"github.com/hbagdi/hit/pkg/log"
+	"github.com/hbagdi/hit/pkg/model"
+	"github.com/hbagdi/hit/pkg/parser"
+	"github.com/hbagdi/hit/pkg/printer"
+	"github.com/hbagdi/hit/pkg/report"
+)
+
+// flowFlags are the flags of 'hit flow'.
+type flowFlags struct {
+	requestFlags
+	// continueOnFailure overrides whether the steps following a failed
+	// step are executed.
+	continueOnFailure *bool
+}
+
+func parseFlowFlags(args []string) (flowFlags, []string, error) {
+	var res flowFlags
+	fs := flag.NewFlagSet("hit flow", flag.ContinueOnError)
+	parsed := addRequestFlags(fs, &res.requestFlags)
+	fs.Var(optionalBool{&res.continueOnFailure}, "continue-on-failure",
+		"execute the remaining steps once a step failed")
+	if err := fs.Parse(args); err != nil {
+		return flowFlags{}, nil, err
+	}
+	parsed()
+	return res, fs.Args(), nil
+}
+
+// executeFlow executes the steps of the flow named by args, or the requests
+// of args in order, and prints a summary. Each step sees the responses and
+// variables of the previous ones. A step fails if it can't be executed, if
+// one of its assertions fails or, for a request without assertions, if its
+// response is not successful.
+func executeFlow(ctx context.Context, args []string) error {
+	flags, args, err := parseFlowFlags(args)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("need a flow name or requests to execute")
+	}
+
+	store, err := db.NewStore(ctx, db.StoreOpts{Logger: log.Logger})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err := store.Close()
+		if err != nil {
+			log.Logger.Sugar().Errorf("failed to close store: %v", err)
+		}
+	}()
+	executor, err := executorPkg.NewExecutor(&executorPkg.Opts{
+		Cache:       cache.GetDBCache(store),
+		Options:     flags.options,
+		Environment: flags.environment,
+	})
+	if err != nil {
+		return fmt.Errorf("initialize executor: %v", err)
+	}
+	defer executor.Close()
+	if err := executor.LoadFiles(); err != nil {
+		return fmt.Errorf("read hit files: %v", err)
+	}
+
+	flow, err := flowOfArgs(executor, args)
+	if err != nil {
+		return err
+	}
+	if flags.continueOnFailure != nil {
+		flow.ContinueOnFailure = *flags.continueOnFailure
+	}
+
+	p := printer.NewPrinter(printer.Opts{
+		Mode:   printer.ModeColorConsole,
+		Writer: os.Stdout,
+	})
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+	var (
+		cases  []report.Case
+		failed bool
+	)
+	for i, step := range flow.Steps {
+		if (failed && !flow.ContinueOnFailure) || ctx.Err() != nil {
+			break
+		}
+		fmt.Printf("==> [%d/%d] %v\n", i+1, len(flow.Steps), step)
+		c, hit := executeStep(ctx, executor, step)
+		if hit != nil {
+			if err := p.Print(*hit); err != nil {
+				return fmt.Errorf("print request to console: %w", err)
+			}
+		}
+		cases = append(cases, c)
+		failed = failed || !c.Passed()
+	}
+	return printFlowSummary(os.Stdout, flow, cases)
+}
+
+// executeStep executes the request of step like testRequest. A request
+// without assertions fails if its response is not successful.
+func executeStep(ctx context.Context, executor *executorPkg.Executor,
+	step parser.Step,
+) (report.Case, *model.Hit) {
+	c, hit := testRequest(ctx, executor, step)
+	if hit != nil && c.Passed() && len(c.Assertions) == 0 &&
+		!executorPkg.Successful(*hit) {
+		c = report.NewCase(c.ID, c.Duration, hit, fmt.Errorf(
+			"unsuccessful response: %v", hit.Response.Status), nil)
+	}
+	return c, hit
+}
+
+// flowOfArgs returns the flow named by args or, if args are request IDs, a
+// flow executing them in order.
+func flowOfArgs(executor *executorPkg.Executor, args []string) (parser.Flow, error) {
+	if !strings.HasPrefix(args[0], "@") {
+		if len(args) > 1 {
+			return parser.Flow{}, fmt.Errorf("expected a single flow name or " +
+				"requests beginning with '@' character")
+		}
+		return executor.Flow(args[0])
+	}
+	var res parser.Flow
+	for _, id := range args {
+		if !strings.HasPrefix(id, "@") {
+			return parser.Flow{}, fmt.Errorf("request must begin with '@' character")
+		}
+		res.Steps = append(res.Steps, parser.Step{ID: id[1:]})
+	}
+	return res, nil
+}
+
+// printFlowSummary prints the outcome of each step of flow. Steps without a
+// case were skipped. It returns an error if a step failed.
+func printFlowSummary(w io.Writer, flow parser.Flow, cases []report.Case) error {
+	fmt.Fprintln(w)
+	if flow.Name != "" {
+		fmt.Fprintf(w, "flow %v:\n", flow.Name)
+	}
+	passed, failed := 0, 0
+	for _, c := range cases {
+		printTestCase(w, c)
+		if c.Passed() {
+			passed++
+		} else {
+			failed++
+		}
+	}
+	for _, step := range flow.Steps[len(cases):] {
+		fmt.Fprintf(w, "SKIP %v\n", step)
+	}
+	skipped := len(flow.Steps) - len(cases)
+	fmt.Fprintf(w, "\n%d passed, %d failed, %d skipped\n", passed, failed, skipped)
+	if failed > 0 {
+		return fmt.Errorf("flow: %d of %d step(s) failed", failed, len(flow.Steps))
+	}
+	if skipped > 0 {
+		return fmt.Errorf("flow: interrupted, %d step(s) skipped", skipped)
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/hbagdi/hit/pkg/lint"
+)
+
+func executeLint() error {
+	filenames, err := filepath.Glob("*.hit")
+	if err != nil {
+		return fmt.Errorf("list hit files: %v", err)
+	}
+	if len(filenames) == 0 {
+		return fmt.Errorf("no hit files found")
+	}
+	problems := lint.Lint(filenames)
+	for _, problem := range problems {
+		fmt.Println(problem)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("lint: found %d problem(s)", len(problems))
+	}
+	return nil
+}
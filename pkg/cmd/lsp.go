@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/hbagdi/hit/pkg/db"
+	"github.com/hbagdi/hit/pkg/log"
+	"github.com/hbagdi/hit/pkg/lsp"
+)
+
+func executeLSP(ctx context.Context) error {
+	store, err := db.NewStore(ctx, db.StoreOpts{Logger: log.Logger})
+	if err != nil {
+		return fmt.Errorf("set up DB: %v", err)
+	}
+	defer func() {
+		err := store.Close()
+		if err != nil {
+			log.Logger.Sugar().Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	server := lsp.NewServer(lsp.Opts{
+		In:     os.Stdin,
+		Out:    os.Stdout,
+		Hits:   store,
+		Logger: log.Logger,
+	})
+	if err := server.Serve(ctx); err != nil {
+		return fmt.Errorf("language server: %v", err)
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"os/signal"
 	"strings"
 	"sync"
 
@@ -12,6 +13,7 @@ import (
 	"github.com/hbagdi/hit/pkg/db"
 	executorPkg "github.com/hbagdi/hit/pkg/executor"
 	"github.com/hbagdi/hit/pkg/log"
+	"github.com/hbagdi/hit/pkg/model"
 	"github.com/hbagdi/hit/pkg/printer"
 	"github.com/hbagdi/hit/pkg/version"
 	"go.uber.org/zap"
@@ -77,11 +79,37 @@ func Run(ctx context.Context, args ...string) (err error) {
 		return executeVersion()
 	case id == "browse":
 		return executeBrowse(ctx)
-	case id[0] == '@':
+	case id == "lint" || id == "validate":
+		return executeLint()
+	case id == "lsp":
+		return executeLSP(ctx)
+	case id == "cookies":
+		return executeCookies(ctx, args[2:])
+	case id == "test":
+		return executeTest(ctx, args[2:])
+	case id == "flow":
+		return executeFlow(ctx, args[2:])
+	case id == "deps":
+		return executeDeps(ctx, args[2:])
+	case id[0] == '@' || id[0] == '-':
 	default:
 		return fmt.Errorf("request must begin with '@' character")
 	}
-	id = id[1:]
+	return executeRequest(ctx, args[1:])
+}
+
+func executeRequest(ctx context.Context, args []string) (err error) {
+	flags, args, err := parseRequestFlags(args)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("need a request to execute")
+	}
+	if !strings.HasPrefix(args[0], "@") {
+		return fmt.Errorf("request must begin with '@' character")
+	}
+	id := args[0][1:]
 
 	store, err := db.NewStore(ctx, db.StoreOpts{Logger: log.Logger})
 	if err != nil {
@@ -107,8 +135,16 @@ func Run(ctx context.Context, args ...string) (err error) {
 		}
 	}()
 
+	p := &streamPrinter{Printer: printer.NewPrinter(printer.Opts{
+		Mode:   printer.ModeColorConsole,
+		Writer: os.Stdout,
+	})}
 	executor, err := executorPkg.NewExecutor(&executorPkg.Opts{
-		Cache: dbCache,
+		Cache:       dbCache,
+		Options:     flags.options,
+		Environment: flags.environment,
+		Stream:      p,
+		Progress:    os.Stderr,
 	})
 	if err != nil {
 		return fmt.Errorf("initialize executor: %v", err)
@@ -120,8 +156,25 @@ func Run(ctx context.Context, args ...string) (err error) {
 		return fmt.Errorf("read hit files: %v", err)
 	}
 
+	// interrupting a streamed response ends it, the captured portion is
+	// kept
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+	err = executor.ExecuteDependencies(ctx, id, flags.refreshDeps,
+		func(hit model.Hit) {
+			fmt.Fprintf(os.Stderr, "executed dependency @%v: %v\n",
+				hit.HitRequestID, hit.Response.Status)
+		})
+	if err != nil {
+		return err
+	}
+	if flags.each != "" {
+		return executeEach(ctx, executor, id, args[1:], flags.each,
+			flags.concurrency)
+	}
+
 	req, err := executor.BuildRequest(id, &executorPkg.RequestOpts{
-		Params: args[1:],
+		Params: args,
 	})
 	if err != nil {
 		return fmt.Errorf("build request: %v", err)
@@ -132,19 +185,33 @@ func Run(ctx context.Context, args ...string) (err error) {
 		return fmt.Errorf("execute request: %v", err)
 	}
 
-	p := printer.NewPrinter(printer.Opts{
-		Mode:   printer.ModeColorConsole,
-		Writer: os.Stdout,
-	})
-	err = p.Print(hit)
-	if err != nil {
-		return fmt.Errorf("print request to console: %w", err)
+	if p.streamed {
+		p.StreamEnd(hit)
+	} else {
+		err = p.Print(hit)
+		if err != nil {
+			return fmt.Errorf("print request to console: %w", err)
+		}
+	}
+	if err := executor.Capture(id, hit); err != nil {
+		return fmt.Errorf("capture variables: %v", err)
 	}
 
 	printLatestVersion()
 	return err
 }
 
+// streamPrinter is a printer which records if a response was streamed.
+type streamPrinter struct {
+	printer.Printer
+	streamed bool
+}
+
+func (p *streamPrinter) StreamStart(hit model.Hit) error {
+	p.streamed = true
+	return p.Printer.StreamStart(hit)
+}
+
 func printLatestVersion() {
 	latestVersion := getLatestVersion()
 	if latestVersion == "" {
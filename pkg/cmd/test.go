@@ -0,0 +1,205 @@
+package cmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/hbagdi/hit/pkg/assertion"
+	"github.com/hbagdi/hit/pkg/cache"
+	"github.com/hbagdi/hit/pkg/db"
+	executorPkg "github.com/hbagdi/hit/pkg/executor"
+	"github.com/hbagdi/hit/pkg/log"
+	"github.com/hbagdi/hit/pkg/model"
+	"github.com/hbagdi/hit/pkg/parser"
+	"github.com/hbagdi/hit/pkg/report"
+)
+
+// testFlags are the flags of 'hit test'.
+type testFlags struct {
+	requestFlags
+	// report is the format of the report written, if any.
+	report string
+	// reportFile is the file the report is written to instead of the
+	// summary printed.
+	reportFile string
+}
+
+func parseTestFlags(args []string) (testFlags, []string, error) {
+	var res testFlags
+	fs := flag.NewFlagSet("hit test", flag.ContinueOnError)
+	parsed := addRequestFlags(fs, &res.requestFlags)
+	fs.StringVar(&res.report, "report", "", "write a report in a format: "+
+		"'junit', 'tap' or 'json'")
+	fs.StringVar(&res.reportFile, "report-file", "", "write the report to a "+
+		"file instead of the standard output")
+	if err := fs.Parse(args); err != nil {
+		return testFlags{}, nil, err
+	}
+	parsed()
+	if res.report != "" {
+		if err := report.ValidFormat(res.report); err != nil {
+			return testFlags{}, nil, err
+		}
+	} else if res.reportFile != "" {
+		return testFlags{}, nil, fmt.Errorf("--report-file requires --report")
+	}
+	return res, fs.Args(), nil
+}
+
+// executeTest executes the requests of args, or every request if there are
+// none, and checks their assertions. A summary is printed unless a report
+// is written to the standard output.
+func executeTest(ctx context.Context, args []string) error {
+	flags, ids, err := parseTestFlags(args)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if !strings.HasPrefix(id, "@") {
+			return fmt.Errorf("request must begin with '@' character")
+		}
+	}
+
+	store, err := db.NewStore(ctx, db.StoreOpts{Logger: log.Logger})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err := store.Close()
+		if err != nil {
+			log.Logger.Sugar().Errorf("failed to close store: %v", err)
+		}
+	}()
+	executor, err := executorPkg.NewExecutor(&executorPkg.Opts{
+		Cache:       cache.GetDBCache(store),
+		Options:     flags.options,
+		Environment: flags.environment,
+	})
+	if err != nil {
+		return fmt.Errorf("initialize executor: %v", err)
+	}
+	defer executor.Close()
+	if err := executor.LoadFiles(); err != nil {
+		return fmt.Errorf("read hit files: %v", err)
+	}
+	if len(ids) == 0 {
+		ids, err = executor.AllRequestIDs()
+		if err != nil {
+			return err
+		}
+	}
+
+	var out io.Writer = os.Stdout
+	if flags.report != "" && flags.reportFile == "" {
+		out = io.Discard
+	}
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+	var cases []report.Case
+	for _, id := range ids {
+		c, _ := testRequest(ctx, executor, parser.Step{ID: id[1:]})
+		printTestCase(out, c)
+		cases = append(cases, c)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	passed, failed := 0, 0
+	for _, c := range cases {
+		if c.Passed() {
+			passed++
+		} else {
+			failed++
+		}
+	}
+	fmt.Fprintf(out, "\n%d passed, %d failed\n", passed, failed)
+	if flags.report != "" {
+		if err := writeReport(flags, cases); err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("test: %d of %d request(s) failed", failed, len(cases))
+	}
+	return nil
+}
+
+// writeReport writes the report of cases to the report file of flags or
+// the standard output.
+func writeReport(flags testFlags, cases []report.Case) error {
+	if flags.reportFile == "" {
+		return report.Write(os.Stdout, flags.report, cases)
+	}
+	f, err := os.Create(flags.reportFile)
+	if err != nil {
+		return fmt.Errorf("create report: %v", err)
+	}
+	if err := report.Write(f, flags.report, cases); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("write report: %v", err)
+	}
+	return nil
+}
+
+// testRequest executes the request of step, captures its variables and
+// checks its assertions. The hit is nil if the request was not executed.
+func testRequest(ctx context.Context, executor *executorPkg.Executor,
+	step parser.Step,
+) (report.Case, *model.Hit) {
+	start := time.Now()
+	id := step.String()
+	assertions, err := executor.Assertions(step.ID)
+	if err != nil {
+		return report.NewCase(id, time.Since(start), nil, err, nil), nil
+	}
+	req, err := executor.BuildRequest(step.ID, &executorPkg.RequestOpts{
+		Params:   append([]string{"@" + step.ID}, step.Args...),
+		Literals: step.Literals,
+	})
+	if err != nil {
+		return report.NewCase(id, time.Since(start), nil,
+			fmt.Errorf("build request: %v", err), nil), nil
+	}
+	hit, err := executor.Execute(ctx, step.ID, req)
+	if err != nil {
+		return report.NewCase(id, time.Since(start), nil,
+			fmt.Errorf("execute request: %v", err), nil), nil
+	}
+	var results []assertion.Result
+	if assertions != nil {
+		results = assertion.Check(*assertions, hit)
+	}
+	if err := executor.Capture(step.ID, hit); err != nil {
+		return report.NewCase(id, time.Since(start), &hit,
+			fmt.Errorf("capture variables: %v", err), results), &hit
+	}
+	return report.NewCase(id, time.Since(start), &hit, nil, results), &hit
+}
+
+func printTestCase(w io.Writer, c report.Case) {
+	status := "PASS"
+	if !c.Passed() {
+		status = "FAIL"
+	}
+	fmt.Fprintf(w, "%v %v (%v)\n", status, c.ID,
+		c.Duration.Round(time.Millisecond))
+	if c.Error != "" {
+		fmt.Fprintf(w, "  error: %v\n", c.Error)
+	}
+	for _, r := range c.Assertions {
+		if r.Passed {
+			fmt.Fprintf(w, "  ok   %v\n", r.Assertion)
+		} else {
+			fmt.Fprintf(w, "  fail %v: %v\n", r.Assertion, r.Message)
+		}
+	}
+}
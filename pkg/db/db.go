@@ -4,9 +4,11 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/hbagdi/hit/pkg/model"
@@ -22,6 +24,16 @@ func init() {
 type Store struct {
 	db     *sql.DB
 	logger *zap.Logger
+	// writeMu serializes writes, concurrent writers of SQLite only wait for
+	// each other as long as the busy timeout.
+	writeMu sync.Mutex
+}
+
+// exec executes query, which writes to the database.
+func (s *Store) exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return s.db.ExecContext(ctx, query, args...)
 }
 
 const loadLatestQuery = `select 
@@ -39,7 +51,7 @@ http_response_code,
 http_response_body
 from hits
 where hit_request_id=@hitRequestID
-order by created_at desc limit 1;`
+order by created_at desc, id desc limit 1;`
 
 func (s *Store) LoadLatestHitForID(ctx context.Context, hitRequestID string) (model.Hit, error) {
 	rows := s.db.QueryRowContext(ctx, loadLatestQuery,
@@ -76,7 +88,23 @@ http_response_code,
 http_response_proto,
 http_response_status,
 http_response_headers,
-http_response_body
+http_response_body,
+redirects,
+latency_dns_resolution,
+latency_tcp_connection,
+latency_tls_connection,
+latency_ttfb,
+latency_total,
+network_ip,
+network_port,
+tls_version,
+tls_cipher_suite,
+proxy,
+attempts,
+http_response_body_file,
+http_response_body_size,
+kind,
+messages
 )
 values(
 @hitRequestID,
@@ -93,7 +121,23 @@ values(
 @httpResponseProto,
 @httpResponseStatus,
 @httpResponseHeaders,
-@httpResponseBody
+@httpResponseBody,
+@redirects,
+@latencyDNSResolution,
+@latencyTCPConnection,
+@latencyTLSConnection,
+@latencyTTFB,
+@latencyTotal,
+@networkIP,
+@networkPort,
+@tlsVersion,
+@tlsCipherSuite,
+@proxy,
+@attempts,
+@httpResponseBodyFile,
+@httpResponseBodySize,
+@kind,
+@messages
 );`
 
 func (s *Store) Save(ctx context.Context, hit model.Hit) error {
@@ -105,7 +149,23 @@ func (s *Store) Save(ctx context.Context, hit model.Hit) error {
 	if err != nil {
 		return fmt.Errorf("marshal HTTP headers into json: %v", err)
 	}
-	_, err = s.db.ExecContext(ctx, saveQuery,
+	redirects, err := json.Marshal(hit.Redirects)
+	if err != nil {
+		return fmt.Errorf("marshal redirects into json: %v", err)
+	}
+	attempts, err := json.Marshal(hit.Attempts)
+	if err != nil {
+		return fmt.Errorf("marshal attempts into json: %v", err)
+	}
+	messages, err := json.Marshal(hit.Messages)
+	if err != nil {
+		return fmt.Errorf("marshal messages into json: %v", err)
+	}
+	kind := hit.Kind
+	if kind == "" {
+		kind = model.HitKindHTTP
+	}
+	_, err = s.exec(ctx, saveQuery,
 		sql.Named("hitRequestID", hit.HitRequestID),
 		sql.Named("createdAt", time.Now().Unix()),
 		sql.Named("httpRequestProto", hit.Request.Proto),
@@ -121,6 +181,22 @@ func (s *Store) Save(ctx context.Context, hit model.Hit) error {
 		sql.Named("httpResponseStatus", hit.Response.Status),
 		sql.Named("httpResponseHeaders", string(responseHeaders)),
 		sql.Named("httpResponseBody", hit.Response.Body),
+		sql.Named("redirects", string(redirects)),
+		sql.Named("latencyDNSResolution", hit.Latency.DNSResolution),
+		sql.Named("latencyTCPConnection", hit.Latency.TCPConnection),
+		sql.Named("latencyTLSConnection", hit.Latency.TLSConnection),
+		sql.Named("latencyTTFB", hit.Latency.TTFB),
+		sql.Named("latencyTotal", hit.Latency.Total),
+		sql.Named("networkIP", hit.Network.IPInUse),
+		sql.Named("networkPort", hit.Network.PortInUse),
+		sql.Named("tlsVersion", hit.TLS.Version),
+		sql.Named("tlsCipherSuite", hit.TLS.CipherSuite),
+		sql.Named("proxy", hit.Proxy),
+		sql.Named("attempts", string(attempts)),
+		sql.Named("httpResponseBodyFile", hit.Response.BodyFile),
+		sql.Named("httpResponseBodySize", hit.Response.BodySize),
+		sql.Named("kind", string(kind)),
+		sql.Named("messages", string(messages)),
 	)
 	if err != nil {
 		return fmt.Errorf("execute sql: %v", err)
@@ -146,9 +222,25 @@ http_response_proto,
 http_response_code,
 http_response_status,
 http_response_headers,
-http_response_body
+http_response_body,
+redirects,
+latency_dns_resolution,
+latency_tcp_connection,
+latency_tls_connection,
+latency_ttfb,
+latency_total,
+network_ip,
+network_port,
+tls_version,
+tls_cipher_suite,
+proxy,
+attempts,
+http_response_body_file,
+http_response_body_size,
+kind,
+messages
 from hits
-order by created_at desc limit 1000;`
+order by created_at desc, id desc limit 1000;`
 
 func (s *Store) List(ctx context.Context, opts PageOpts) ([]model.Hit, error) {
 	rows, err := s.db.QueryContext(ctx, listQuery)
@@ -166,13 +258,28 @@ func (s *Store) List(ctx context.Context, opts PageOpts) ([]model.Hit, error) {
 			requestHeaders        http.Header
 			responseHeadersAsJSON sql.NullString
 			responseHeaders       http.Header
+			redirectsAsJSON       sql.NullString
+			latency               [5]sql.NullInt64
+			networkIP             sql.NullString
+			networkPort           sql.NullInt64
+			tlsVersion            sql.NullString
+			tlsCipherSuite        sql.NullString
+			proxy                 sql.NullString
+			attemptsAsJSON        sql.NullString
+			bodyFile              sql.NullString
+			bodySize              sql.NullInt64
+			kind                  sql.NullString
+			messagesAsJSON        sql.NullString
 		)
 		err := rows.Scan(&hit.HitRequestID, &hit.CreatedAt,
 			&hit.Request.Proto, &hit.Request.Scheme, &hit.Request.Method,
 			&hit.Request.Host, &hit.Request.Path, &hit.Request.QueryString,
 			&requestHeadersAsJSON, &hit.Request.Body,
 			&hit.Response.Proto, &hit.Response.Code, &hit.Response.Status,
-			&responseHeadersAsJSON, &hit.Response.Body)
+			&responseHeadersAsJSON, &hit.Response.Body, &redirectsAsJSON,
+			&latency[0], &latency[1], &latency[2], &latency[3], &latency[4],
+			&networkIP, &networkPort, &tlsVersion, &tlsCipherSuite, &proxy,
+			&attemptsAsJSON, &bodyFile, &bodySize, &kind, &messagesAsJSON)
 		if err != nil {
 			return nil, err
 		}
@@ -192,12 +299,262 @@ func (s *Store) List(ctx context.Context, opts PageOpts) ([]model.Hit, error) {
 			}
 			hit.Response.Header = responseHeaders
 		}
+		hit.Latency = model.Latency{
+			DNSResolution: time.Duration(latency[0].Int64),
+			TCPConnection: time.Duration(latency[1].Int64),
+			TLSConnection: time.Duration(latency[2].Int64),
+			TTFB:          time.Duration(latency[3].Int64),
+			Total:         time.Duration(latency[4].Int64),
+		}
+		hit.Network.IPInUse = networkIP.String
+		hit.Network.PortInUse = int(networkPort.Int64)
+		hit.TLS.Version = tlsVersion.String
+		hit.TLS.CipherSuite = tlsCipherSuite.String
+		hit.Proxy = proxy.String
+		hit.Response.BodyFile = bodyFile.String
+		hit.Response.BodySize = bodySize.Int64
+		hit.Kind = model.HitKindHTTP
+		if kind.Valid {
+			hit.Kind = model.HitKind(kind.String)
+		}
+		if redirectsAsJSON.Valid {
+			err = json.Unmarshal([]byte(redirectsAsJSON.String), &hit.Redirects)
+			if err != nil {
+				return nil, fmt.Errorf("unmarshal redirects from JSON: %v", err)
+			}
+		}
+		if attemptsAsJSON.Valid {
+			err = json.Unmarshal([]byte(attemptsAsJSON.String), &hit.Attempts)
+			if err != nil {
+				return nil, fmt.Errorf("unmarshal attempts from JSON: %v", err)
+			}
+		}
+		if messagesAsJSON.Valid {
+			err = json.Unmarshal([]byte(messagesAsJSON.String), &hit.Messages)
+			if err != nil {
+				return nil, fmt.Errorf("unmarshal messages from JSON: %v", err)
+			}
+		}
 
 		res = append(res, hit)
 	}
 	return res, nil
 }
 
+const loadTokenQuery = `select access_token, refresh_token, expires_at
+from oauth2_tokens where key=@key;`
+
+// LoadToken returns the OAuth2 token cached under key. It returns false if
+// there is no such token.
+func (s *Store) LoadToken(ctx context.Context, key string) (model.Token, bool, error) {
+	var (
+		token     model.Token
+		expiresAt int64
+	)
+	err := s.db.QueryRowContext(ctx, loadTokenQuery, sql.Named("key", key)).
+		Scan(&token.AccessToken, &token.RefreshToken, &expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.Token{}, false, nil
+	}
+	if err != nil {
+		return model.Token{}, false, fmt.Errorf("load OAuth2 token: %v", err)
+	}
+	if expiresAt != 0 {
+		token.Expiry = time.Unix(expiresAt, 0)
+	}
+	return token, true, nil
+}
+
+const saveTokenQuery = `insert or replace into oauth2_tokens(
+key, access_token, refresh_token, expires_at)
+values(@key, @accessToken, @refreshToken, @expiresAt);`
+
+// SaveToken caches token under key, replacing the token cached under key.
+func (s *Store) SaveToken(ctx context.Context, key string, token model.Token) error {
+	var expiresAt int64
+	if !token.Expiry.IsZero() {
+		expiresAt = token.Expiry.Unix()
+	}
+	_, err := s.exec(ctx, saveTokenQuery,
+		sql.Named("key", key),
+		sql.Named("accessToken", token.AccessToken),
+		sql.Named("refreshToken", token.RefreshToken),
+		sql.Named("expiresAt", expiresAt),
+	)
+	if err != nil {
+		return fmt.Errorf("save OAuth2 token: %v", err)
+	}
+	return nil
+}
+
+const loadCookiesQuery = `select domain, path, name, value, expires_at, secure,
+http_only, host_only from cookies
+where directory=@directory and environment=@environment
+order by domain, path, name;`
+
+// LoadCookies returns the cookies of scope.
+func (s *Store) LoadCookies(ctx context.Context, scope model.Scope) ([]model.Cookie, error) {
+	rows, err := s.db.QueryContext(ctx, loadCookiesQuery,
+		sql.Named("directory", scope.Directory),
+		sql.Named("environment", scope.Environment),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("load cookies: %v", err)
+	}
+	defer rows.Close()
+	var res []model.Cookie
+	for rows.Next() {
+		var (
+			cookie    model.Cookie
+			expiresAt int64
+		)
+		err := rows.Scan(&cookie.Domain, &cookie.Path, &cookie.Name,
+			&cookie.Value, &expiresAt, &cookie.Secure, &cookie.HTTPOnly,
+			&cookie.HostOnly)
+		if err != nil {
+			return nil, fmt.Errorf("load cookies: %v", err)
+		}
+		if expiresAt != 0 {
+			cookie.Expires = time.Unix(expiresAt, 0)
+		}
+		res = append(res, cookie)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("load cookies: %v", err)
+	}
+	return res, nil
+}
+
+const saveCookieQuery = `insert or replace into cookies(directory,
+environment, domain, path, name, value, expires_at, secure, http_only,
+host_only)
+values(@directory, @environment, @domain, @path, @name, @value, @expiresAt,
+@secure, @httpOnly, @hostOnly);`
+
+// SaveCookie stores cookie in scope, replacing the cookie with the same
+// domain, path and name.
+func (s *Store) SaveCookie(ctx context.Context, scope model.Scope, cookie model.Cookie) error {
+	var expiresAt int64
+	if !cookie.Expires.IsZero() {
+		expiresAt = cookie.Expires.Unix()
+	}
+	_, err := s.exec(ctx, saveCookieQuery,
+		sql.Named("directory", scope.Directory),
+		sql.Named("environment", scope.Environment),
+		sql.Named("domain", cookie.Domain),
+		sql.Named("path", cookie.Path),
+		sql.Named("name", cookie.Name),
+		sql.Named("value", cookie.Value),
+		sql.Named("expiresAt", expiresAt),
+		sql.Named("secure", cookie.Secure),
+		sql.Named("httpOnly", cookie.HTTPOnly),
+		sql.Named("hostOnly", cookie.HostOnly),
+	)
+	if err != nil {
+		return fmt.Errorf("save cookie: %v", err)
+	}
+	return nil
+}
+
+const deleteCookieQuery = `delete from cookies
+where directory=@directory and environment=@environment and domain=@domain
+and path=@path and name=@name;`
+
+// DeleteCookie removes the cookie of scope with the domain, path and name of
+// cookie, if any.
+func (s *Store) DeleteCookie(ctx context.Context, scope model.Scope, cookie model.Cookie) error {
+	_, err := s.exec(ctx, deleteCookieQuery,
+		sql.Named("directory", scope.Directory),
+		sql.Named("environment", scope.Environment),
+		sql.Named("domain", cookie.Domain),
+		sql.Named("path", cookie.Path),
+		sql.Named("name", cookie.Name),
+	)
+	if err != nil {
+		return fmt.Errorf("delete cookie: %v", err)
+	}
+	return nil
+}
+
+const clearCookiesQuery = `delete from cookies
+where directory=@directory and environment=@environment;`
+
+// ClearCookies removes every cookie of scope. It returns the number of
+// cookies removed.
+func (s *Store) ClearCookies(ctx context.Context, scope model.Scope) (int64, error) {
+	res, err := s.exec(ctx, clearCookiesQuery,
+		sql.Named("directory", scope.Directory),
+		sql.Named("environment", scope.Environment),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("clear cookies: %v", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("clear cookies: %v", err)
+	}
+	return n, nil
+}
+
+const loadVariablesQuery = `select name, value from variables
+where directory=@directory and environment=@environment;`
+
+// LoadVariables returns the captured variables of scope by name.
+func (s *Store) LoadVariables(ctx context.Context, scope model.Scope) (map[string]interface{}, error) {
+	rows, err := s.db.QueryContext(ctx, loadVariablesQuery,
+		sql.Named("directory", scope.Directory),
+		sql.Named("environment", scope.Environment),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("load variables: %v", err)
+	}
+	defer rows.Close()
+	res := map[string]interface{}{}
+	for rows.Next() {
+		var (
+			name  string
+			value []byte
+			v     interface{}
+		)
+		if err := rows.Scan(&name, &value); err != nil {
+			return nil, fmt.Errorf("load variables: %v", err)
+		}
+		if err := json.Unmarshal(value, &v); err != nil {
+			return nil, fmt.Errorf("load variable '%v': %v", name, err)
+		}
+		res[name] = v
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("load variables: %v", err)
+	}
+	return res, nil
+}
+
+const saveVariableQuery = `insert or replace into variables(directory,
+environment, name, value)
+values(@directory, @environment, @name, @value);`
+
+// SaveVariable stores variable name of scope with value, replacing its
+// previous value.
+func (s *Store) SaveVariable(ctx context.Context, scope model.Scope, name string,
+	value interface{},
+) error {
+	js, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("save variable '%v': %v", name, err)
+	}
+	_, err = s.exec(ctx, saveVariableQuery,
+		sql.Named("directory", scope.Directory),
+		sql.Named("environment", scope.Environment),
+		sql.Named("name", name),
+		sql.Named("value", string(js)),
+	)
+	if err != nil {
+		return fmt.Errorf("save variable '%v': %v", name, err)
+	}
+	return nil
+}
+
 func (s *Store) Close() error {
 	if err := s.db.Close(); err != nil {
 		return fmt.Errorf("close database: %v", err)
@@ -221,7 +578,8 @@ func init() {
 }
 
 func genDSN(fileName string) string {
-	dsn := fmt.Sprintf("%s?_journal_mode=WAL&_busy_timeout=500", fileName)
+	// the busy timeout covers writes of other hit processes
+	dsn := fmt.Sprintf("%s?_journal_mode=WAL&_busy_timeout=5000", fileName)
 	return dsn
 }
 
@@ -59,6 +59,30 @@ var migrations = []string{
 	`alter table hits add column http_response_proto text;`,
 	`alter table hits add column http_request_proto text;`,
 	`alter table hits add column http_request_scheme text;`,
+	`alter table hits add column redirects text;`,
+	`alter table hits add column latency_dns_resolution integer;`,
+	`alter table hits add column latency_tcp_connection integer;`,
+	`alter table hits add column latency_tls_connection integer;`,
+	`alter table hits add column latency_ttfb integer;`,
+	`alter table hits add column latency_total integer;`,
+	`alter table hits add column network_ip text;`,
+	`alter table hits add column network_port integer;`,
+	`alter table hits add column tls_version text;`,
+	`alter table hits add column tls_cipher_suite text;`,
+	`alter table hits add column proxy text;`,
+	`alter table hits add column attempts text;`,
+	`alter table hits add column http_response_body_file text;`,
+	`alter table hits add column http_response_body_size integer;`,
+	`alter table hits add column kind text;`,
+	`alter table hits add column messages text;`,
+	`create table if not exists oauth2_tokens(key text primary key,
+access_token text, refresh_token text, expires_at integer);`,
+	`create table if not exists cookies(directory text, environment text,
+domain text, path text, name text, value text, expires_at integer,
+secure integer, http_only integer, host_only integer,
+primary key(directory, environment, domain, path, name));`,
+	`create table if not exists variables(directory text, environment text,
+name text, value text, primary key(directory, environment, name));`,
 }
 
 func doMigrate(ctx context.Context, db *sql.DB, migrations []string) error {
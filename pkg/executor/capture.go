@@ -0,0 +1,147 @@
+package executor
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/hbagdi/hit/pkg/model"
+	"github.com/hbagdi/hit/pkg/parser"
+	"github.com/tidwall/gjson"
+)
+
+var variableNameRegex = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_-]*$`)
+
+// ValidateCaptures returns an error if a variable of captures can't be
+// captured, such as a capture without a source.
+func ValidateCaptures(captures map[string]parser.Capture) error {
+	for _, name := range sortedCaptures(captures) {
+		if err := validateCapture(name, captures[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateCapture(name string, c parser.Capture) error {
+	if !variableNameRegex.MatchString(name) {
+		return fmt.Errorf("invalid variable name '%v': names start with a "+
+			"letter followed by letters, digits, '_' or '-'", name)
+	}
+	n := 0
+	for _, source := range []string{c.Body, c.Header, c.Regex} {
+		if source != "" {
+			n++
+		}
+	}
+	if n != 1 {
+		return fmt.Errorf("capture '%v' requires exactly one of 'body', "+
+			"'header' or 'regex'", name)
+	}
+	if c.Regex != "" {
+		if _, err := regexp.Compile(c.Regex); err != nil {
+			return fmt.Errorf("invalid regular expression of capture '%v': %v",
+				name, err)
+		}
+	}
+	return nil
+}
+
+// Capture stores the variables of the ~capture block of request id from
+// hit. Variables are captured only if the response is successful.
+func (e *Executor) Capture(id string, hit model.Hit) error {
+	r, err := e.fetchRequest(id)
+	if err != nil {
+		return err
+	}
+	if len(r.Captures) == 0 || !Successful(hit) {
+		return nil
+	}
+	if err := ValidateCaptures(r.Captures); err != nil {
+		return err
+	}
+	values := map[string]interface{}{}
+	for _, name := range sortedCaptures(r.Captures) {
+		v, err := capture(r.Captures[name], hit.Response)
+		if err != nil {
+			return fmt.Errorf("capture '%v': %v", name, err)
+		}
+		values[name] = v
+	}
+	scope, err := Scope(e.environment)
+	if err != nil {
+		return err
+	}
+	for _, name := range sortedCaptures(r.Captures) {
+		if err := e.cache.SaveVariable(scope, name, values[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Variables returns the captured variables of the environment of the
+// executor.
+func (e *Executor) Variables() (map[string]interface{}, error) {
+	scope, err := Scope(e.environment)
+	if err != nil {
+		return nil, err
+	}
+	return e.cache.Variables(scope)
+}
+
+// Successful returns true if the response of hit is an HTTP response with a
+// code below 400 or a gRPC response with an OK status.
+func Successful(hit model.Hit) bool {
+	if hit.Kind == model.HitKindGRPC {
+		return hit.Response.Code == 0
+	}
+	return hit.Response.Code < 400
+}
+
+// capture returns the value captured by c from resp.
+func capture(c parser.Capture, resp model.Response) (interface{}, error) {
+	if c.Header != "" {
+		values := resp.Header.Values(c.Header)
+		if len(values) == 0 {
+			return nil, fmt.Errorf("response header '%v' not found", c.Header)
+		}
+		return values[0], nil
+	}
+	if resp.BodyFile != "" {
+		return nil, fmt.Errorf("response body was written to '%v'", resp.BodyFile)
+	}
+	if c.Regex != "" {
+		match := regexp.MustCompile(c.Regex).FindSubmatch(resp.Body)
+		switch {
+		case match == nil:
+			return nil, fmt.Errorf("response body does not match %q", c.Regex)
+		case len(match) > 1:
+			return string(match[1]), nil
+		default:
+			return string(match[0]), nil
+		}
+	}
+	if !gjson.ValidBytes(resp.Body) {
+		return nil, fmt.Errorf("response body is not JSON")
+	}
+	res := gjson.GetBytes(resp.Body, c.Body)
+	switch res.Type {
+	case gjson.Null:
+		return nil, fmt.Errorf("'%v' not found in response body", c.Body)
+	case gjson.JSON:
+		return nil, fmt.Errorf("found json at '%v', expected a string, "+
+			"number or boolean", c.Body)
+	default:
+		return res.Value(), nil
+	}
+}
+
+func sortedCaptures(captures map[string]parser.Capture) []string {
+	names := make([]string, 0, len(captures))
+	for name := range captures {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,198 @@
+package executor
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hbagdi/hit/pkg/cache"
+	"github.com/hbagdi/hit/pkg/log"
+	"github.com/hbagdi/hit/pkg/model"
+	"github.com/hbagdi/hit/pkg/parser"
+	"go.uber.org/zap"
+	"golang.org/x/net/publicsuffix"
+)
+
+// cookiesEnabled returns true if cookies are sent and stored with opts.
+func cookiesEnabled(opts parser.Options) bool {
+	return opts.Cookies == nil || *opts.Cookies
+}
+
+// Scope returns the scope of the cookies and variables of environment for the
+// working directory.
+func Scope(environment string) (model.Scope, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return model.Scope{}, fmt.Errorf("find working directory: %v", err)
+	}
+	return model.Scope{Directory: dir, Environment: environment}, nil
+}
+
+// cookieJar returns the cookie jar of the executor, loading it the first
+// time it is used.
+func (e *Executor) cookieJar() (http.CookieJar, error) {
+	e.jarMu.Lock()
+	defer e.jarMu.Unlock()
+	if e.jar != nil {
+		return e.jar, nil
+	}
+	scope, err := Scope(e.environment)
+	if err != nil {
+		return nil, err
+	}
+	e.jar, err = loadCookieJar(e.cache, scope)
+	if err != nil {
+		return nil, err
+	}
+	return e.jar, nil
+}
+
+// persistentJar is a cookie jar whose cookies are stored in a cache, which
+// keeps them across invocations of hit.
+type persistentJar struct {
+	jar   *cookiejar.Jar
+	cache cache.Cache
+	scope model.Scope
+}
+
+// loadCookieJar returns the cookie jar of scope with the cookies stored in
+// c. Expired cookies are removed.
+func loadCookieJar(c cache.Cache, scope model.Scope) (*persistentJar, error) {
+	jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	if err != nil {
+		return nil, fmt.Errorf("create cookie jar: %v", err)
+	}
+	cookies, err := c.Cookies(scope)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for _, cookie := range cookies {
+		if !cookie.Expires.IsZero() && !cookie.Expires.After(now) {
+			if err := c.DeleteCookie(scope, cookie); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		u := &url.URL{Scheme: "http", Host: cookie.Domain, Path: cookie.Path}
+		if cookie.Secure {
+			u.Scheme = "https"
+		}
+		httpCookie := &http.Cookie{
+			Name:     cookie.Name,
+			Value:    cookie.Value,
+			Path:     cookie.Path,
+			Expires:  cookie.Expires,
+			Secure:   cookie.Secure,
+			HttpOnly: cookie.HTTPOnly,
+		}
+		if !cookie.HostOnly {
+			httpCookie.Domain = cookie.Domain
+		}
+		jar.SetCookies(u, []*http.Cookie{httpCookie})
+	}
+	return &persistentJar{jar: jar, cache: c, scope: scope}, nil
+}
+
+func (j *persistentJar) Cookies(u *url.URL) []*http.Cookie {
+	return j.jar.Cookies(u)
+}
+
+// SetCookies stores the cookies received in a response to u. Errors are
+// logged as responses are not failed because of them.
+func (j *persistentJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.jar.SetCookies(u, cookies)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		// the jar ignores cookies of other schemes
+		return
+	}
+	now := time.Now()
+	for _, c := range cookies {
+		cookie, ok := storedCookie(u, c, now)
+		if !ok {
+			continue
+		}
+		var err error
+		if !cookie.Expires.IsZero() && !cookie.Expires.After(now) {
+			err = j.cache.DeleteCookie(j.scope, cookie)
+		} else {
+			err = j.cache.SaveCookie(j.scope, cookie)
+		}
+		if err != nil {
+			log.Logger.Error("failed to store cookie", zap.String("name", c.Name),
+				zap.Error(err))
+		}
+	}
+}
+
+// storedCookie returns c as it is stored once received in a response to u
+// at time now, following RFC 6265. It returns false if c is rejected.
+func storedCookie(u *url.URL, c *http.Cookie, now time.Time) (model.Cookie, bool) {
+	host := strings.ToLower(u.Hostname())
+	res := model.Cookie{
+		Domain:   host,
+		Path:     c.Path,
+		Name:     c.Name,
+		Value:    c.Value,
+		Secure:   c.Secure,
+		HTTPOnly: c.HttpOnly,
+		HostOnly: true,
+	}
+	if c.Name == "" {
+		return model.Cookie{}, false
+	}
+	if c.Domain != "" {
+		domain := strings.TrimPrefix(strings.ToLower(c.Domain), ".")
+		if host != domain && !strings.HasSuffix(host, "."+domain) {
+			return model.Cookie{}, false
+		}
+		if suffix, _ := publicsuffix.PublicSuffix(domain); suffix == domain && host != domain {
+			return model.Cookie{}, false
+		}
+		res.Domain, res.HostOnly = domain, false
+	}
+	if res.Path == "" || res.Path[0] != '/' {
+		res.Path = defaultCookiePath(u.Path)
+	}
+	switch {
+	case c.MaxAge < 0:
+		res.Expires = now
+	case c.MaxAge > 0:
+		res.Expires = now.Add(time.Duration(c.MaxAge) * time.Second)
+	case !c.Expires.IsZero():
+		res.Expires = c.Expires
+	}
+	return res, true
+}
+
+// defaultCookiePath returns the path of cookies received in response to a
+// request for path without a path attribute.
+func defaultCookiePath(path string) string {
+	i := strings.LastIndex(path, "/")
+	if i <= 0 {
+		return "/"
+	}
+	return path[:i]
+}
+
+// withCookies returns req with the cookies sent from the cookie jar added to
+// its Cookie header.
+func withCookies(req model.Request, cookies []*http.Cookie) model.Request {
+	if len(cookies) == 0 {
+		return req
+	}
+	values := make([]string, 0, len(cookies)+1)
+	if header := req.Header.Get("Cookie"); header != "" {
+		values = append(values, header)
+	}
+	for _, c := range cookies {
+		values = append(values, c.Name+"="+c.Value)
+	}
+	req.Header = req.Header.Clone()
+	req.Header.Set("Cookie", strings.Join(values, "; "))
+	return req
+}
@@ -0,0 +1,250 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hbagdi/hit/pkg/model"
+	"github.com/hbagdi/hit/pkg/request"
+)
+
+// Dependency is a request executed before another one if it has no cached
+// response.
+type Dependency struct {
+	// ID is the ID of the request without the leading '@'.
+	ID string
+	// Reference is the reference the dependency is inferred from, such as
+	// '@create-node.id' or '@var.token' for the request capturing token. It
+	// is empty if the dependency is declared with dependsOn.
+	Reference string
+}
+
+// Dependencies returns the direct dependencies of request id: the requests
+// of its dependsOn option followed by the requests it references. A
+// reference to a captured variable depends on the request capturing it.
+func (e *Executor) Dependencies(id string) ([]Dependency, error) {
+	if _, err := e.fetchRequest(id); err != nil {
+		return nil, err
+	}
+	var (
+		res  []Dependency
+		seen = map[string]bool{id: true}
+	)
+	add := func(d Dependency) {
+		if !seen[d.ID] {
+			seen[d.ID] = true
+			res = append(res, d)
+		}
+	}
+	opts := e.requestOptions(id)
+	for _, dep := range opts.DependsOn {
+		if !strings.HasPrefix(dep, "@") {
+			return nil, fmt.Errorf("invalid dependency '%v' of '@%v': "+
+				"request must begin with '@' character", dep, id)
+		}
+		if _, err := e.fetchRequest(dep[1:]); err != nil {
+			return nil, fmt.Errorf("dependency '%v' of '@%v': %v", dep, id, err)
+		}
+		add(Dependency{ID: dep[1:]})
+	}
+
+	recorder, err := e.references(id)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range recorder.keys {
+		if strings.HasPrefix(key, request.VariablePrefix) {
+			if capturing, ok := e.capturingRequest(key[len(request.VariablePrefix):]); ok {
+				add(Dependency{ID: capturing, Reference: "@" + key})
+			}
+			continue
+		}
+		ref := strings.SplitN(key, ".", 2)[0]
+		if _, err := e.fetchRequest(ref); err == nil {
+			add(Dependency{ID: ref, Reference: "@" + key})
+		}
+	}
+	return res, nil
+}
+
+// references returns the references of request id, found by generating the
+// request with every reference resolved to a placeholder.
+func (e *Executor) references(id string) (*referenceRecorder, error) {
+	r, err := e.fetchRequest(id)
+	if err != nil {
+		return nil, err
+	}
+	opts := e.requestOptions(id)
+	recorder := &referenceRecorder{}
+	global := e.global
+	global.BaseURL = opts.BaseURL
+	_, err = request.Generate(r, request.Options{
+		GlobalContext: global,
+		Resolver:      recorder,
+		Auth:          opts.Auth,
+		Sign:          opts.Sign,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("find references of '@%v': %v", id, err)
+	}
+	return recorder, nil
+}
+
+// capturingRequest returns the ID of the first request capturing variable
+// name.
+func (e *Executor) capturingRequest(name string) (string, bool) {
+	for _, file := range e.files {
+		for _, r := range file.Requests {
+			if _, ok := r.Captures[name]; ok {
+				return r.ID, true
+			}
+		}
+	}
+	return "", false
+}
+
+// DependencyOrder returns the transitive dependencies of request id in the
+// order they are executed, dependencies first. It returns an error if the
+// dependencies form a cycle.
+func (e *Executor) DependencyOrder(id string) ([]Dependency, error) {
+	return e.dependencyOrder(id, nil)
+}
+
+// dependencyOrder returns the transitive dependencies of request id like
+// DependencyOrder. Dependencies for which skip returns true are left out
+// along with their own dependencies.
+func (e *Executor) dependencyOrder(id string,
+	skip func(Dependency) (bool, error),
+) ([]Dependency, error) {
+	var (
+		res   []Dependency
+		done  = map[string]bool{}
+		stack []string
+	)
+	var visit func(d Dependency) error
+	visit = func(d Dependency) error {
+		for i, s := range stack {
+			if s == d.ID {
+				cycle := append(append([]string{}, stack[i:]...), d.ID)
+				return fmt.Errorf("dependency cycle: @%v",
+					strings.Join(cycle, " -> @"))
+			}
+		}
+		if done[d.ID] {
+			return nil
+		}
+		stack = append(stack, d.ID)
+		deps, err := e.Dependencies(d.ID)
+		if err != nil {
+			return err
+		}
+		for _, dep := range deps {
+			if skip != nil && !done[dep.ID] {
+				skipped, err := skip(dep)
+				if err != nil {
+					return err
+				}
+				if skipped {
+					continue
+				}
+			}
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		stack = stack[:len(stack)-1]
+		done[d.ID] = true
+		res = append(res, d)
+		return nil
+	}
+	if err := visit(Dependency{ID: id}); err != nil {
+		return nil, err
+	}
+	// the last request is id itself
+	return res[:len(res)-1], nil
+}
+
+// Satisfied returns true if dependency d has a cached response or, for a
+// dependency capturing a variable, if the variable is set.
+func (e *Executor) Satisfied(d Dependency) (bool, error) {
+	if strings.HasPrefix(d.Reference, "@"+request.VariablePrefix) {
+		variables, err := e.Variables()
+		if err != nil {
+			return false, err
+		}
+		_, ok := variables[d.Reference[len("@"+request.VariablePrefix):]]
+		return ok, nil
+	}
+	_, ok, err := e.cache.Hit(d.ID)
+	return ok, err
+}
+
+// ExecuteDependencies executes the dependencies of request id in order if
+// they are not satisfied, or every dependency if refresh is set. The
+// dependencies of a satisfied dependency are not executed. Executed
+// dependencies capture their variables and are passed to executed. It
+// returns an error if a dependency fails or its response is not
+// successful, or if a dependency to execute takes positional arguments.
+func (e *Executor) ExecuteDependencies(ctx context.Context, id string,
+	refresh bool, executed func(model.Hit),
+) error {
+	// the dependencies of a satisfied dependency are not needed
+	var skip func(Dependency) (bool, error)
+	if !refresh {
+		skip = e.Satisfied
+	}
+	deps, err := e.dependencyOrder(id, skip)
+	if err != nil {
+		return err
+	}
+	for _, d := range deps {
+		recorder, err := e.references(d.ID)
+		if err != nil {
+			return err
+		}
+		if recorder.positional {
+			return fmt.Errorf("dependency '@%v' takes positional arguments and "+
+				"can't be executed automatically: execute '@%v' with its "+
+				"arguments first", d.ID, d.ID)
+		}
+		req, err := e.BuildRequest(d.ID, &RequestOpts{Params: []string{"@" + d.ID}})
+		if err != nil {
+			return fmt.Errorf("dependency '@%v': build request: %v", d.ID, err)
+		}
+		hit, err := e.Execute(ctx, d.ID, req)
+		if err != nil {
+			return fmt.Errorf("dependency '@%v': execute request: %v", d.ID, err)
+		}
+		if executed != nil {
+			executed(hit)
+		}
+		if !Successful(hit) {
+			return fmt.Errorf("dependency '@%v': unsuccessful response: %v",
+				d.ID, hit.Response.Status)
+		}
+		if err := e.Capture(d.ID, hit); err != nil {
+			return fmt.Errorf("dependency '@%v': capture variables: %v", d.ID, err)
+		}
+	}
+	return nil
+}
+
+// referenceRecorder resolves every reference to a placeholder and records
+// the references to requests and variables.
+type referenceRecorder struct {
+	keys []string
+	// positional is set if a positional argument is referenced.
+	positional bool
+}
+
+func (r *referenceRecorder) Resolve(key string) (interface{}, error) {
+	key = key[1:]
+	if _, err := strconv.Atoi(key); err == nil {
+		r.positional = true
+	} else if strings.Contains(key, ".") {
+		r.keys = append(r.keys, key)
+	}
+	return "hit", nil
+}
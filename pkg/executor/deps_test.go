@@ -0,0 +1,134 @@
+package executor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hbagdi/hit/pkg/cache"
+	"github.com/hbagdi/hit/pkg/model"
+	"github.com/hbagdi/hit/pkg/parser"
+	"github.com/stretchr/testify/require"
+)
+
+// hitCache is a cache holding the hits of requests and the variables of
+// every scope.
+type hitCache struct {
+	cache.Cache
+	hits      map[string]model.Hit
+	variables map[string]interface{}
+}
+
+func (c hitCache) Hit(id string) (model.Hit, bool, error) {
+	hit, ok := c.hits[id]
+	return hit, ok, nil
+}
+
+func (c hitCache) Variables(model.Scope) (map[string]interface{}, error) {
+	return c.variables, nil
+}
+
+func TestDependencies(t *testing.T) {
+	c := hitCache{hits: map[string]model.Hit{}, variables: map[string]interface{}{}}
+	e := &Executor{
+		cache: c,
+		global: parser.Global{
+			Options: parser.Options{BaseURL: "http://localhost"},
+		},
+		files: []parser.File{{Requests: []parser.Request{
+			{
+				ID:       "login",
+				Method:   "POST",
+				Path:     "/login",
+				Captures: map[string]parser.Capture{"token": {Body: "token"}},
+			},
+			{
+				ID:     "create-node",
+				Method: "POST",
+				Path:   "/nodes",
+				Options: parser.Options{Auth: &parser.AuthOptions{
+					Type:  "bearer",
+					Token: "@var.token",
+				}},
+			},
+			{
+				ID:      "get-node",
+				Method:  "GET",
+				Path:    "/nodes/@create-node.id?version=@1",
+				Options: parser.Options{DependsOn: []string{"@login"}},
+			},
+			{ID: "a", Method: "GET", Path: "/@b.id"},
+			{ID: "b", Method: "GET", Path: "/@c.id"},
+			{ID: "c", Method: "GET", Path: "/@a.id"},
+			{ID: "chain-a", Method: "GET", Path: "/@chain-b.id"},
+			{ID: "chain-b", Method: "GET", Path: "/@chain-c.id"},
+			{ID: "chain-c", Method: "GET", Path: "/c"},
+			{
+				ID:           "invalid-body",
+				Method:       "POST",
+				Path:         "/@create-node.id",
+				BodyEncoding: "y2j",
+				Body:         []string{"name: [hit"},
+			},
+		}}},
+	}
+
+	deps, err := e.Dependencies("get-node")
+	require.NoError(t, err)
+	require.Equal(t, []Dependency{
+		{ID: "login"},
+		{ID: "create-node", Reference: "@create-node.id"},
+	}, deps)
+
+	deps, err = e.DependencyOrder("get-node")
+	require.NoError(t, err)
+	require.Equal(t, []Dependency{
+		{ID: "login"},
+		{ID: "create-node", Reference: "@create-node.id"},
+	}, deps)
+	deps, err = e.DependencyOrder("create-node")
+	require.NoError(t, err)
+	require.Equal(t, []Dependency{{ID: "login", Reference: "@var.token"}}, deps)
+
+	_, err = e.DependencyOrder("a")
+	require.EqualError(t, err, "dependency cycle: @a -> @b -> @c -> @a")
+
+	ok, err := e.Satisfied(Dependency{ID: "login", Reference: "@var.token"})
+	require.NoError(t, err)
+	require.False(t, ok)
+	c.hits["login"] = model.Hit{}
+	ok, err = e.Satisfied(Dependency{ID: "login", Reference: "@var.token"})
+	require.NoError(t, err)
+	require.False(t, ok)
+	ok, err = e.Satisfied(Dependency{ID: "login"})
+	require.NoError(t, err)
+	require.True(t, ok)
+	c.variables["token"] = "t1"
+	ok, err = e.Satisfied(Dependency{ID: "login", Reference: "@var.token"})
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	_, err = e.Dependencies("invalid-body")
+	require.ErrorContains(t, err, "find references of '@invalid-body': "+
+		"invalid y2j body: ")
+
+	// dependencies of a satisfied dependency are not executed
+	deps, err = e.dependencyOrder("chain-a", e.Satisfied)
+	require.NoError(t, err)
+	require.Equal(t, []Dependency{
+		{ID: "chain-c", Reference: "@chain-c.id"},
+		{ID: "chain-b", Reference: "@chain-b.id"},
+	}, deps)
+	c.hits["chain-b"] = model.Hit{}
+	deps, err = e.dependencyOrder("chain-a", e.Satisfied)
+	require.NoError(t, err)
+	require.Empty(t, deps)
+	require.NoError(t, e.ExecuteDependencies(context.Background(), "chain-a",
+		false, func(hit model.Hit) {
+			t.Errorf("unexpected execution of '@%v'", hit.HitRequestID)
+		}))
+
+	e.files[0].Requests[2].Options.DependsOn = []string{"@missing"}
+	_, err = e.Dependencies("get-node")
+	require.EqualError(t, err, "dependency '@missing' of '@get-node': "+
+		"request 'missing' not found")
+}
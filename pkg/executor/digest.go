@@ -0,0 +1,175 @@
+package executor
+
+import (
+	"context"
+	"crypto/md5" //nolint:gosec
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/hbagdi/hit/pkg/model"
+)
+
+// digestNonceCount is the nonce count of the single request sent per
+// challenge.
+const digestNonceCount = "00000001"
+
+// parseDigestChallenge returns the parameters of the digest challenge among
+// the WWW-Authenticate headers of a response, if any.
+func parseDigestChallenge(headers []string) (map[string]string, bool) {
+	for _, header := range headers {
+		scheme, params, _ := strings.Cut(strings.TrimSpace(header), " ")
+		if !strings.EqualFold(scheme, "Digest") {
+			continue
+		}
+		return parseAuthParams(params), true
+	}
+	return nil, false
+}
+
+// parseAuthParams parses comma-separated 'key=value' parameters whose values
+// are optionally quoted.
+func parseAuthParams(s string) map[string]string {
+	res := map[string]string{}
+	for s != "" {
+		s = strings.TrimLeft(s, " ,")
+		key, rest, ok := strings.Cut(s, "=")
+		if !ok {
+			break
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		rest = strings.TrimLeft(rest, " ")
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			var b strings.Builder
+			i := 1
+			for ; i < len(rest) && rest[i] != '"'; i++ {
+				if rest[i] == '\\' && i+1 < len(rest) {
+					i++
+				}
+				b.WriteByte(rest[i])
+			}
+			if i < len(rest) {
+				// the closing quote
+				i++
+			}
+			value, s = b.String(), rest[i:]
+		} else {
+			value, s, _ = strings.Cut(rest, ",")
+			value = strings.TrimSpace(value)
+		}
+		res[key] = value
+	}
+	return res
+}
+
+// digestAuthorization returns the Authorization header answering challenge
+// for a request with method and uri.
+func digestAuthorization(challenge map[string]string, credentials model.Credentials,
+	method, uri, cnonce string,
+) (string, error) {
+	algorithm := challenge["algorithm"]
+	if algorithm == "" {
+		algorithm = "MD5"
+	}
+	var newHash func() hash.Hash
+	switch strings.TrimSuffix(strings.ToUpper(algorithm), "-SESS") {
+	case "MD5":
+		newHash = md5.New
+	case "SHA-256":
+		newHash = sha256.New
+	default:
+		return "", fmt.Errorf("unsupported digest algorithm '%v'", algorithm)
+	}
+	h := func(s string) string {
+		d := newHash()
+		_, _ = io.WriteString(d, s)
+		return hex.EncodeToString(d.Sum(nil))
+	}
+
+	realm, nonce := challenge["realm"], challenge["nonce"]
+	ha1 := h(credentials.Username + ":" + realm + ":" + credentials.Password)
+	if strings.HasSuffix(strings.ToUpper(algorithm), "-SESS") {
+		ha1 = h(ha1 + ":" + nonce + ":" + cnonce)
+	}
+	ha2 := h(method + ":" + uri)
+
+	var qop string
+	if challenge["qop"] != "" {
+		for _, q := range strings.Split(challenge["qop"], ",") {
+			if strings.TrimSpace(q) == "auth" {
+				qop = "auth"
+			}
+		}
+		if qop == "" {
+			return "", fmt.Errorf("unsupported digest qop '%v'", challenge["qop"])
+		}
+	}
+	var response string
+	if qop == "" {
+		response = h(ha1 + ":" + nonce + ":" + ha2)
+	} else {
+		response = h(strings.Join([]string{
+			ha1, nonce, digestNonceCount, cnonce, qop, ha2,
+		}, ":"))
+	}
+
+	res := fmt.Sprintf(`Digest username="%s", realm="%s", nonce="%s", uri="%s", `+
+		`algorithm=%s, response="%s"`, credentials.Username, realm, nonce, uri,
+		algorithm, response)
+	if qop != "" {
+		res += fmt.Sprintf(`, qop=%s, nc=%s, cnonce="%s"`, qop,
+			digestNonceCount, cnonce)
+	}
+	if opaque, ok := challenge["opaque"]; ok {
+		res += fmt.Sprintf(`, opaque="%s"`, opaque)
+	}
+	return res, nil
+}
+
+// authenticateDigest answers the digest challenge of resp, the response to
+// req, by sending req again with the credentials of req, signed once more as
+// its Authorization header changed. It returns resp and req unchanged if resp
+// has no digest challenge.
+func authenticateDigest(ctx context.Context, client *http.Client,
+	req model.Request, resp *http.Response,
+) (*http.Response, model.Request, error) {
+	challenge, ok := parseDigestChallenge(resp.Header.Values("WWW-Authenticate"))
+	if !ok {
+		return resp, req, nil
+	}
+	uri := resp.Request.URL.RequestURI()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	cnonce := make([]byte, 16) //nolint:gomnd
+	if _, err := rand.Read(cnonce); err != nil {
+		return nil, req, err
+	}
+	authorization, err := digestAuthorization(challenge, *req.Digest,
+		req.Method, uri, hex.EncodeToString(cnonce))
+	if err != nil {
+		return nil, req, err
+	}
+
+	req.Header = req.Header.Clone()
+	req.Header.Set("Authorization", authorization)
+	req, err = signRequest(req)
+	if err != nil {
+		return nil, req, err
+	}
+	httpRequest, err := httpRequestFromHitRequest(req)
+	if err != nil {
+		return nil, req, err
+	}
+	resp, err = client.Do(httpRequest.WithContext(ctx))
+	if err != nil {
+		return nil, req, fmt.Errorf("do request: %w", err)
+	}
+	return resp, req, nil
+}
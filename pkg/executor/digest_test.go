@@ -0,0 +1,51 @@
+package executor
+
+import (
+	"testing"
+
+	"github.com/hbagdi/hit/pkg/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDigestChallenge(t *testing.T) {
+	challenge, ok := parseDigestChallenge([]string{
+		`Basic realm="api"`,
+		`Digest realm="testrealm@host.com", qop="auth,auth-int", ` +
+			`nonce="dcd98b7102dd2f0e8b11d0f600bfb0c093", algorithm=MD5, ` +
+			`opaque="5ccc069c403ebaf9f0171e9517f40e41"`,
+	})
+	require.True(t, ok)
+	require.Equal(t, map[string]string{
+		"realm":     "testrealm@host.com",
+		"qop":       "auth,auth-int",
+		"nonce":     "dcd98b7102dd2f0e8b11d0f600bfb0c093",
+		"algorithm": "MD5",
+		"opaque":    "5ccc069c403ebaf9f0171e9517f40e41",
+	}, challenge)
+
+	_, ok = parseDigestChallenge([]string{`Bearer realm="api"`})
+	require.False(t, ok)
+}
+
+func TestDigestAuthorization(t *testing.T) {
+	// the example of RFC 2617
+	challenge := map[string]string{
+		"realm":  "testrealm@host.com",
+		"qop":    "auth,auth-int",
+		"nonce":  "dcd98b7102dd2f0e8b11d0f600bfb0c093",
+		"opaque": "5ccc069c403ebaf9f0171e9517f40e41",
+	}
+	credentials := model.Credentials{Username: "Mufasa", Password: "Circle Of Life"}
+	authorization, err := digestAuthorization(challenge, credentials, "GET",
+		"/dir/index.html", "0a4f113b")
+	require.Nil(t, err)
+	require.Equal(t, `Digest username="Mufasa", realm="testrealm@host.com", `+
+		`nonce="dcd98b7102dd2f0e8b11d0f600bfb0c093", uri="/dir/index.html", `+
+		`algorithm=MD5, response="6629fae49393a05397450978507c4ef1", qop=auth, `+
+		`nc=00000001, cnonce="0a4f113b", opaque="5ccc069c403ebaf9f0171e9517f40e41"`,
+		authorization)
+
+	challenge["algorithm"] = "SHA-512"
+	_, err = digestAuthorization(challenge, credentials, "GET", "/", "0a4f113b")
+	require.EqualError(t, err, "unsupported digest algorithm 'SHA-512'")
+}
@@ -0,0 +1,136 @@
+package executor
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hbagdi/hit/pkg/parser"
+)
+
+const progressInterval = 100 * time.Millisecond
+
+// outputPath returns the file the body of resp is written to for the output
+// option. If output is a directory, the name of the file is taken from the
+// Content-Disposition header, the URL or the request ID, in that order.
+func outputPath(output string, resp *http.Response, requestID string) string {
+	isDir := strings.HasSuffix(output, "/") || strings.HasSuffix(output, string(os.PathSeparator))
+	if info, err := os.Stat(output); err == nil && info.IsDir() {
+		isDir = true
+	}
+	if !isDir {
+		return output
+	}
+	var name string
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil {
+		name = filepath.Base(params["filename"])
+	}
+	if name == "" || name == "." || name == string(os.PathSeparator) {
+		name = path.Base(resp.Request.URL.Path)
+	}
+	if name == "" || name == "." || name == "/" {
+		name = requestID
+	}
+	return filepath.Join(output, name)
+}
+
+// download writes the body of resp to the file filename and reports the
+// progress to progress, if any. It returns the size of the body.
+func download(resp *http.Response, filename string, maxBodySize parser.Size,
+	progress io.Writer,
+) (int64, error) {
+	if err := checkContentLength(resp, maxBodySize); err != nil {
+		return 0, err
+	}
+	file, err := os.Create(filename)
+	if err != nil {
+		return 0, fmt.Errorf("create output file: %v", err)
+	}
+	var w io.Writer = file
+	var p *progressWriter
+	if progress != nil {
+		p = &progressWriter{w: progress, name: filename, total: resp.ContentLength}
+		w = io.MultiWriter(file, p)
+	}
+	n, err := copyBody(w, resp.Body, maxBodySize)
+	if p != nil {
+		p.done()
+	}
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("write output file: %v", closeErr)
+	}
+	if err != nil {
+		_ = os.Remove(filename)
+		return 0, err
+	}
+	return n, nil
+}
+
+func bodyTooLarge(maxBodySize parser.Size) error {
+	return fmt.Errorf("response body exceeds maxBodySize of %v", maxBodySize)
+}
+
+// checkContentLength fails early for responses announcing a body larger
+// than maxBodySize.
+func checkContentLength(resp *http.Response, maxBodySize parser.Size) error {
+	if maxBodySize > 0 && resp.ContentLength > int64(maxBodySize) {
+		return bodyTooLarge(maxBodySize)
+	}
+	return nil
+}
+
+// copyBody copies body to dst and fails if it is larger than maxBodySize,
+// zero means no limit.
+func copyBody(dst io.Writer, body io.Reader, maxBodySize parser.Size) (int64, error) {
+	if maxBodySize <= 0 {
+		return io.Copy(dst, body)
+	}
+	n, err := io.Copy(dst, io.LimitReader(body, int64(maxBodySize)+1))
+	if err != nil {
+		return n, err
+	}
+	if n > int64(maxBodySize) {
+		return n, bodyTooLarge(maxBodySize)
+	}
+	return n, nil
+}
+
+// progressWriter counts the bytes written to it and reports them to w at
+// most every progressInterval.
+type progressWriter struct {
+	w       io.Writer
+	name    string
+	total   int64
+	written int64
+	last    time.Time
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	p.written += int64(len(b))
+	if time.Since(p.last) >= progressInterval {
+		p.report()
+		p.last = time.Now()
+	}
+	return len(b), nil
+}
+
+func (p *progressWriter) report() {
+	if p.total > 0 {
+		fmt.Fprintf(p.w, "\rdownloading %s: %v of %v (%d%%)", p.name,
+			parser.Size(p.written), parser.Size(p.total),
+			p.written*100/p.total) //nolint:gomnd
+		return
+	}
+	fmt.Fprintf(p.w, "\rdownloading %s: %v", p.name, parser.Size(p.written))
+}
+
+func (p *progressWriter) done() {
+	p.report()
+	fmt.Fprintln(p.w)
+}
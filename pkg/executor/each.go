@@ -0,0 +1,106 @@
+package executor
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// Items returns the items a request is executed for once each. Each item
+// is the list of positional arguments it is bound to, starting at '@1'.
+// source is either a reference such as '@list-nodes.items.#.id' to an array
+// of the cached response of a request, a JSON file holding an array or a
+// CSV file whose rows are items.
+func (e *Executor) Items(source string) ([][]string, error) {
+	if strings.HasPrefix(source, "@") {
+		return e.responseItems(source)
+	}
+	switch strings.ToLower(filepath.Ext(source)) {
+	case ".json":
+		js, err := os.ReadFile(source)
+		if err != nil {
+			return nil, fmt.Errorf("read items: %v", err)
+		}
+		if !gjson.ValidBytes(js) {
+			return nil, fmt.Errorf("read items: '%v' is not valid JSON", source)
+		}
+		return arrayItems(gjson.ParseBytes(js), "'"+source+"'")
+	case ".csv":
+		f, err := os.Open(source)
+		if err != nil {
+			return nil, fmt.Errorf("read items: %v", err)
+		}
+		defer f.Close()
+		r := csv.NewReader(f)
+		// rows may have a varying number of arguments
+		r.FieldsPerRecord = -1
+		rows, err := r.ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("read items of '%v': %v", source, err)
+		}
+		return rows, nil
+	default:
+		return nil, fmt.Errorf("invalid items '%v': expected a reference "+
+			"such as '@request-id.path', a .json file or a .csv file", source)
+	}
+}
+
+func (e *Executor) responseItems(source string) ([][]string, error) {
+	const splitN = 2
+	splits := strings.SplitN(source[1:], ".", splitN)
+	if len(splits) != splitN || splits[1] == "" {
+		return nil, fmt.Errorf("invalid reference: '%v'", source)
+	}
+	hit, ok, err := e.cache.Hit(splits[0])
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("no response of '@%v' to resolve '%v': "+
+			"execute '@%v' first", splits[0], source, splits[0])
+	}
+	return arrayItems(gjson.GetBytes(hit.Response.Body, splits[1]), "'"+source+"'")
+}
+
+// arrayItems returns the items of the array value described by name.
+// Scalar elements are bound to '@1', the scalars of array elements are
+// bound to '@1' onwards.
+func arrayItems(value gjson.Result, name string) ([][]string, error) {
+	if !value.IsArray() {
+		return nil, fmt.Errorf("%v is not an array", name)
+	}
+	var res [][]string
+	for i, element := range value.Array() {
+		values := []gjson.Result{element}
+		if element.IsArray() {
+			values = element.Array()
+		}
+		item := make([]string, 0, len(values))
+		for _, v := range values {
+			if v.IsObject() || v.IsArray() || v.Type == gjson.Null {
+				return nil, fmt.Errorf("item %d of %v is %v, expected a "+
+					"string, number or boolean: select the values of "+
+					"objects with a path such as 'items.#.id'", i+1, name,
+					describe(v))
+			}
+			item = append(item, v.String())
+		}
+		res = append(res, item)
+	}
+	return res, nil
+}
+
+func describe(v gjson.Result) string {
+	switch {
+	case v.IsObject():
+		return "an object"
+	case v.IsArray():
+		return "an array"
+	default:
+		return "null"
+	}
+}
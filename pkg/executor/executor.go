@@ -3,44 +3,73 @@ package executor
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
-	"net/url"
+	"net/http/httptrace"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/hbagdi/hit/pkg/cache"
+	"github.com/hbagdi/hit/pkg/log"
 	"github.com/hbagdi/hit/pkg/model"
 	"github.com/hbagdi/hit/pkg/parser"
 	"github.com/hbagdi/hit/pkg/request"
+	"github.com/hbagdi/hit/pkg/sign"
+	"go.uber.org/zap"
 )
 
-const timeout = 10 * time.Second
+const (
+	defaultTimeout      = 10 * time.Second
+	defaultMaxRedirects = 10
+)
 
 type Executor struct {
-	files      []parser.File
-	global     parser.Global
-	cache      cache.Cache
-	httpClient *http.Client
+	files       []parser.File
+	global      parser.Global
+	cache       cache.Cache
+	overrides   parser.Options
+	environment string
+	stream      StreamHandler
+	progress    io.Writer
+
+	jarMu sync.Mutex
+	jar   *persistentJar
+
+	transportsMu sync.Mutex
+	// transports are the round trippers by their JSON-encoded
+	// transportOptions.
+	transports map[string]idleRoundTripper
 }
 
 type Opts struct {
 	Cache cache.Cache
+	// Options override the options of every request, these are usually set
+	// on the command line.
+	Options parser.Options
+	// Environment is the name of the environment of the @_global section
+	// to use, if any.
+	Environment string
+	// Stream receives the responses of requests with streaming enabled as
+	// they arrive.
+	Stream StreamHandler
+	// Progress receives the progress of responses written to a file, if
+	// set.
+	Progress io.Writer
 }
 
 func NewExecutor(opts *Opts) (*Executor, error) {
-	client := &http.Client{
-		CheckRedirect: func(_ *http.Request, _ []*http.Request) error {
-			return http.ErrUseLastResponse
-		},
-	}
-
-	e := &Executor{
-		httpClient: client,
-	}
+	e := &Executor{}
 	if opts != nil {
 		e.cache = opts.Cache
+		e.overrides = opts.Options
+		e.environment = opts.Environment
+		e.stream = opts.Stream
+		e.progress = opts.Progress
 	}
 
 	return e, nil
@@ -53,32 +82,68 @@ func (e *Executor) LoadFiles() error {
 	}
 	e.files = files
 
-	global, err := fetchGlobal(e.files)
+	global, err := FetchGlobal(e.files)
 	if err != nil {
 		return err
 	}
-	e.global = global
-	return nil
+	e.global, err = applyEnvironment(global, e.environment)
+	return err
+}
+
+// applyEnvironment overrides the settings of global with the environment
+// named name.
+func applyEnvironment(global parser.Global, name string) (parser.Global, error) {
+	if name == "" {
+		return global, nil
+	}
+	env, ok := global.Environments[name]
+	if !ok {
+		return parser.Global{}, fmt.Errorf("environment '%v' not defined "+
+			"in @_global", name)
+	}
+	global.Options = global.Options.Merge(env)
+	return global, nil
 }
 
 func validateGlobal(g parser.Global) error {
 	if g.Version != 0 && g.Version != 1 {
 		return fmt.Errorf("invalid hit file version '%v'", g.Version)
 	}
-	if g.BaseURL != "" {
-		u, err := url.Parse(g.BaseURL)
-		if err != nil {
-			return fmt.Errorf("invalid baseURL '%v': %v", g.BaseURL, err)
+	if err := validateBaseURL(g.BaseURL); err != nil {
+		return err
+	}
+	for name, env := range g.Environments {
+		if err := validateBaseURL(env.BaseURL); err != nil {
+			return fmt.Errorf("environment '%v': %v", name, err)
 		}
-		if u.Scheme != "http" && u.Scheme != "https" {
-			return fmt.Errorf("invalid scheme '%v': only 'http' "+
-				"or 'https' is supported", u.Scheme)
+	}
+	return nil
+}
+
+func validateBaseURL(baseURL string) error {
+	if baseURL == "" {
+		return nil
+	}
+	u, err := model.ParseURL(baseURL)
+	if err != nil {
+		return fmt.Errorf("invalid baseURL '%v': %v", baseURL, err)
+	}
+	switch u.Scheme {
+	case "http", "https":
+	case model.SchemeHTTPUnix:
+		if u.Host == "" {
+			return fmt.Errorf("invalid baseURL '%v': expected the "+
+				"percent-encoded path of a Unix socket as the host", baseURL)
 		}
+	default:
+		return fmt.Errorf("invalid scheme '%v': only 'http', 'https' "+
+			"or '%v' is supported", u.Scheme, model.SchemeHTTPUnix)
 	}
 	return nil
 }
 
-func fetchGlobal(files []parser.File) (parser.Global, error) {
+// FetchGlobal validates and merges the @_global sections of files.
+func FetchGlobal(files []parser.File) (parser.Global, error) {
 	var res parser.Global
 	for _, file := range files {
 		if err := validateGlobal(file.Global); err != nil {
@@ -87,12 +152,18 @@ func fetchGlobal(files []parser.File) (parser.Global, error) {
 		if file.Global.Version == 1 {
 			res.Version = 1
 		}
-		if res.BaseURL == "" && file.Global.BaseURL != "" {
-			res.BaseURL = file.Global.BaseURL
-		}
 		if res.Headers == nil && file.Global.Headers != nil {
 			res.Headers = file.Global.Headers
 		}
+		res.Options = file.Global.Options.Merge(res.Options)
+		for name, env := range file.Global.Environments {
+			if res.Environments == nil {
+				res.Environments = map[string]parser.Options{}
+			}
+			if _, ok := res.Environments[name]; !ok {
+				res.Environments[name] = env
+			}
+		}
 	}
 	if res.Version != 1 {
 		return parser.Global{}, fmt.Errorf("no global.version")
@@ -133,6 +204,9 @@ func (e *Executor) fetchRequest(id string) (parser.Request, error) {
 
 type RequestOpts struct {
 	Params []string
+	// Literals is the number of Params from '@1' onwards which are values,
+	// even if they begin with '@'.
+	Literals int
 }
 
 func (e *Executor) BuildRequest(id string, opts *RequestOpts) (model.Request, error) {
@@ -143,10 +217,24 @@ func (e *Executor) BuildRequest(id string, opts *RequestOpts) (model.Request, er
 	if opts == nil {
 		opts = &RequestOpts{}
 	}
+	requestOptions := e.requestOptions(id)
+	global := e.global
+	global.BaseURL = requestOptions.BaseURL
+	if err := validateBaseURL(global.BaseURL); err != nil {
+		return model.Request{}, err
+	}
+	variables, err := e.Variables()
+	if err != nil {
+		return model.Request{}, err
+	}
 	request, err := request.Generate(parserRequest, request.Options{
-		GlobalContext: e.global,
+		GlobalContext: global,
 		Cache:         e.cache,
 		Args:          opts.Params,
+		Literals:      opts.Literals,
+		Auth:          requestOptions.Auth,
+		Sign:          requestOptions.Sign,
+		Variables:     variables,
 	})
 	if err != nil {
 		return model.Request{}, fmt.Errorf("failed to build request: %v", err)
@@ -154,55 +242,393 @@ func (e *Executor) BuildRequest(id string, opts *RequestOpts) (model.Request, er
 	return request, nil
 }
 
+// Close closes the idle connections of the executor.
 func (e *Executor) Close() error {
+	e.transportsMu.Lock()
+	defer e.transportsMu.Unlock()
+	for _, t := range e.transports {
+		t.CloseIdleConnections()
+	}
+	e.transports = nil
 	return nil
 }
 
+// requestOptions returns the options of request id merged with the global
+// options and the overrides of the executor.
+func (e *Executor) requestOptions(id string) parser.Options {
+	opts := e.global.Options
+	if r, err := e.fetchRequest(id); err == nil {
+		opts = opts.Merge(r.Options)
+	}
+	return opts.Merge(e.overrides)
+}
+
 func (e *Executor) Execute(ctx context.Context, requestID string, req model.Request) (model.Hit, error) {
-	var err error
+	opts := e.requestOptions(requestID)
+	if req.OAuth2 != nil {
+		transport, err := e.transport(requestID, opts, false)
+		if err != nil {
+			return model.Hit{}, err
+		}
+		req, err = e.authenticateOAuth2(ctx, req, transport)
+		if err != nil {
+			return model.Hit{}, err
+		}
+	}
+	var jar http.CookieJar
+	if cookiesEnabled(opts) {
+		var err error
+		jar, err = e.cookieJar()
+		if err != nil {
+			return model.Hit{}, err
+		}
+	}
+	switch req.Method {
+	case model.MethodWebSocket:
+		return e.executeWebSocket(ctx, requestID, req, opts, jar)
+	case model.MethodGRPC:
+		return e.executeGRPC(ctx, requestID, req, opts)
+	}
+	protocol, err := requestProtocol(opts, req.Scheme)
+	if err != nil {
+		return model.Hit{}, err
+	}
+	roundTripper, err := e.transport(requestID, opts, protocol == protocolH2C)
+	if err != nil {
+		return model.Hit{}, err
+	}
+	policy := newRetryPolicy(opts.Retry)
+	var (
+		stream  StreamHandler
+		started *startedStreamHandler
+	)
+	if opts.Stream != nil && *opts.Stream {
+		started = &startedStreamHandler{StreamHandler: e.stream}
+		if e.stream == nil {
+			started.StreamHandler = nopStreamHandler{}
+		}
+		stream = started
+	}
 
-	httpRequest, err := httpRequestFromHitRequest(req)
+	var attempts []model.Attempt
+	for n := 1; ; n++ {
+		start := time.Now()
+		hit, err := e.attempt(ctx, model.Hit{
+			HitRequestID: requestID,
+			Kind:         model.HitKindHTTP,
+			Attempts:     attempts,
+		}, req, opts, roundTripper, jar, stream)
+		a := model.Attempt{Duration: time.Since(start)}
+		if err != nil {
+			a.Error = err.Error()
+		} else {
+			a.Code = hit.Response.Code
+		}
+		// streamed responses are not retried once passed on, even if they
+		// fail partway, as their output can't be taken back
+		retry := n < policy.maxAttempts && ctx.Err() == nil &&
+			(started == nil || !started.started) && policy.retryable(req.Method, a.Code, err)
+		if retry {
+			a.Delay = policy.delay(n, hit.Response.Header)
+		}
+		attempts = append(attempts, a)
+		if !retry {
+			if err != nil {
+				if n > 1 {
+					return model.Hit{}, fmt.Errorf("%v (after %d attempts)", err, n)
+				}
+				return model.Hit{}, err
+			}
+			hit.Attempts = attempts
+			if err := e.cache.Save(hit); err != nil {
+				return model.Hit{}, fmt.Errorf("save response: %v", err)
+			}
+			return hit, nil
+		}
+		select {
+		case <-ctx.Done():
+			return model.Hit{}, fmt.Errorf("retry request: %v", ctx.Err())
+		case <-time.After(a.Delay):
+		}
+	}
+}
+
+func requestTimeout(opts parser.Options) time.Duration {
+	if opts.Timeout != nil {
+		return time.Duration(*opts.Timeout)
+	}
+	return defaultTimeout
+}
+
+// headerTimeout returns true if the timeout of requests with opts only
+// applies to receiving the response headers. The body of streams and
+// downloads may take any time to arrive.
+func headerTimeout(opts parser.Options) bool {
+	return (opts.Stream != nil && *opts.Stream) || opts.Output != ""
+}
+
+// attempt executes req once using transport and returns hit with the
+// request and response. Cookies are sent from and stored in jar, if any.
+// Responses are passed on to stream, if any, as they arrive.
+func (e *Executor) attempt(ctx context.Context, hit model.Hit, req model.Request,
+	opts parser.Options, transport http.RoundTripper, jar http.CookieJar,
+	stream StreamHandler,
+) (model.Hit, error) {
+	signed, err := signRequest(req)
+	if err != nil {
+		return model.Hit{}, err
+	}
+	httpRequest, err := httpRequestFromHitRequest(signed)
 	if err != nil {
 		return model.Hit{}, err
 	}
-	ctx, cancel := context.WithTimeout(ctx, timeout)
-	defer cancel()
+	// the timeout of streams and downloads is enforced by the transport as
+	// it only applies to the response headers
+	if timeout := requestTimeout(opts); timeout > 0 && !headerTimeout(opts) {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	var proxy string
+	ctx = withProxyRecorder(ctx, &proxy)
+	tracer := newTracer()
+	ctx = httptrace.WithClientTrace(ctx, tracer.clientTrace())
 	httpRequest = httpRequest.WithContext(ctx)
 
-	resp, err := e.httpClient.Do(httpRequest)
+	var redirects []model.Redirect
+	httpClient := &http.Client{
+		Transport:     transport,
+		CheckRedirect: checkRedirect(opts, &redirects),
+	}
+	var cookies []*http.Cookie
+	if jar != nil {
+		httpClient.Jar = jar
+		cookies = jar.Cookies(httpRequest.URL)
+	}
+	resp, err := httpClient.Do(httpRequest)
 	if err != nil {
-		return model.Hit{}, fmt.Errorf("do request: %v", err)
+		return model.Hit{}, fmt.Errorf("do request: %w", err)
 	}
-	updatedRequest := req
-	updatedRequest.Proto = httpRequest.Proto
+	if resp.StatusCode == http.StatusUnauthorized && req.Digest != nil {
+		resp, signed, err = authenticateDigest(ctx, httpClient, signed, resp)
+		if err != nil {
+			return model.Hit{}, fmt.Errorf("digest auth: %w", err)
+		}
+	}
+	defer resp.Body.Close()
+	if err := checkProtocol(opts.Protocol, resp); err != nil {
+		return model.Hit{}, err
+	}
+	hit.Request = withCookies(signed, cookies)
+	// the request is sent with the protocol of the response
+	hit.Request.Proto = resp.Proto
+	hit.Redirects = redirects
+	_, hit.Network = tracer.result()
+	hit.Proxy = proxy
+	if resp.TLS != nil {
+		hit.TLS = model.TLS{
+			Version:     tlsVersionName(resp.TLS.Version),
+			CipherSuite: tls.CipherSuiteName(resp.TLS.CipherSuite),
+		}
+	}
+
+	var maxBodySize parser.Size
+	if opts.MaxBodySize != nil {
+		maxBodySize = *opts.MaxBodySize
+	}
+	switch {
+	case opts.Output != "":
+		hit.Response = hitResponseHead(resp)
+		filename := outputPath(opts.Output, resp, hit.HitRequestID)
+		hit.Response.BodySize, err = download(resp, filename, maxBodySize, e.progress)
+		if err != nil {
+			return model.Hit{}, fmt.Errorf("download response: %v", err)
+		}
+		hit.Response.BodyFile = filename
+	case stream != nil:
+		hit.Response = hitResponseHead(resp)
+		if err := stream.StreamStart(hit); err != nil {
+			return model.Hit{}, err
+		}
+		maxEvents := 0
+		if opts.MaxEvents != nil {
+			maxEvents = *opts.MaxEvents
+		}
+		hit.Response.Body, err = readStream(ctx, resp.Body, resp.Header,
+			maxEvents, stream)
+		if err != nil {
+			return model.Hit{}, fmt.Errorf("read stream: %w", err)
+		}
+		hit.Response.BodySize = int64(len(hit.Response.Body))
+	default:
+		hit.Response, err = hitResponseFromHitRequest(resp, maxBodySize)
+		if err != nil {
+			return model.Hit{}, fmt.Errorf("read response: %w", err)
+		}
+	}
+	tracer.done()
+	hit.Latency, _ = tracer.result()
+	return hit, nil
+}
 
-	hitResponse, err := hitResponseFromHitRequest(resp)
+// signRequest returns req signed as configured by its Signing. Requests are
+// signed right before they are sent, once every header is set, for every
+// attempt to carry a fresh signature.
+func signRequest(req model.Request) (model.Request, error) {
+	if err := sign.Sign(&req, time.Now()); err != nil {
+		return model.Request{}, fmt.Errorf("sign request: %v", err)
+	}
+	return req, nil
+}
+
+// transportOptions are the options a transport is configured with.
+type transportOptions struct {
+	ResponseHeaderTimeout time.Duration
+	Proxy                 string
+	NoProxy               string
+	TLS                   *parser.TLSOptions
+	Resolve               map[string]string
+	Protocol              string
+	H2C                   bool
+}
+
+// transport returns the round tripper executing request id with opts,
+// speaking HTTP/2 without TLS if h2c is set. Requests with the same
+// transport options share a round tripper to reuse its connections, which
+// are closed by Close.
+func (e *Executor) transport(id string, opts parser.Options, h2c bool) (http.RoundTripper, error) {
+	key := transportOptions{
+		Proxy:    opts.Proxy,
+		NoProxy:  opts.NoProxy,
+		TLS:      opts.TLS,
+		Resolve:  opts.Resolve,
+		Protocol: opts.Protocol,
+		H2C:      h2c,
+	}
+	if headerTimeout(opts) {
+		key.ResponseHeaderTimeout = requestTimeout(opts)
+	}
+	js, err := json.Marshal(key)
 	if err != nil {
-		return model.Hit{}, err
+		return nil, fmt.Errorf("marshal transport options: %v", err)
 	}
 
-	hit := model.Hit{
-		HitRequestID: requestID,
-		Request:      updatedRequest,
-		Response:     hitResponse,
+	e.transportsMu.Lock()
+	defer e.transportsMu.Unlock()
+	if t, ok := e.transports[string(js)]; ok {
+		return t, nil
+	}
+	transport, err := newTransport(id, opts)
+	if err != nil {
+		return nil, err
+	}
+	var res idleRoundTripper = transport
+	if h2c {
+		res = h2cTransport(transport.Transport)
+	}
+	if e.transports == nil {
+		e.transports = map[string]idleRoundTripper{}
 	}
+	e.transports[string(js)] = res
+	return res, nil
+}
+
+// idleRoundTripper is a round tripper keeping idle connections open for
+// later requests.
+type idleRoundTripper interface {
+	http.RoundTripper
+	CloseIdleConnections()
+}
+
+// httpTransport is a transport which also executes requests over Unix
+// sockets.
+type httpTransport struct {
+	*http.Transport
+	unix unixRoundTripper
+}
 
-	err = e.cache.Save(hit)
+func (t *httpTransport) CloseIdleConnections() {
+	t.Transport.CloseIdleConnections()
+	t.unix.transport.CloseIdleConnections()
+}
+
+// newTransport returns the transport used to execute request id with opts.
+// The proxy used, if any, is recorded as configured by withProxyRecorder.
+func newTransport(id string, opts parser.Options) (*httpTransport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if headerTimeout(opts) {
+		transport.ResponseHeaderTimeout = requestTimeout(opts)
+	}
+	var err error
+	transport.Proxy, err = proxyFunc(opts)
 	if err != nil {
-		return model.Hit{}, fmt.Errorf("save response: %v", err)
+		return nil, err
+	}
+	if opts.TLS != nil {
+		config, err := tlsConfig(*opts.TLS)
+		if err != nil {
+			return nil, err
+		}
+		if config.InsecureSkipVerify {
+			log.Logger.Warn("TLS certificate verification is disabled, "+
+				"the connection is INSECURE", zap.String("request", id))
+		}
+		transport.TLSClientConfig = config
 	}
+	if len(opts.Resolve) > 0 {
+		transport.DialContext = resolveDialer(opts.Resolve, transport.DialContext)
+	}
+	configureProtocol(transport, opts.Protocol)
+	unix := newUnixRoundTripper(transport)
+	transport.RegisterProtocol(model.SchemeHTTPUnix, unix)
+	return &httpTransport{Transport: transport, unix: unix}, nil
+}
 
-	return hit, nil
+// checkRedirect returns a redirect policy for opts which records every
+// redirect followed in redirects.
+func checkRedirect(opts parser.Options, redirects *[]model.Redirect) func(*http.Request, []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if opts.FollowRedirects == nil || !*opts.FollowRedirects {
+			return http.ErrUseLastResponse
+		}
+		maxRedirects := defaultMaxRedirects
+		if opts.MaxRedirects != nil {
+			maxRedirects = *opts.MaxRedirects
+		}
+		if len(via) > maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+		*redirects = append(*redirects, model.Redirect{
+			URL:      via[len(via)-1].URL.String(),
+			Code:     req.Response.StatusCode,
+			Location: req.URL.String(),
+		})
+		return nil
+	}
 }
 
 func httpRequestFromHitRequest(req model.Request) (*http.Request, error) {
 	body := bytes.NewReader(req.Body)
 
-	httpRequest, err := http.NewRequest(req.Method, req.URL(), body) //nolint:noctx
+	u, err := model.ParseURL(req.URL())
+	if err != nil {
+		return nil, fmt.Errorf("create HTTP request: %w", err)
+	}
+	// the URL is set after creating the request as http.NewRequest can't
+	// parse URLs of Unix sockets
+	httpRequest, err := http.NewRequest(req.Method, "", body) //nolint:noctx
 	if err != nil {
 		return nil, fmt.Errorf("create HTTP request: %w", err)
 	}
+	httpRequest.URL = u
+	httpRequest.Host = u.Host
 	for key, values := range req.Header {
+		// the Host header is sent from the Host field of the request, the
+		// whitespace following the colon isn't part of the host
+		if http.CanonicalHeaderKey(key) == "Host" {
+			httpRequest.Host = strings.Trim(values[0], " \t")
+			continue
+		}
 		if httpRequest.Header.Get(key) == "" {
 			for _, value := range values {
 				httpRequest.Header.Add(key, value)
@@ -212,19 +638,50 @@ func httpRequestFromHitRequest(req model.Request) (*http.Request, error) {
 	return httpRequest, nil
 }
 
-func hitResponseFromHitRequest(resp *http.Response) (model.Response, error) {
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
+func hitResponseFromHitRequest(resp *http.Response, maxBodySize parser.Size) (model.Response, error) {
+	if err := checkContentLength(resp, maxBodySize); err != nil {
+		return model.Response{}, err
+	}
+	var body bytes.Buffer
+	if _, err := copyBody(&body, resp.Body, maxBodySize); err != nil {
 		return model.Response{}, err
 	}
 
+	res := hitResponseHead(resp)
+	res.Body = body.Bytes()
+	res.BodySize = int64(body.Len())
+	return res, nil
+}
+
+// hitResponseHead returns the response without its body.
+func hitResponseHead(resp *http.Response) model.Response {
 	return model.Response{
 		Proto:  resp.Proto,
 		Code:   resp.StatusCode,
 		Status: resp.Status,
 		Header: resp.Header.Clone(),
-		Body:   body,
-	}, nil
+	}
+}
+
+// Assertions returns the assertions of request id, nil if it has none.
+func (e *Executor) Assertions(id string) (*parser.Assertions, error) {
+	r, err := e.fetchRequest(id)
+	if err != nil {
+		return nil, err
+	}
+	return r.Assertions, nil
+}
+
+// Flow returns the flow name defined in a '@_flow' section.
+func (e *Executor) Flow(name string) (parser.Flow, error) {
+	for _, file := range e.files {
+		for _, f := range file.Flows {
+			if f.Name == name {
+				return f, nil
+			}
+		}
+	}
+	return parser.Flow{}, fmt.Errorf("flow '%v' not found", name)
 }
 
 func (e *Executor) AllRequestIDs() ([]string, error) {
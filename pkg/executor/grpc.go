@@ -0,0 +1,284 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/hbagdi/hit/pkg/model"
+	"github.com/hbagdi/hit/pkg/parser"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/jhump/protoreflect/grpcreflect"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const grpcProto = "gRPC"
+
+// SplitGRPCMethod splits the full name of a gRPC method such as
+// '/grpc.health.v1.Health/Check' into the service and the method name.
+func SplitGRPCMethod(name string) (string, string, error) {
+	parts := strings.Split(strings.TrimPrefix(name, "/"), "/")
+	if !strings.HasPrefix(name, "/") || len(parts) != 2 || //nolint:gomnd
+		parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid gRPC method '%v': expected "+
+			"'/package.Service/Method'", name)
+	}
+	return parts[0], parts[1], nil
+}
+
+// grpcMetadataSkipped are the headers of a request which are not sent as
+// gRPC metadata.
+var grpcMetadataSkipped = map[string]bool{
+	"Host":         true,
+	"User-Agent":   true,
+	"Content-Type": true,
+}
+
+// executeGRPC calls the unary gRPC method named by the path of req with the
+// JSON message in its body. The headers of req are sent as metadata.
+func (e *Executor) executeGRPC(ctx context.Context, requestID string,
+	req model.Request, opts parser.Options,
+) (model.Hit, error) {
+	service, methodName, err := SplitGRPCMethod(req.Path)
+	if err != nil {
+		return model.Hit{}, err
+	}
+	if timeout := requestTimeout(opts); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	var proxy atomic.Value
+	conn, err := grpcDial(ctx, req, opts, &proxy)
+	if err != nil {
+		return model.Hit{}, err
+	}
+	defer conn.Close()
+
+	method, err := grpcMethod(ctx, conn, opts.GRPC, service, methodName)
+	if err != nil {
+		return model.Hit{}, err
+	}
+	if method.IsStreamingClient() || method.IsStreamingServer() {
+		return model.Hit{}, fmt.Errorf("gRPC method '%v' is a streaming "+
+			"method: only unary methods are supported", req.Path)
+	}
+	in := dynamicpb.NewMessage(method.Input())
+	if len(req.Body) > 0 {
+		if err := protojson.Unmarshal(req.Body, in); err != nil {
+			return model.Hit{}, fmt.Errorf("invalid message for '%v': %v",
+				method.Input().FullName(), err)
+		}
+	}
+	req, err = signRequest(req)
+	if err != nil {
+		return model.Hit{}, err
+	}
+	md := metadata.MD{}
+	for key, values := range req.Header {
+		if grpcMetadataSkipped[http.CanonicalHeaderKey(key)] {
+			continue
+		}
+		// metadata is sent as is, the whitespace following the colon
+		// isn't part of the value
+		for _, value := range values {
+			md.Append(key, strings.Trim(value, " \t"))
+		}
+	}
+	ctx = metadata.NewOutgoingContext(ctx, md)
+
+	var (
+		header, trailer metadata.MD
+		p               peer.Peer
+	)
+	out := dynamicpb.NewMessage(method.Output())
+	start := time.Now()
+	err = conn.Invoke(ctx, req.Path, in, out, grpc.Header(&header),
+		grpc.Trailer(&trailer), grpc.Peer(&p))
+	total := time.Since(start)
+	st, ok := status.FromError(err)
+	if !ok {
+		return model.Hit{}, fmt.Errorf("call gRPC method: %v", err)
+	}
+
+	hit := model.Hit{
+		HitRequestID: requestID,
+		Kind:         model.HitKindGRPC,
+		Request:      req,
+		Response:     grpcResponse(st, header, trailer),
+		Latency:      model.Latency{Total: total},
+	}
+	hit.Request.Proto = "HTTP/2.0"
+	hit.Proxy, _ = proxy.Load().(string)
+	if err == nil {
+		hit.Response.Body, err = grpcJSON(out)
+		if err != nil {
+			return model.Hit{}, err
+		}
+		hit.Response.BodySize = int64(len(hit.Response.Body))
+	}
+	if addr, ok := p.Addr.(*net.TCPAddr); ok {
+		hit.Network.IPInUse = addr.IP.String()
+		hit.Network.PortInUse = addr.Port
+	}
+	if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+		hit.TLS = model.TLS{
+			Version:     tlsVersionName(info.State.Version),
+			CipherSuite: tls.CipherSuiteName(info.State.CipherSuite),
+		}
+	}
+	if err := e.cache.Save(hit); err != nil {
+		return model.Hit{}, fmt.Errorf("save response: %v", err)
+	}
+	return hit, nil
+}
+
+// grpcDial returns a connection to the server of req. Servers with the
+// 'https' scheme are connected to with TLS. Connections are proxied like
+// HTTP requests, the URL of the proxy used is stored in proxy.
+func grpcDial(ctx context.Context, req model.Request, opts parser.Options,
+	proxy *atomic.Value,
+) (*grpc.ClientConn, error) {
+	target := req.Host
+	if req.Scheme == model.SchemeHTTPUnix {
+		target = "unix://" + req.Host
+	}
+	creds := insecure.NewCredentials()
+	if req.Scheme == "https" {
+		config := &tls.Config{} //nolint:gosec
+		if opts.TLS != nil {
+			var err error
+			config, err = tlsConfig(*opts.TLS)
+			if err != nil {
+				return nil, err
+			}
+		}
+		creds = credentials.NewTLS(config)
+	}
+	dialOpts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithUserAgent(req.Header.Get("User-Agent")),
+	}
+	if req.Scheme != model.SchemeHTTPUnix {
+		selectProxy, err := proxyFunc(opts)
+		if err != nil {
+			return nil, err
+		}
+		var d net.Dialer
+		dial := dialFunc(d.DialContext)
+		if len(opts.Resolve) > 0 {
+			dial = resolveDialer(opts.Resolve, dial)
+		}
+		dial = proxyDialer(selectProxy, req.Scheme, dial, proxy)
+		dialOpts = append(dialOpts, grpc.WithContextDialer(
+			func(ctx context.Context, addr string) (net.Conn, error) {
+				return dial(ctx, "tcp", addr)
+			}))
+	}
+	conn, err := grpc.DialContext(ctx, target, dialOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("connect to gRPC server: %v", err)
+	}
+	return conn, nil
+}
+
+// grpcMethod returns the descriptor of the method of service. The service is
+// described by the .proto files of opts or by the server reflection service
+// of the server of conn.
+func grpcMethod(ctx context.Context, conn *grpc.ClientConn,
+	opts *parser.GRPCOptions, service, method string,
+) (protoreflect.MethodDescriptor, error) {
+	var (
+		sd  *desc.ServiceDescriptor
+		err error
+	)
+	if opts != nil && len(opts.ProtoFiles) > 0 {
+		sd, err = protoFileService(opts, service)
+	} else {
+		client := grpcreflect.NewClientAuto(ctx, conn)
+		defer client.Reset()
+		sd, err = client.ResolveService(service)
+		if err != nil {
+			err = fmt.Errorf("resolve gRPC service '%v' via server "+
+				"reflection: %v", service, err)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	res := sd.UnwrapService().Methods().ByName(protoreflect.Name(method))
+	if res == nil {
+		return nil, fmt.Errorf("gRPC service '%v' has no method '%v'",
+			service, method)
+	}
+	return res, nil
+}
+
+// protoFileService returns the descriptor of service from the .proto files
+// of opts.
+func protoFileService(opts *parser.GRPCOptions, service string) (*desc.ServiceDescriptor, error) {
+	p := protoparse.Parser{ImportPaths: opts.ImportPaths}
+	files, err := p.ParseFiles(opts.ProtoFiles...)
+	if err != nil {
+		return nil, fmt.Errorf("parse .proto files: %v", err)
+	}
+	for _, file := range files {
+		if sd := file.FindService(service); sd != nil {
+			return sd, nil
+		}
+	}
+	return nil, fmt.Errorf("gRPC service '%v' not found in %v", service,
+		strings.Join(opts.ProtoFiles, ", "))
+}
+
+// grpcResponse returns the response of a call which ended with st. Header
+// and trailer metadata are the headers of the response.
+func grpcResponse(st *status.Status, header, trailer metadata.MD) model.Response {
+	res := model.Response{
+		Proto:  grpcProto,
+		Code:   int(st.Code()),
+		Status: fmt.Sprintf("%d %v", st.Code(), st.Code()),
+		Header: http.Header{},
+	}
+	if st.Message() != "" {
+		res.Status += ": " + st.Message()
+	}
+	for _, md := range []metadata.MD{header, trailer} {
+		for key, values := range md {
+			for _, value := range values {
+				res.Header.Add(key, value)
+			}
+		}
+	}
+	return res
+}
+
+// grpcJSON returns the compact JSON encoding of message.
+func grpcJSON(message *dynamicpb.Message) ([]byte, error) {
+	js, err := protojson.Marshal(message)
+	if err != nil {
+		return nil, fmt.Errorf("encode gRPC response as JSON: %v", err)
+	}
+	// protojson varies its whitespace
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, js); err != nil {
+		return nil, fmt.Errorf("encode gRPC response as JSON: %v", err)
+	}
+	return buf.Bytes(), nil
+}
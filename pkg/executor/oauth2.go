@@ -0,0 +1,174 @@
+package executor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/hbagdi/hit/pkg/log"
+	"github.com/hbagdi/hit/pkg/model"
+	"go.uber.org/zap"
+)
+
+const (
+	// oauth2ExpiryMargin is the time before their expiry tokens are
+	// refreshed.
+	oauth2ExpiryMargin = 30 * time.Second
+	// maxTokenResponseSize limits the size of responses of token endpoints.
+	maxTokenResponseSize = 1 << 20
+)
+
+// authenticateOAuth2 returns req with the OAuth2 access token of req sent in
+// the Authorization header. Headers set by the request take precedence.
+func (e *Executor) authenticateOAuth2(ctx context.Context, req model.Request,
+	transport http.RoundTripper,
+) (model.Request, error) {
+	if req.Header.Get("Authorization") != "" {
+		return req, nil
+	}
+	token, err := e.oauth2Token(ctx, *req.OAuth2, transport)
+	if err != nil {
+		return model.Request{}, err
+	}
+	req.Header = req.Header.Clone()
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Secrets = append(append([]string(nil), req.Secrets...), token)
+	return req, nil
+}
+
+// oauth2Token returns the access token for config. Cached tokens are used
+// until they are about to expire, they are then refreshed if possible.
+func (e *Executor) oauth2Token(ctx context.Context, config model.OAuth2,
+	transport http.RoundTripper,
+) (string, error) {
+	key, err := oauth2CacheKey(config)
+	if err != nil {
+		return "", err
+	}
+	cached, ok, err := e.cache.Token(key)
+	if err != nil {
+		return "", err
+	}
+	if ok && (cached.Expiry.IsZero() ||
+		time.Until(cached.Expiry) > oauth2ExpiryMargin) {
+		return cached.AccessToken, nil
+	}
+
+	var token model.Token
+	if ok && cached.RefreshToken != "" {
+		refresh := config
+		refresh.Grant = model.GrantRefreshToken
+		refresh.RefreshToken = cached.RefreshToken
+		token, err = fetchToken(ctx, refresh, transport)
+		if err != nil {
+			log.Logger.Debug("failed to refresh OAuth2 token", zap.Error(err))
+		}
+	}
+	if token.AccessToken == "" {
+		token, err = fetchToken(ctx, config, transport)
+		if err != nil {
+			return "", err
+		}
+	}
+	if token.RefreshToken == "" && ok {
+		// the refresh token remains valid
+		token.RefreshToken = cached.RefreshToken
+	}
+	if err := e.cache.SaveToken(key, token); err != nil {
+		return "", err
+	}
+	return token.AccessToken, nil
+}
+
+// oauth2CacheKey returns the key tokens for config are cached under.
+func oauth2CacheKey(config model.OAuth2) (string, error) {
+	js, err := json.Marshal(config)
+	if err != nil {
+		return "", fmt.Errorf("marshal OAuth2 config: %v", err)
+	}
+	sum := sha256.Sum256(js)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// tokenResponse is the response of a token endpoint, including errors.
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	RefreshToken     string `json:"refresh_token"`
+	ExpiresIn        int64  `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// fetchToken requests a token for the grant of config from its token
+// endpoint.
+func fetchToken(ctx context.Context, config model.OAuth2,
+	transport http.RoundTripper,
+) (model.Token, error) {
+	form := url.Values{"grant_type": {config.Grant}}
+	switch config.Grant {
+	case model.GrantPassword:
+		form.Set("username", config.Username)
+		form.Set("password", config.Password)
+	case model.GrantRefreshToken:
+		form.Set("refresh_token", config.RefreshToken)
+	}
+	if len(config.Scopes) > 0 {
+		form.Set("scope", strings.Join(config.Scopes, " "))
+	}
+	if config.ClientSecret == "" {
+		form.Set("client_id", config.ClientID)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		config.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return model.Token{}, fmt.Errorf("create OAuth2 token request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(config.ClientID),
+			url.QueryEscape(config.ClientSecret))
+	}
+
+	start := time.Now()
+	resp, err := (&http.Client{Transport: transport}).Do(req)
+	if err != nil {
+		return model.Token{}, fmt.Errorf("fetch OAuth2 token: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxTokenResponseSize))
+	if err != nil {
+		return model.Token{}, fmt.Errorf("fetch OAuth2 token: %v", err)
+	}
+	var tr tokenResponse
+	if err := json.Unmarshal(body, &tr); err != nil {
+		return model.Token{}, fmt.Errorf("fetch OAuth2 token: %v: invalid "+
+			"response: %v", resp.Status, err)
+	}
+	if tr.Error != "" {
+		if tr.ErrorDescription != "" {
+			tr.Error += ": " + tr.ErrorDescription
+		}
+		return model.Token{}, fmt.Errorf("fetch OAuth2 token: %v", tr.Error)
+	}
+	if resp.StatusCode != http.StatusOK || tr.AccessToken == "" {
+		return model.Token{}, fmt.Errorf("fetch OAuth2 token: %v: no access "+
+			"token in response", resp.Status)
+	}
+
+	token := model.Token{
+		AccessToken:  tr.AccessToken,
+		RefreshToken: tr.RefreshToken,
+	}
+	if tr.ExpiresIn > 0 {
+		token.Expiry = start.Add(time.Duration(tr.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
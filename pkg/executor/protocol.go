@@ -0,0 +1,110 @@
+package executor
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/hbagdi/hit/pkg/parser"
+	"golang.org/x/net/http2"
+)
+
+// Protocols of the protocol option.
+const (
+	// protocolAuto uses HTTP/2 if it is negotiated over TLS and HTTP/1.1
+	// otherwise.
+	protocolAuto  = "auto"
+	protocolHTTP1 = "http1"
+	// protocolHTTP2 requires HTTP/2 negotiated over TLS.
+	protocolHTTP2 = "http2"
+	// protocolH2C is HTTP/2 over cleartext TCP with prior knowledge.
+	protocolH2C = "h2c"
+)
+
+// requestProtocol returns the protocol of opts for a request with scheme.
+func requestProtocol(opts parser.Options, scheme string) (string, error) {
+	switch opts.Protocol {
+	case "", protocolAuto:
+		return protocolAuto, nil
+	case protocolHTTP1:
+	case protocolHTTP2:
+		if scheme != "https" {
+			return "", fmt.Errorf("protocol '%v' requires an 'https' URL, "+
+				"use '%v' for HTTP/2 without TLS", protocolHTTP2, protocolH2C)
+		}
+	case protocolH2C:
+		if scheme != "http" {
+			return "", fmt.Errorf("protocol '%v' requires an 'http' URL",
+				protocolH2C)
+		}
+	default:
+		return "", fmt.Errorf("invalid protocol '%v': only '%v', '%v', '%v' "+
+			"or '%v' is supported", opts.Protocol, protocolAuto, protocolHTTP1,
+			protocolHTTP2, protocolH2C)
+	}
+	return opts.Protocol, nil
+}
+
+// configureProtocol configures transport for protocol. It must be called
+// before transport is used.
+func configureProtocol(transport *http.Transport, protocol string) {
+	switch protocol {
+	case protocolHTTP1:
+		// a non-nil map disables HTTP/2
+		transport.ForceAttemptHTTP2 = false
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	case protocolHTTP2:
+		transport.ForceAttemptHTTP2 = true
+	}
+}
+
+// h2cTransport returns a transport speaking HTTP/2 without TLS over the
+// connections of transport. Proxies aren't supported, requests which would
+// be proxied fail.
+func h2cTransport(transport *http.Transport) h2cRoundTripper {
+	dial := transport.DialContext
+	return h2cRoundTripper{
+		Transport: &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string,
+				_ *tls.Config,
+			) (net.Conn, error) {
+				return dial(ctx, network, addr)
+			},
+		},
+		proxy: transport.Proxy,
+	}
+}
+
+// h2cRoundTripper sends requests over HTTP/2 without TLS.
+type h2cRoundTripper struct {
+	*http2.Transport
+	proxy func(*http.Request) (*url.URL, error)
+}
+
+func (t h2cRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.proxy != nil {
+		u, err := t.proxy(req)
+		if err != nil {
+			return nil, err
+		}
+		if u != nil {
+			return nil, fmt.Errorf("protocol '%v' does not support proxies, "+
+				"add '%v' to noProxy to connect directly", protocolH2C,
+				req.URL.Hostname())
+		}
+	}
+	return t.Transport.RoundTrip(req)
+}
+
+// checkProtocol fails if resp wasn't received over protocol.
+func checkProtocol(protocol string, resp *http.Response) error {
+	if protocol == protocolHTTP2 && resp.ProtoMajor != 2 { //nolint:gomnd
+		return fmt.Errorf("server did not negotiate HTTP/2, received %v",
+			resp.Proto)
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+package executor
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/hbagdi/hit/pkg/parser"
+	"golang.org/x/net/proxy"
+)
+
+// proxyKey is the context key of the string the URL of the proxy used by a
+// request is stored in.
+type proxyKey struct{}
+
+// withProxyRecorder returns ctx recording the URL of the proxy used by a
+// request with ctx in used, with the password redacted.
+func withProxyRecorder(ctx context.Context, used *string) context.Context {
+	return context.WithValue(ctx, proxyKey{}, used)
+}
+
+// proxyFunc returns the proxy selection of a transport for opts. The URL of
+// every proxy selected is recorded as configured by withProxyRecorder.
+func proxyFunc(opts parser.Options) (func(*http.Request) (*url.URL, error), error) {
+	var proxyURL *url.URL
+	if opts.Proxy != "" {
+		u, err := url.Parse(opts.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy '%v': %v", opts.Proxy, err)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("invalid proxy scheme '%v': only 'http', "+
+				"'https', 'socks5' or 'socks5h' is supported", u.Scheme)
+		}
+		proxyURL = u
+	}
+	noProxy := parseNoProxy(opts.NoProxy)
+	return func(req *http.Request) (*url.URL, error) {
+		if noProxy.matches(req.URL) {
+			return nil, nil //nolint:nilnil
+		}
+		u := proxyURL
+		if u == nil {
+			var err error
+			u, err = http.ProxyFromEnvironment(req)
+			if err != nil || u == nil {
+				return u, err
+			}
+		}
+		if used, ok := req.Context().Value(proxyKey{}).(*string); ok {
+			*used = u.Redacted()
+		}
+		return u, nil
+	}, nil
+}
+
+// noProxy is a list of hosts which are not proxied.
+type noProxy []string
+
+func parseNoProxy(s string) noProxy {
+	var res noProxy
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry != "" {
+			res = append(res, entry)
+		}
+	}
+	return res
+}
+
+// matches reports if u must not be proxied. Entries are hosts and domains
+// with an optional port, IP addresses, CIDR ranges or '*' to match
+// everything. Domains also match their subdomains.
+func (n noProxy) matches(u *url.URL) bool {
+	host, port := strings.ToLower(u.Hostname()), u.Port()
+	ip := net.ParseIP(host)
+	for _, entry := range n {
+		if entry == "*" {
+			return true
+		}
+		if _, cidr, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && cidr.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		entryHost, entryPort := entry, ""
+		if h, p, err := net.SplitHostPort(entry); err == nil {
+			entryHost, entryPort = h, p
+		}
+		if entryPort != "" && entryPort != port {
+			continue
+		}
+		if entryIP := net.ParseIP(entryHost); entryIP != nil {
+			if ip != nil && entryIP.Equal(ip) {
+				return true
+			}
+			continue
+		}
+		entryHost = strings.TrimPrefix(entryHost, ".")
+		if host == entryHost || strings.HasSuffix(host, "."+entryHost) {
+			return true
+		}
+	}
+	return false
+}
+
+// proxyDialer returns dial with the connections for which selectProxy
+// selects a proxy tunneled through it, using CONNECT for HTTP and HTTPS
+// proxies. Proxies are selected for URLs with scheme. The URL of the last
+// proxy used is stored in used, with the password redacted.
+func proxyDialer(selectProxy func(*http.Request) (*url.URL, error), scheme string,
+	dial dialFunc, used *atomic.Value,
+) dialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodConnect,
+			scheme+"://"+addr, nil)
+		if err != nil {
+			return nil, err
+		}
+		u, err := selectProxy(req)
+		if err != nil {
+			return nil, err
+		}
+		if u == nil {
+			return dial(ctx, network, addr)
+		}
+		used.Store(u.Redacted())
+		switch u.Scheme {
+		case "socks5", "socks5h":
+			return dialSOCKS5(ctx, u, network, addr)
+		default:
+			return dialConnect(ctx, u, addr)
+		}
+	}
+}
+
+// dialSOCKS5 connects to addr through the SOCKS5 proxy u.
+func dialSOCKS5(ctx context.Context, u *url.URL, network, addr string) (net.Conn, error) {
+	var auth *proxy.Auth
+	if u.User != nil {
+		password, _ := u.User.Password()
+		auth = &proxy.Auth{User: u.User.Username(), Password: password}
+	}
+	d, err := proxy.SOCKS5("tcp", proxyAddr(u), auth, proxy.Direct)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := d.(proxy.ContextDialer).DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, fmt.Errorf("connect to '%v' through proxy: %v", addr, err)
+	}
+	return conn, nil
+}
+
+// dialConnect connects to addr through the HTTP or HTTPS proxy u.
+func dialConnect(ctx context.Context, u *url.URL, addr string) (net.Conn, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", proxyAddr(u))
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	if u.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ //nolint:gosec
+			ServerName: u.Hostname(),
+		})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: http.Header{},
+	}
+	if u.User != nil {
+		password, _ := u.User.Password()
+		credentials := u.User.Username() + ":" + password
+		req.Header.Set("Proxy-Authorization", "Basic "+
+			base64.StdEncoding.EncodeToString([]byte(credentials)))
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("connect to '%v' through proxy: %v", addr,
+			resp.Status)
+	}
+	_ = conn.SetDeadline(time.Time{})
+	return bufferedConn{Conn: conn, r: r}, nil
+}
+
+// proxyAddr returns the host and port of the proxy u.
+func proxyAddr(u *url.URL) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+	port := "80"
+	switch u.Scheme {
+	case "https":
+		port = "443"
+	case "socks5", "socks5h":
+		port = "1080"
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
+
+// bufferedConn is a connection whose reads start with the data buffered
+// by r.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c bufferedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
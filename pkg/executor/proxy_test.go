@@ -0,0 +1,34 @@
+package executor
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNoProxyMatches(t *testing.T) {
+	noProxy := parseNoProxy("localhost, .internal,example.com:8443,10.0.0.0/8,::1")
+	for _, tc := range []struct {
+		url     string
+		matches bool
+	}{
+		{"http://localhost:8080/", true},
+		{"http://api.internal/", true},
+		{"http://internal/", true},
+		{"http://example.com:8443/", true},
+		{"http://example.com/", false},
+		{"http://www.example.com:8443/", true},
+		{"http://10.1.2.3/", true},
+		{"http://11.1.2.3/", false},
+		{"http://[::1]:80/", true},
+		{"http://notlocalhost/", false},
+	} {
+		u, err := url.Parse(tc.url)
+		require.NoError(t, err)
+		require.Equal(t, tc.matches, noProxy.matches(u), tc.url)
+	}
+	u, _ := url.Parse("http://anything/")
+	require.True(t, parseNoProxy("*").matches(u))
+	require.False(t, parseNoProxy("").matches(u))
+}
@@ -0,0 +1,48 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+)
+
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// resolveDialer returns dial with the addresses in resolve connected to
+// instead of the hosts they are mapped to. The host names remain in use for
+// TLS server name indication and certificate verification.
+func resolveDialer(resolve map[string]string, dial dialFunc) dialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		target, err := resolveAddr(resolve, addr)
+		if err != nil {
+			return nil, err
+		}
+		return dial(ctx, network, target)
+	}
+}
+
+// resolveAddr returns the address to connect to for addr, a host and port.
+// Entries of resolve for the host and port take precedence over entries for
+// the host only.
+func resolveAddr(resolve map[string]string, addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr, nil //nolint:nilerr
+	}
+	target, ok := resolve[strings.ToLower(addr)]
+	if !ok {
+		target, ok = resolve[strings.ToLower(host)]
+	}
+	if !ok {
+		return addr, nil
+	}
+	if _, _, err := net.SplitHostPort(target); err == nil {
+		return target, nil
+	}
+	if net.ParseIP(strings.Trim(target, "[]")) == nil {
+		return "", fmt.Errorf("invalid resolve address '%v' for '%v': "+
+			"expected an IP address with an optional port", target, addr)
+	}
+	return net.JoinHostPort(strings.Trim(target, "[]"), port), nil
+}
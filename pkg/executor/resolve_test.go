@@ -0,0 +1,32 @@
+package executor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveAddr(t *testing.T) {
+	resolve := map[string]string{
+		"api.example.com:443": "10.0.0.1",
+		"api.example.com":     "10.0.0.2",
+		"auth.example.com":    "10.0.0.3:8443",
+		"v6.example.com":      "::1",
+		"bad.example.com":     "not-an-ip",
+	}
+	for addr, expected := range map[string]string{
+		"api.example.com:443":  "10.0.0.1:443",
+		"API.example.com:443":  "10.0.0.1:443",
+		"api.example.com:80":   "10.0.0.2:80",
+		"auth.example.com:443": "10.0.0.3:8443",
+		"v6.example.com:80":    "[::1]:80",
+		"other.example.com:80": "other.example.com:80",
+	} {
+		target, err := resolveAddr(resolve, addr)
+		require.NoError(t, err)
+		require.Equal(t, expected, target, addr)
+	}
+	_, err := resolveAddr(resolve, "bad.example.com:80")
+	require.EqualError(t, err, "invalid resolve address 'not-an-ip' for "+
+		"'bad.example.com:80': expected an IP address with an optional port")
+}
@@ -0,0 +1,165 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/hbagdi/hit/pkg/parser"
+)
+
+const (
+	defaultBackoff    = 100 * time.Millisecond
+	defaultMaxBackoff = 10 * time.Second
+)
+
+var defaultRetryStatusCodes = []int{
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// retryPolicy decides if and when a failed attempt is retried.
+type retryPolicy struct {
+	maxAttempts int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+	statusCodes map[int]bool
+	// connectionErrors enables retries of connection errors of idempotent
+	// requests and of failures to connect.
+	connectionErrors bool
+	// allConnectionErrors enables retries of every connection error, even
+	// if a request which is not idempotent may have been sent.
+	allConnectionErrors bool
+}
+
+func newRetryPolicy(opts *parser.RetryOptions) retryPolicy {
+	res := retryPolicy{
+		maxAttempts:      1,
+		backoff:          defaultBackoff,
+		maxBackoff:       defaultMaxBackoff,
+		statusCodes:      map[int]bool{},
+		connectionErrors: true,
+	}
+	if opts == nil {
+		return res
+	}
+	if opts.MaxAttempts != nil {
+		res.maxAttempts = *opts.MaxAttempts
+	}
+	if opts.Backoff != nil {
+		res.backoff = time.Duration(*opts.Backoff)
+	}
+	if opts.MaxBackoff != nil {
+		res.maxBackoff = time.Duration(*opts.MaxBackoff)
+	}
+	statusCodes := defaultRetryStatusCodes
+	if opts.StatusCodes != nil {
+		statusCodes = opts.StatusCodes
+	}
+	for _, code := range statusCodes {
+		res.statusCodes[code] = true
+	}
+	if opts.ConnectionErrors != nil {
+		res.connectionErrors = *opts.ConnectionErrors
+		res.allConnectionErrors = *opts.ConnectionErrors
+	}
+	return res
+}
+
+// retryable reports if an attempt of a request with method which got
+// response code or error err is retried.
+func (p retryPolicy) retryable(method string, code int, err error) bool {
+	if err != nil {
+		if !p.connectionErrors || !isConnectionError(err) {
+			return false
+		}
+		return p.allConnectionErrors || idempotentMethods[method] ||
+			isDialError(err)
+	}
+	return p.statusCodes[code]
+}
+
+// idempotentMethods are the methods of requests which have the same effect
+// if they are sent again.
+var idempotentMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+	http.MethodTrace:   true,
+	http.MethodPut:     true,
+	http.MethodDelete:  true,
+}
+
+// delay returns the time to wait after the attempt-th attempt which
+// received header. The Retry-After header takes precedence over the
+// backoff. Delays are capped at the maximum backoff.
+func (p retryPolicy) delay(attempt int, header http.Header) time.Duration {
+	if d, ok := retryAfter(header); ok {
+		if d > p.maxBackoff {
+			return p.maxBackoff
+		}
+		return d
+	}
+	d := p.backoff
+	for i := 1; i < attempt && d < p.maxBackoff; i++ {
+		d *= 2
+	}
+	if d > p.maxBackoff {
+		d = p.maxBackoff
+	}
+	half := int64(d / 2) //nolint:gomnd
+	if half <= 0 {
+		return d
+	}
+	return d - time.Duration(rand.Int63n(half)) //nolint:gosec
+}
+
+// retryAfter parses the Retry-After header, either a number of seconds or
+// a date.
+func retryAfter(header http.Header) (time.Duration, bool) {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// isDialError reports if err is a failure to connect to the server, before
+// any part of the request is sent.
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// isConnectionError reports if err is an error connecting to the server,
+// sending the request or receiving the response.
+func isConnectionError(err error) bool {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		// url.Error is a net.Error itself, check the error it wraps
+		err = urlErr.Err
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,80 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hbagdi/hit/pkg/parser"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	backoff := parser.Duration(100 * time.Millisecond)
+	maxBackoff := parser.Duration(time.Second)
+	p := newRetryPolicy(&parser.RetryOptions{Backoff: &backoff, MaxBackoff: &maxBackoff})
+
+	for attempt, max := range map[int]time.Duration{
+		1:  100 * time.Millisecond,
+		2:  200 * time.Millisecond,
+		3:  400 * time.Millisecond,
+		5:  time.Second,
+		50: time.Second,
+	} {
+		d := p.delay(attempt, http.Header{})
+		require.LessOrEqual(t, d, max, attempt)
+		require.Greater(t, d, max/2, attempt)
+	}
+
+	header := http.Header{}
+	header.Set("Retry-After", "0")
+	require.Zero(t, p.delay(1, header))
+	header.Set("Retry-After", "120")
+	require.Equal(t, time.Second, p.delay(1, header))
+	header.Set("Retry-After", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	require.Equal(t, time.Second, p.delay(1, header))
+}
+
+func TestRetryPolicyDefaults(t *testing.T) {
+	p := newRetryPolicy(nil)
+	require.Equal(t, 1, p.maxAttempts)
+
+	p = newRetryPolicy(&parser.RetryOptions{})
+	require.True(t, p.retryable(http.MethodGet, http.StatusServiceUnavailable, nil))
+	require.False(t, p.retryable(http.MethodGet, http.StatusInternalServerError, nil))
+
+	p = newRetryPolicy(&parser.RetryOptions{StatusCodes: []int{500}})
+	require.True(t, p.retryable(http.MethodGet, http.StatusInternalServerError, nil))
+	require.False(t, p.retryable(http.MethodGet, http.StatusServiceUnavailable, nil))
+}
+
+func TestRetryConnectionErrors(t *testing.T) {
+	refused := &url.Error{Op: "Post", URL: "http://localhost", Err: &net.OpError{
+		Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED,
+	}}
+	timeout := &url.Error{Op: "Post", URL: "http://localhost",
+		Err: context.DeadlineExceeded}
+
+	p := newRetryPolicy(&parser.RetryOptions{})
+	require.True(t, p.retryable(http.MethodGet, 0, timeout))
+	require.True(t, p.retryable(http.MethodPut, 0, timeout))
+	require.True(t, p.retryable(http.MethodPost, 0, refused))
+	require.False(t, p.retryable(http.MethodPost, 0, timeout))
+	require.False(t, p.retryable(http.MethodPatch, 0, timeout))
+	require.False(t, p.retryable(http.MethodGet, 0, errors.New("invalid request")))
+
+	enabled := true
+	p = newRetryPolicy(&parser.RetryOptions{ConnectionErrors: &enabled})
+	require.True(t, p.retryable(http.MethodPost, 0, timeout))
+	require.True(t, p.retryable(http.MethodPatch, 0, timeout))
+
+	disabled := false
+	p = newRetryPolicy(&parser.RetryOptions{ConnectionErrors: &disabled})
+	require.False(t, p.retryable(http.MethodGet, 0, timeout))
+	require.False(t, p.retryable(http.MethodPost, 0, refused))
+}
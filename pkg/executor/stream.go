@@ -0,0 +1,155 @@
+package executor
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/hbagdi/hit/pkg/model"
+)
+
+const streamChunkSize = 32 * 1024
+
+// StreamHandler receives streamed responses as they arrive.
+type StreamHandler interface {
+	// StreamStart is called once the response headers are received, the
+	// body of the response of hit is empty.
+	StreamStart(hit model.Hit) error
+	// StreamChunk is called with every chunk of a response which is not an
+	// event stream.
+	StreamChunk(chunk []byte) error
+	// StreamEvent is called with every event of an event stream.
+	StreamEvent(event model.Event) error
+	// StreamMessage is called with every message sent or received during a
+	// WebSocket session.
+	StreamMessage(message model.Message) error
+}
+
+type nopStreamHandler struct{}
+
+func (nopStreamHandler) StreamStart(model.Hit) error       { return nil }
+func (nopStreamHandler) StreamChunk([]byte) error          { return nil }
+func (nopStreamHandler) StreamEvent(model.Event) error     { return nil }
+func (nopStreamHandler) StreamMessage(model.Message) error { return nil }
+
+// startedStreamHandler passes streamed responses on to StreamHandler and
+// records whether a response has been passed on.
+type startedStreamHandler struct {
+	StreamHandler
+	started bool
+}
+
+func (h *startedStreamHandler) StreamStart(hit model.Hit) error {
+	h.started = true
+	return h.StreamHandler.StreamStart(hit)
+}
+
+func isEventStream(header http.Header) bool {
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	return err == nil && mediaType == "text/event-stream"
+}
+
+// readStream reads body until it ends, maxEvents events or chunks have been
+// read or ctx is done and passes them to handler. It returns the bytes read.
+func readStream(ctx context.Context, body io.Reader, header http.Header,
+	maxEvents int, handler StreamHandler,
+) ([]byte, error) {
+	var (
+		captured []byte
+		err      error
+	)
+	if isEventStream(header) {
+		captured, err = readEvents(body, maxEvents, handler)
+	} else {
+		captured, err = readChunks(body, maxEvents, handler)
+	}
+	if errors.Is(err, io.EOF) || (err != nil && ctx.Err() != nil) {
+		// the stream ended or was interrupted
+		return captured, nil
+	}
+	return captured, err
+}
+
+func readChunks(body io.Reader, maxEvents int, handler StreamHandler) ([]byte, error) {
+	var captured []byte
+	buf := make([]byte, streamChunkSize)
+	for n := 0; maxEvents == 0 || n < maxEvents; n++ {
+		read, err := body.Read(buf)
+		if read > 0 {
+			captured = append(captured, buf[:read]...)
+			if err := handler.StreamChunk(buf[:read]); err != nil {
+				return captured, err
+			}
+		}
+		if err != nil {
+			return captured, err
+		}
+	}
+	return captured, nil
+}
+
+func readEvents(body io.Reader, maxEvents int, handler StreamHandler) ([]byte, error) {
+	var (
+		captured []byte
+		parser   eventParser
+		n        int
+	)
+	reader := bufio.NewReader(body)
+	for maxEvents == 0 || n < maxEvents {
+		line, err := reader.ReadString('\n')
+		captured = append(captured, line...)
+		if event, ok := parser.line(line); ok {
+			n++
+			if err := handler.StreamEvent(event); err != nil {
+				return captured, err
+			}
+		}
+		if err != nil {
+			return captured, err
+		}
+	}
+	return captured, nil
+}
+
+// eventParser parses the lines of an event stream into events.
+type eventParser struct {
+	event model.Event
+	data  []string
+}
+
+// line parses a line of the stream and returns an event if the line
+// completed one.
+func (p *eventParser) line(line string) (model.Event, bool) {
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		if p.data == nil {
+			p.event.Type = ""
+			return model.Event{}, false
+		}
+		event := p.event
+		event.Data = strings.Join(p.data, "\n")
+		p.event.Type, p.data = "", nil
+		return event, true
+	}
+	if strings.HasPrefix(line, ":") {
+		// comment
+		return model.Event{}, false
+	}
+	field, value := line, ""
+	if i := strings.Index(line, ":"); i >= 0 {
+		field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+	}
+	switch field {
+	case "event":
+		p.event.Type = value
+	case "data":
+		p.data = append(p.data, value)
+	case "id":
+		p.event.ID = value
+	}
+	return model.Event{}, false
+}
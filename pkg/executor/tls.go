@@ -0,0 +1,67 @@
+package executor
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"github.com/hbagdi/hit/pkg/parser"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// tlsConfig builds the TLS configuration of a request from opts.
+func tlsConfig(opts parser.TLSOptions) (*tls.Config, error) {
+	config := &tls.Config{
+		ServerName: opts.ServerName,
+		//nolint:gosec
+		InsecureSkipVerify: opts.InsecureSkipVerify != nil && *opts.InsecureSkipVerify,
+	}
+	if opts.MinVersion != "" {
+		version, ok := tlsVersions[opts.MinVersion]
+		if !ok {
+			return nil, fmt.Errorf("invalid TLS minVersion '%v': expected "+
+				"one of '1.0', '1.1', '1.2' or '1.3'", opts.MinVersion)
+		}
+		config.MinVersion = version
+	}
+	if opts.CAFile != "" {
+		pem, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read TLS caFile: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM encoded certificates found in "+
+				"TLS caFile '%v'", opts.CAFile)
+		}
+		config.RootCAs = pool
+	}
+	if (opts.CertFile == "") != (opts.KeyFile == "") {
+		return nil, fmt.Errorf("TLS certFile and keyFile must be set together")
+	}
+	if opts.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load TLS client certificate: %v", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// tlsVersionName returns the name of a TLS version such as 'TLS 1.3'.
+func tlsVersionName(version uint16) string {
+	for name, v := range tlsVersions {
+		if v == version {
+			return "TLS " + name
+		}
+	}
+	return fmt.Sprintf("0x%04x", version)
+}
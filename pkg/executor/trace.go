@@ -0,0 +1,110 @@
+package executor
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/hbagdi/hit/pkg/model"
+)
+
+// tracer records the latency and network details of a request. Its hooks
+// may be called concurrently, such as by the goroutines of an HTTP/2
+// connection, so its state is guarded by mu.
+type tracer struct {
+	mu           sync.Mutex
+	start        time.Time
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	wroteRequest time.Time
+
+	latency model.Latency
+	network model.Network
+}
+
+func newTracer() *tracer {
+	return &tracer{start: time.Now()}
+}
+
+func (t *tracer) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.dnsStart = time.Now()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.latency.DNSResolution = time.Since(t.dnsStart)
+		},
+		ConnectStart: func(_, _ string) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.connectStart = time.Now()
+		},
+		ConnectDone: func(_, _ string, err error) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if err == nil {
+				t.latency.TCPConnection = time.Since(t.connectStart)
+			}
+		},
+		TLSHandshakeStart: func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if err == nil {
+				t.latency.TLSConnection = time.Since(t.tlsStart)
+			}
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if info.Reused {
+				t.latency.DNSResolution = 0
+				t.latency.TCPConnection = 0
+				t.latency.TLSConnection = 0
+			}
+			addr, ok := info.Conn.RemoteAddr().(*net.TCPAddr)
+			if ok {
+				t.network.IPInUse = addr.IP.String()
+				t.network.PortInUse = addr.Port
+			}
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.wroteRequest = time.Now()
+		},
+		GotFirstResponseByte: func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			// WebSocket handshakes don't report writing the request
+			if !t.wroteRequest.IsZero() {
+				t.latency.TTFB = time.Since(t.wroteRequest)
+			}
+		},
+	}
+}
+
+// done records the end of the request.
+func (t *tracer) done() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.latency.Total = time.Since(t.start)
+}
+
+// result returns the latency and network details recorded so far.
+func (t *tracer) result() (model.Latency, model.Network) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.latency, t.network
+}
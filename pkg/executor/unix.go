@@ -0,0 +1,52 @@
+package executor
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// unixRoundTripper sends requests with the 'http+unix' scheme over the Unix
+// socket named by the host of the URL.
+type unixRoundTripper struct {
+	transport *http.Transport
+}
+
+func newUnixRoundTripper(transport *http.Transport) unixRoundTripper {
+	transport = transport.Clone()
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, _, addr string) (net.Conn, error) {
+		// addr is the hex-encoded path of the socket and the default port
+		if i := strings.LastIndex(addr, ":"); i >= 0 {
+			addr = addr[:i]
+		}
+		path, err := hex.DecodeString(addr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Unix socket address '%v'", addr)
+		}
+		var d net.Dialer
+		return d.DialContext(ctx, "unix", string(path))
+	}
+	return unixRoundTripper{transport: transport}
+}
+
+func (u unixRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := req.Clone(req.Context())
+	r.URL.Scheme = "http"
+	// the transport requires a valid host, it identifies the connections
+	// to the socket
+	r.URL.Host = hex.EncodeToString([]byte(req.URL.Host))
+	if r.Host == "" || r.Host == req.URL.Host {
+		r.Host = "localhost"
+	}
+	resp, err := u.transport.RoundTrip(r)
+	if err != nil {
+		return nil, err
+	}
+	// redirects are resolved against the URL of the original request
+	resp.Request = req
+	return resp, nil
+}
@@ -0,0 +1,274 @@
+package executor
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sort"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/websocket"
+	"github.com/hbagdi/hit/pkg/model"
+	"github.com/hbagdi/hit/pkg/parser"
+)
+
+// webSocketCloseTimeout is the time given to the server to acknowledge the
+// closing of a session.
+const webSocketCloseTimeout = time.Second
+
+// executeWebSocket opens a WebSocket session for req and sends its messages.
+// Messages are received until the server closes the session, maxEvents
+// messages have been received, no message is received within the timeout or
+// ctx is done. Every message is passed on to
+// the stream handler of the executor. Cookies are sent from and stored in
+// jar, if any.
+func (e *Executor) executeWebSocket(ctx context.Context, requestID string,
+	req model.Request, opts parser.Options, jar http.CookieJar,
+) (model.Hit, error) {
+	req, err := signRequest(req)
+	if err != nil {
+		return model.Hit{}, err
+	}
+	dialer, err := newWebSocketDialer(requestID, req, opts)
+	if err != nil {
+		return model.Hit{}, err
+	}
+	var cookies []*http.Cookie
+	if jar != nil {
+		dialer.Jar = jar
+		if u, err := model.ParseURL(req.URL()); err == nil {
+			cookies = jar.Cookies(u)
+		}
+	}
+	u, err := model.ParseURL(req.URL())
+	if err != nil {
+		return model.Hit{}, fmt.Errorf("create WebSocket request: %v", err)
+	}
+	switch u.Scheme {
+	case "https":
+		u.Scheme = "wss"
+	case model.SchemeHTTPUnix:
+		// the dialer connects to the socket
+		u.Scheme, u.Host = "ws", "localhost"
+	default:
+		u.Scheme = "ws"
+	}
+
+	var proxy string
+	ctx = withProxyRecorder(ctx, &proxy)
+	tracer := newTracer()
+	conn, resp, err := dialer.DialContext(
+		httptrace.WithClientTrace(ctx, tracer.clientTrace()), u.String(),
+		req.Header)
+	if err != nil {
+		if errors.Is(err, websocket.ErrBadHandshake) && resp != nil {
+			return model.Hit{}, fmt.Errorf("open WebSocket session: %v (%v)",
+				err, resp.Status)
+		}
+		return model.Hit{}, fmt.Errorf("open WebSocket session: %w", err)
+	}
+	defer conn.Close()
+	tracer.done()
+	latency, network := tracer.result()
+
+	hit := model.Hit{
+		HitRequestID: requestID,
+		Kind:         model.HitKindWebSocket,
+		Request:      withCookies(req, cookies),
+		Response:     hitResponseHead(resp),
+		Latency:      latency,
+		Network:      network,
+		Proxy:        proxy,
+	}
+	hit.Request.Proto = resp.Proto
+	if tlsConn, ok := conn.UnderlyingConn().(*tls.Conn); ok {
+		state := tlsConn.ConnectionState()
+		hit.TLS = model.TLS{
+			Version:     tlsVersionName(state.Version),
+			CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+		}
+	}
+	handler := e.stream
+	if handler == nil {
+		handler = nopStreamHandler{}
+	}
+	if err := handler.StreamStart(hit); err != nil {
+		return model.Hit{}, err
+	}
+
+	hit.Messages, err = webSocketSession(ctx, conn, req.Messages, opts, handler)
+	if err != nil {
+		return model.Hit{}, err
+	}
+	if err := e.cache.Save(hit); err != nil {
+		return model.Hit{}, fmt.Errorf("save response: %v", err)
+	}
+	return hit, nil
+}
+
+// newWebSocketDialer returns the dialer used to open the WebSocket session
+// of request id with opts.
+func newWebSocketDialer(id string, req model.Request, opts parser.Options,
+) (*websocket.Dialer, error) {
+	transport, err := newTransport(id, opts)
+	if err != nil {
+		return nil, err
+	}
+	dialer := &websocket.Dialer{
+		Proxy:            transport.Proxy,
+		TLSClientConfig:  transport.TLSClientConfig,
+		NetDialContext:   transport.DialContext,
+		HandshakeTimeout: requestTimeout(opts),
+	}
+	if req.Scheme == model.SchemeHTTPUnix {
+		socket := req.Host
+		dialer.Proxy = nil
+		dialer.NetDialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socket)
+		}
+	}
+	return dialer, nil
+}
+
+// received is the outcome of reading a message of a WebSocket session.
+type received struct {
+	message model.Message
+	err     error
+}
+
+// webSocketSession sends messages over conn and receives messages until the
+// session ends. It returns every message sent and received.
+func webSocketSession(ctx context.Context, conn *websocket.Conn,
+	messages [][]byte, opts parser.Options, handler StreamHandler,
+) ([]model.Message, error) {
+	if opts.MaxBodySize != nil {
+		conn.SetReadLimit(int64(*opts.MaxBodySize))
+	}
+	maxEvents := 0
+	if opts.MaxEvents != nil {
+		maxEvents = *opts.MaxEvents
+	}
+
+	// the session ends once no message is received within the timeout
+	timeout := requestTimeout(opts)
+	incoming := make(chan received)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			if timeout > 0 {
+				_ = conn.SetReadDeadline(time.Now().Add(timeout))
+			}
+			messageType, data, err := conn.ReadMessage()
+			r := received{
+				message: model.Message{
+					Time:   time.Now(),
+					Binary: messageType == websocket.BinaryMessage,
+					Data:   data,
+				},
+				err: err,
+			}
+			select {
+			case incoming <- r:
+			case <-done:
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	var res []model.Message
+	for _, data := range messages {
+		messageType := websocket.TextMessage
+		if !utf8.Valid(data) {
+			messageType = websocket.BinaryMessage
+		}
+		if err := conn.WriteMessage(messageType, data); err != nil {
+			return nil, fmt.Errorf("send WebSocket message: %v", err)
+		}
+		message := model.Message{
+			Sent:   true,
+			Time:   time.Now(),
+			Binary: messageType == websocket.BinaryMessage,
+			Data:   data,
+		}
+		res = append(res, message)
+		if err := handler.StreamMessage(message); err != nil {
+			return nil, err
+		}
+	}
+
+	count := 0
+	for (maxEvents == 0 || count < maxEvents) && ctx.Err() == nil {
+		select {
+		case <-ctx.Done():
+			// the session was interrupted
+			closeWebSocket(conn, incoming)
+			return sortMessages(res), nil
+		case r := <-incoming:
+			if r.err != nil {
+				var closeErr *websocket.CloseError
+				if errors.As(r.err, &closeErr) {
+					// the server closed the session
+					return sortMessages(res), nil
+				}
+				var netErr net.Error
+				if errors.As(r.err, &netErr) && netErr.Timeout() {
+					// the server was silent for longer than the timeout, the
+					// connection can't be read anymore
+					_ = conn.WriteControl(websocket.CloseMessage,
+						websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+						time.Now().Add(webSocketCloseTimeout))
+					return sortMessages(res), nil
+				}
+				return nil, fmt.Errorf("receive WebSocket message: %v", r.err)
+			}
+			res = append(res, r.message)
+			count++
+			if err := handler.StreamMessage(r.message); err != nil {
+				return nil, err
+			}
+		}
+	}
+	// the session was interrupted or enough messages have been received
+	closeWebSocket(conn, incoming)
+	return sortMessages(res), nil
+}
+
+// closeWebSocket closes the session of conn and waits for the server to
+// acknowledge it on incoming.
+func closeWebSocket(conn *websocket.Conn, incoming <-chan received) {
+	deadline := time.Now().Add(webSocketCloseTimeout)
+	err := conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), deadline)
+	if err != nil {
+		return
+	}
+	timeout := time.After(webSocketCloseTimeout)
+	for {
+		select {
+		case r := <-incoming:
+			if r.err != nil {
+				return
+			}
+		case <-timeout:
+			return
+		}
+	}
+}
+
+// sortMessages sorts messages by the time they were sent or received.
+func sortMessages(messages []model.Message) []model.Message {
+	sort.SliceStable(messages, func(i, j int) bool {
+		return messages[i].Time.Before(messages[j].Time)
+	})
+	return messages
+}
@@ -0,0 +1,368 @@
+package lint
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/hbagdi/hit/pkg/assertion"
+	"github.com/hbagdi/hit/pkg/executor"
+	"github.com/hbagdi/hit/pkg/model"
+	"github.com/hbagdi/hit/pkg/parser"
+	"github.com/hbagdi/hit/pkg/request"
+)
+
+var (
+	validMethods = map[string]bool{
+		http.MethodGet:        true,
+		http.MethodHead:       true,
+		http.MethodPost:       true,
+		http.MethodPut:        true,
+		http.MethodPatch:      true,
+		http.MethodDelete:     true,
+		http.MethodConnect:    true,
+		http.MethodOptions:    true,
+		http.MethodTrace:      true,
+		model.MethodWebSocket: true,
+		model.MethodGRPC:      true,
+	}
+	validEncodings = map[string]bool{
+		"":    true,
+		"y2j": true,
+	}
+)
+
+// Problem is an issue found in a hit file.
+type Problem struct {
+	Filename  string
+	RequestID string
+	// Span locates the problem in the file, it is zero if the problem is
+	// not tied to a location.
+	Span    parser.Span
+	Message string
+}
+
+func (p Problem) String() string {
+	var prefix string
+	if p.Filename != "" {
+		prefix += p.Filename
+		if p.Span.Start.Line > 0 {
+			prefix += fmt.Sprintf(":%d:%d", p.Span.Start.Line,
+				p.Span.Start.Column)
+		}
+		prefix += ": "
+	}
+	if p.RequestID != "" {
+		prefix += "@" + p.RequestID + ": "
+	}
+	return prefix + p.Message
+}
+
+// Lint parses filenames and checks every request in them without executing
+// any request.
+func Lint(filenames []string) []Problem {
+	var (
+		problems []Problem
+		asts     []*parser.AST
+	)
+	for _, filename := range filenames {
+		src, err := os.ReadFile(filename)
+		if err != nil {
+			problems = append(problems, Problem{
+				Filename: filename,
+				Message:  err.Error(),
+			})
+			continue
+		}
+		asts = append(asts, parser.ParseAST(filename, src))
+	}
+	return append(problems, LintASTs(asts)...)
+}
+
+type parsedFile struct {
+	ast  *parser.AST
+	file parser.File
+	// sections holds the syntax node of each request in file.
+	sections []*parser.RequestSection
+	// flows holds the syntax node of each flow in file.
+	flows []*parser.FlowSection
+}
+
+// LintASTs checks every request in asts without executing any request.
+func LintASTs(asts []*parser.AST) []Problem {
+	var (
+		problems []Problem
+		files    []parsedFile
+	)
+	for _, ast := range asts {
+		for _, err := range ast.Errors {
+			problems = append(problems, Problem{
+				Filename: ast.Filename,
+				Span:     err.Span,
+				Message:  err.Message,
+			})
+		}
+		if len(ast.Errors) > 0 {
+			continue
+		}
+		file, err := ast.File()
+		if err != nil {
+			problem := Problem{Filename: ast.Filename, Message: err.Error()}
+			var parseErr *parser.Error
+			if errors.As(err, &parseErr) {
+				problem.Span = parseErr.Span
+				problem.Message = parseErr.Message
+			}
+			problems = append(problems, problem)
+			continue
+		}
+		f := parsedFile{ast: ast, file: file}
+		for _, node := range ast.Nodes {
+			switch section := node.(type) {
+			case *parser.RequestSection:
+				f.sections = append(f.sections, section)
+			case *parser.FlowSection:
+				f.flows = append(f.flows, section)
+			}
+		}
+		files = append(files, f)
+	}
+
+	parsedFiles := make([]parser.File, 0, len(files))
+	for _, f := range files {
+		parsedFiles = append(parsedFiles, f.file)
+	}
+	global, err := executor.FetchGlobal(parsedFiles)
+	if err != nil {
+		problems = append(problems, Problem{Message: err.Error()})
+	}
+
+	ids := map[string]string{}
+	variables := map[string]bool{}
+	for _, f := range files {
+		for i, r := range f.file.Requests {
+			for name := range r.Captures {
+				variables[name] = true
+			}
+			if definedIn, ok := ids[r.ID]; ok {
+				problems = append(problems, Problem{
+					Filename:  f.ast.Filename,
+					RequestID: r.ID,
+					Span:      f.sections[i].ID.Span,
+					Message: fmt.Sprintf("duplicate request ID, "+
+						"already defined in '%s'", definedIn),
+				})
+				continue
+			}
+			ids[r.ID] = f.ast.Filename
+		}
+	}
+
+	for _, f := range files {
+		for i, r := range f.file.Requests {
+			problems = append(problems,
+				lintRequest(f.ast.Filename, r, f.sections[i], global, ids,
+					variables)...)
+		}
+	}
+
+	flows := map[string]string{}
+	for _, f := range files {
+		for i, flow := range f.file.Flows {
+			section := f.flows[i]
+			if definedIn, ok := flows[flow.Name]; ok {
+				problems = append(problems, Problem{
+					Filename: f.ast.Filename,
+					Span:     section.Name.Span,
+					Message: fmt.Sprintf("duplicate flow name '%s', already "+
+						"defined in '%s'", flow.Name, definedIn),
+				})
+				continue
+			}
+			flows[flow.Name] = f.ast.Filename
+			problems = append(problems, lintFlow(f.ast.Filename, flow, section, ids)...)
+		}
+	}
+	return problems
+}
+
+func lintFlow(filename string, flow parser.Flow, section *parser.FlowSection,
+	ids map[string]string,
+) []Problem {
+	var problems []Problem
+	problem := func(span parser.Span, format string, a ...interface{}) {
+		problems = append(problems, Problem{
+			Filename: filename,
+			Span:     span,
+			Message: fmt.Sprintf("flow '%s': %s", flow.Name,
+				fmt.Sprintf(format, a...)),
+		})
+	}
+	if len(flow.Steps) == 0 {
+		problem(section.Name.Span, "no steps")
+	}
+	for _, step := range flow.Steps {
+		if _, ok := ids[step.ID]; !ok {
+			problem(locate(section.Lines(), "@"+step.ID, section.Name.Span),
+				"step '%s' references undefined request '@%s'", step, step.ID)
+		}
+	}
+	return problems
+}
+
+func lintRequest(filename string, r parser.Request,
+	section *parser.RequestSection, global parser.Global,
+	ids map[string]string, variables map[string]bool,
+) []Problem {
+	var problems []Problem
+	problem := func(span parser.Span, format string, a ...interface{}) {
+		problems = append(problems, Problem{
+			Filename:  filename,
+			RequestID: r.ID,
+			Span:      span,
+			Message:   fmt.Sprintf(format, a...),
+		})
+	}
+	if r.ID+"." == request.VariablePrefix {
+		problem(section.ID.Span, "request ID '@%s' is reserved for "+
+			"references to captured variables", r.ID)
+	}
+	if !validMethods[r.Method] {
+		problem(section.RequestLine.Method.Span, "invalid method '%s'", r.Method)
+	}
+	if r.Method == model.MethodGRPC {
+		if _, _, err := executor.SplitGRPCMethod(r.Path); err != nil {
+			problem(section.RequestLine.Path.Span, "%v", err)
+		}
+	}
+	if body := section.Body(); body != nil && !validEncodings[r.BodyEncoding] {
+		problem(body.Name.Span, "unknown body encoding '%s'", r.BodyEncoding)
+	}
+	if r.Assertions != nil {
+		if err := assertion.Validate(*r.Assertions); err != nil {
+			problem(section.Block("assert").Name.Span, "%v", err)
+		}
+	}
+	for _, dep := range r.Options.DependsOn {
+		id := strings.TrimPrefix(dep, "@")
+		if _, ok := ids[id]; !ok || id == dep {
+			problem(locate(section.Lines(), dep, section.ID.Span),
+				"dependency '%s' is not a defined request", dep)
+		}
+	}
+	if err := executor.ValidateCaptures(r.Captures); err != nil {
+		problem(section.Block("capture").Name.Span, "%v", err)
+	}
+
+	resolver := &recordingResolver{
+		ids:       ids,
+		variables: variables,
+		args:      map[int]bool{},
+	}
+	options := global.Options.Merge(r.Options)
+	_, err := request.Generate(r, request.Options{
+		GlobalContext: global,
+		Resolver:      resolver,
+		Auth:          options.Auth,
+		Sign:          options.Sign,
+	})
+	for _, ref := range resolver.undefined {
+		if ref.variable != "" {
+			problem(locate(section.Lines(), "@"+ref.key, section.ID.Span), "reference '@%s' to "+
+				"variable '%s' which no ~capture block sets", ref.key,
+				ref.variable)
+			continue
+		}
+		problem(locate(section.Lines(), "@"+ref.key, section.ID.Span), "reference '@%s' to undefined "+
+			"request '@%s'", ref.key, ref.id)
+	}
+	if err != nil {
+		problem(section.ID.Span, "%v", err)
+		return problems
+	}
+
+	maxArg := 0
+	for n := range resolver.args {
+		if n > maxArg {
+			maxArg = n
+		}
+	}
+	for n := 1; n < maxArg; n++ {
+		if !resolver.args[n] {
+			problem(section.ID.Span, "positional argument '@%d' is never "+
+				"used but '@%d' is", n, maxArg)
+		}
+	}
+	return problems
+}
+
+// locate returns the span of the first occurrence of text in lines,
+// falling back to span.
+func locate(lines []parser.Line, text string, span parser.Span) parser.Span {
+	for _, line := range lines {
+		i := strings.Index(line.Text, text)
+		if i < 0 {
+			continue
+		}
+		start := line.Span.Start
+		start.Offset += i
+		start.Column += i
+		end := start
+		end.Offset += len(text)
+		end.Column += len(text)
+		return parser.Span{Start: start, End: end}
+	}
+	return span
+}
+
+// placeholder is the value every reference resolves to during linting.
+const placeholder = "hit-lint"
+
+// reference is a reference to an undefined request id or, if set, an
+// undefined variable.
+type reference struct {
+	key, id, variable string
+}
+
+// recordingResolver resolves every reference to a placeholder and records
+// the positional arguments used and any references to undefined requests
+// or variables.
+type recordingResolver struct {
+	ids       map[string]string
+	variables map[string]bool
+	args      map[int]bool
+	undefined []reference
+}
+
+func (r *recordingResolver) Resolve(key string) (interface{}, error) {
+	key = key[1:]
+	if len(key) == 0 {
+		return nil, fmt.Errorf("invalid reference '@'")
+	}
+	n, err := strconv.Atoi(key)
+	if err == nil {
+		if n == 0 {
+			return nil, fmt.Errorf("positional argument must be greater than 0")
+		}
+		r.args[n] = true
+		return placeholder, nil
+	}
+	const splitN = 2
+	splits := strings.SplitN(key, ".", splitN)
+	if len(splits) != splitN || splits[1] == "" {
+		return nil, fmt.Errorf("invalid reference: '@%s'", key)
+	}
+	if strings.HasPrefix(key, request.VariablePrefix) {
+		if !r.variables[splits[1]] {
+			r.undefined = append(r.undefined, reference{key: key, variable: splits[1]})
+		}
+		return placeholder, nil
+	}
+	if _, ok := r.ids[splits[0]]; !ok {
+		r.undefined = append(r.undefined, reference{key: key, id: splits[0]})
+	}
+	return placeholder, nil
+}
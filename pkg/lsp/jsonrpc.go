@@ -0,0 +1,126 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"sync"
+)
+
+const jsonrpcVersion = "2.0"
+
+// JSON-RPC error codes used by the server.
+const (
+	codeParseError     = -32700
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeInternalError  = -32603
+)
+
+type incomingMessage struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+}
+
+// isNotification reports whether the message expects no response.
+func (m incomingMessage) isNotification() bool {
+	return m.ID == nil
+}
+
+type responseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *responseError) Error() string {
+	return e.Message
+}
+
+type response struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Result  interface{}      `json:"result"`
+}
+
+type errorResponse struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Error   *responseError   `json:"error"`
+}
+
+type notification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+// conn reads and writes JSON-RPC messages framed with a Content-Length
+// header as specified by the Language Server Protocol.
+type conn struct {
+	reader *textproto.Reader
+
+	mu     sync.Mutex
+	writer io.Writer
+}
+
+func newConn(r io.Reader, w io.Writer) *conn {
+	return &conn{
+		reader: textproto.NewReader(bufio.NewReader(r)),
+		writer: w,
+	}
+}
+
+func (c *conn) read() (incomingMessage, error) {
+	header, err := c.reader.ReadMIMEHeader()
+	if err != nil {
+		return incomingMessage{}, err
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return incomingMessage{}, fmt.Errorf("invalid Content-Length header: %v", err)
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(c.reader.R, body); err != nil {
+		return incomingMessage{}, fmt.Errorf("read message: %v", err)
+	}
+	var m incomingMessage
+	if err := json.Unmarshal(body, &m); err != nil {
+		return incomingMessage{}, &responseError{
+			Code:    codeParseError,
+			Message: fmt.Sprintf("parse message: %v", err),
+		}
+	}
+	return m, nil
+}
+
+func (c *conn) write(v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("marshal message: %v", err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err = fmt.Fprintf(c.writer, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	return err
+}
+
+func (c *conn) reply(id *json.RawMessage, result interface{}) error {
+	return c.write(response{JSONRPC: jsonrpcVersion, ID: id, Result: result})
+}
+
+func (c *conn) replyError(id *json.RawMessage, err *responseError) error {
+	return c.write(errorResponse{JSONRPC: jsonrpcVersion, ID: id, Error: err})
+}
+
+func (c *conn) notify(method string, params interface{}) error {
+	return c.write(notification{
+		JSONRPC: jsonrpcVersion,
+		Method:  method,
+		Params:  params,
+	})
+}
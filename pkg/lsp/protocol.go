@@ -0,0 +1,119 @@
+package lsp
+
+// The subset of the Language Server Protocol types used by the server.
+
+type initializeParams struct {
+	RootURI  string `json:"rootUri"`
+	RootPath string `json:"rootPath"`
+}
+
+type initializeResult struct {
+	Capabilities serverCapabilities `json:"capabilities"`
+	ServerInfo   serverInfo         `json:"serverInfo"`
+}
+
+type serverInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type serverCapabilities struct {
+	TextDocumentSync   textDocumentSyncKind `json:"textDocumentSync"`
+	CompletionProvider completionOptions    `json:"completionProvider"`
+	DefinitionProvider bool                 `json:"definitionProvider"`
+	HoverProvider      bool                 `json:"hoverProvider"`
+}
+
+type textDocumentSyncKind int
+
+const textDocumentSyncFull textDocumentSyncKind = 1
+
+type completionOptions struct {
+	TriggerCharacters []string `json:"triggerCharacters"`
+}
+
+// position is a zero-based line and UTF-16 code unit offset in a document.
+type position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type textRange struct {
+	Start position `json:"start"`
+	End   position `json:"end"`
+}
+
+type location struct {
+	URI   string    `json:"uri"`
+	Range textRange `json:"range"`
+}
+
+type diagnosticSeverity int
+
+const severityError diagnosticSeverity = 1
+
+type diagnostic struct {
+	Range    textRange          `json:"range"`
+	Severity diagnosticSeverity `json:"severity"`
+	Source   string             `json:"source"`
+	Message  string             `json:"message"`
+}
+
+type publishDiagnosticsParams struct {
+	URI         string       `json:"uri"`
+	Diagnostics []diagnostic `json:"diagnostics"`
+}
+
+type textDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+type textDocumentItem struct {
+	URI  string `json:"uri"`
+	Text string `json:"text"`
+}
+
+type didOpenParams struct {
+	TextDocument textDocumentItem `json:"textDocument"`
+}
+
+type contentChange struct {
+	Text string `json:"text"`
+}
+
+type didChangeParams struct {
+	TextDocument   textDocumentIdentifier `json:"textDocument"`
+	ContentChanges []contentChange        `json:"contentChanges"`
+}
+
+type didCloseParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+}
+
+type textDocumentPositionParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+	Position     position               `json:"position"`
+}
+
+type completionItemKind int
+
+const (
+	completionKindField     completionItemKind = 5
+	completionKindReference completionItemKind = 18
+)
+
+type completionItem struct {
+	Label  string             `json:"label"`
+	Kind   completionItemKind `json:"kind"`
+	Detail string             `json:"detail,omitempty"`
+}
+
+type markupContent struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+type hover struct {
+	Contents markupContent `json:"contents"`
+	Range    *textRange    `json:"range,omitempty"`
+}
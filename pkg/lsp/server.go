@@ -0,0 +1,407 @@
+package lsp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hbagdi/hit/pkg/lint"
+	"github.com/hbagdi/hit/pkg/model"
+	"github.com/hbagdi/hit/pkg/parser"
+	"github.com/hbagdi/hit/pkg/version"
+	"github.com/tidwall/gjson"
+	"go.uber.org/zap"
+)
+
+// HitLoader loads the latest hit of a request.
+type HitLoader interface {
+	LoadLatestHitForID(ctx context.Context, hitRequestID string) (model.Hit, error)
+}
+
+type Opts struct {
+	In  io.Reader
+	Out io.Writer
+	// Hits is used to complete and show cached values of references. It is
+	// optional.
+	Hits   HitLoader
+	Logger *zap.Logger
+}
+
+// Server is a language server for hit files speaking the Language Server
+// Protocol.
+type Server struct {
+	conn      *conn
+	hits      HitLoader
+	logger    *zap.Logger
+	workspace *workspace
+}
+
+func NewServer(opts Opts) *Server {
+	logger := opts.Logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &Server{
+		conn:   newConn(opts.In, opts.Out),
+		hits:   opts.Hits,
+		logger: logger,
+		workspace: &workspace{
+			docs: map[string]string{},
+		},
+	}
+}
+
+// Serve handles messages until the client sends an exit notification or
+// closes the input stream.
+func (s *Server) Serve(ctx context.Context) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		m, err := s.conn.read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			var rErr *responseError
+			if errors.As(err, &rErr) {
+				if err := s.conn.replyError(nil, rErr); err != nil {
+					return err
+				}
+				continue
+			}
+			return err
+		}
+		if m.Method == "exit" {
+			return nil
+		}
+
+		result, err := s.handle(ctx, m)
+		if m.isNotification() {
+			if err != nil {
+				s.logger.Debug("failed to handle notification",
+					zap.String("method", m.Method), zap.Error(err))
+			}
+			continue
+		}
+		if err != nil {
+			var rErr *responseError
+			if !errors.As(err, &rErr) {
+				rErr = &responseError{Code: codeInternalError, Message: err.Error()}
+			}
+			err = s.conn.replyError(m.ID, rErr)
+		} else {
+			err = s.conn.reply(m.ID, result)
+		}
+		if err != nil {
+			return fmt.Errorf("write response: %v", err)
+		}
+	}
+}
+
+func decodeParams(m incomingMessage, v interface{}) error {
+	if err := json.Unmarshal(m.Params, v); err != nil {
+		return &responseError{
+			Code:    codeInvalidParams,
+			Message: fmt.Sprintf("invalid params: %v", err),
+		}
+	}
+	return nil
+}
+
+//nolint:nilnil
+func (s *Server) handle(ctx context.Context, m incomingMessage) (interface{}, error) {
+	switch m.Method {
+	case "initialize":
+		var params initializeParams
+		if err := decodeParams(m, &params); err != nil {
+			return nil, err
+		}
+		return s.initialize(params), nil
+	case "initialized", "shutdown":
+		return nil, nil
+	case "textDocument/didOpen":
+		var params didOpenParams
+		if err := decodeParams(m, &params); err != nil {
+			return nil, err
+		}
+		s.workspace.docs[params.TextDocument.URI] = params.TextDocument.Text
+		return nil, s.publishDiagnostics()
+	case "textDocument/didChange":
+		var params didChangeParams
+		if err := decodeParams(m, &params); err != nil {
+			return nil, err
+		}
+		if n := len(params.ContentChanges); n > 0 {
+			s.workspace.docs[params.TextDocument.URI] = params.ContentChanges[n-1].Text
+		}
+		return nil, s.publishDiagnostics()
+	case "textDocument/didSave":
+		return nil, s.publishDiagnostics()
+	case "textDocument/didClose":
+		var params didCloseParams
+		if err := decodeParams(m, &params); err != nil {
+			return nil, err
+		}
+		delete(s.workspace.docs, params.TextDocument.URI)
+		err := s.conn.notify("textDocument/publishDiagnostics",
+			publishDiagnosticsParams{
+				URI:         params.TextDocument.URI,
+				Diagnostics: []diagnostic{},
+			})
+		if err != nil {
+			return nil, err
+		}
+		return nil, s.publishDiagnostics()
+	case "textDocument/completion":
+		var params textDocumentPositionParams
+		if err := decodeParams(m, &params); err != nil {
+			return nil, err
+		}
+		return s.completion(ctx, params), nil
+	case "textDocument/definition":
+		var params textDocumentPositionParams
+		if err := decodeParams(m, &params); err != nil {
+			return nil, err
+		}
+		return s.definition(params), nil
+	case "textDocument/hover":
+		var params textDocumentPositionParams
+		if err := decodeParams(m, &params); err != nil {
+			return nil, err
+		}
+		return s.hover(ctx, params), nil
+	default:
+		if m.isNotification() {
+			return nil, nil
+		}
+		return nil, &responseError{
+			Code:    codeMethodNotFound,
+			Message: fmt.Sprintf("method not found: %v", m.Method),
+		}
+	}
+}
+
+func (s *Server) initialize(params initializeParams) initializeResult {
+	s.workspace.root = params.RootPath
+	if params.RootURI != "" {
+		s.workspace.root = uriToPath(params.RootURI)
+	}
+	return initializeResult{
+		Capabilities: serverCapabilities{
+			TextDocumentSync: textDocumentSyncFull,
+			CompletionProvider: completionOptions{
+				TriggerCharacters: []string{"@", "."},
+			},
+			DefinitionProvider: true,
+			HoverProvider:      true,
+		},
+		ServerInfo: serverInfo{Name: "hit", Version: version.Version},
+	}
+}
+
+// publishDiagnostics lints the workspace and publishes the problems found in
+// every open document.
+func (s *Server) publishDiagnostics() error {
+	asts := s.workspace.asts()
+	problems := lint.LintASTs(asts)
+
+	diagnostics := map[string][]diagnostic{}
+	for uri := range s.workspace.docs {
+		diagnostics[uri] = []diagnostic{}
+	}
+	for _, problem := range problems {
+		src, ok := s.workspace.docs[problem.Filename]
+		if !ok {
+			continue
+		}
+		diagnostics[problem.Filename] = append(diagnostics[problem.Filename],
+			diagnostic{
+				Range:    toRange(src, problem.Span),
+				Severity: severityError,
+				Source:   "hit",
+				Message:  problem.Message,
+			})
+	}
+	for uri, d := range diagnostics {
+		err := s.conn.notify("textDocument/publishDiagnostics",
+			publishDiagnosticsParams{URI: uri, Diagnostics: d})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// document returns the content of the document at uri, preferring the
+// version opened by the client.
+func (s *Server) document(uri string) string {
+	if src, ok := s.workspace.docs[uri]; ok {
+		return src
+	}
+	src, err := os.ReadFile(uriToPath(uri))
+	if err != nil {
+		return ""
+	}
+	return string(src)
+}
+
+var (
+	pathCompletionRegex = regexp.MustCompile(`@([a-zA-Z][a-zA-Z0-9-_]*)\.([^\s"'/?&,@]*)$`)
+	idCompletionRegex   = regexp.MustCompile(`@[a-zA-Z0-9-_]*$`)
+)
+
+func (s *Server) completion(ctx context.Context, params textDocumentPositionParams) []completionItem {
+	src := s.document(params.TextDocument.URI)
+	pos, line := fromPosition(src, params.Position)
+	if pos.Line == 0 {
+		return []completionItem{}
+	}
+	before := line[:pos.Column-1]
+
+	if matches := pathCompletionRegex.FindStringSubmatch(before); matches != nil {
+		return s.pathCompletion(ctx, matches[1], matches[2])
+	}
+	if !idCompletionRegex.MatchString(before) {
+		return []completionItem{}
+	}
+	res := []completionItem{}
+	for _, section := range requestSections(s.workspace.asts()) {
+		item := completionItem{
+			Label: strings.TrimPrefix(section.ID.Text, "@"),
+			Kind:  completionKindReference,
+		}
+		if rl := section.RequestLine; rl != nil {
+			item.Detail = rl.Method.Text + " " + rl.Path.Text
+		}
+		res = append(res, item)
+	}
+	return res
+}
+
+// pathCompletion completes the keys of the latest response of request id at
+// the parent of path.
+func (s *Server) pathCompletion(ctx context.Context, id, path string) []completionItem {
+	res := []completionItem{}
+	if s.hits == nil {
+		return res
+	}
+	hit, err := s.hits.LoadLatestHitForID(ctx, id)
+	if err != nil {
+		return res
+	}
+	value := gjson.ParseBytes(hit.Response.Body)
+	if i := strings.LastIndex(path, "."); i >= 0 {
+		value = value.Get(path[:i])
+	}
+	switch {
+	case value.IsObject():
+		value.ForEach(func(key, value gjson.Result) bool {
+			res = append(res, completionItem{
+				Label:  key.String(),
+				Kind:   completionKindField,
+				Detail: value.Type.String(),
+			})
+			return true
+		})
+	case value.IsArray():
+		res = append(res, completionItem{
+			Label:  "#",
+			Kind:   completionKindField,
+			Detail: "number of elements",
+		})
+		for i := range value.Array() {
+			res = append(res, completionItem{
+				Label: strconv.Itoa(i),
+				Kind:  completionKindField,
+			})
+		}
+	}
+	return res
+}
+
+// referenceAtPosition returns the reference at pos in the document at uri
+// and the range it covers.
+func (s *Server) referenceAtPosition(uri string, p position) (reference, textRange, bool) {
+	src := s.document(uri)
+	pos, line := fromPosition(src, p)
+	if pos.Line == 0 {
+		return reference{}, textRange{}, false
+	}
+	ref, ok := referenceAt(line, pos.Column-1)
+	if !ok {
+		return reference{}, textRange{}, false
+	}
+	lineStart := pos.Offset - (pos.Column - 1)
+	span := parser.Span{
+		Start: parser.Pos{Offset: lineStart + ref.start, Line: pos.Line, Column: ref.start + 1},
+		End:   parser.Pos{Offset: lineStart + ref.end, Line: pos.Line, Column: ref.end + 1},
+	}
+	return ref, toRange(src, span), true
+}
+
+func (s *Server) definition(params textDocumentPositionParams) *location {
+	ref, _, ok := s.referenceAtPosition(params.TextDocument.URI, params.Position)
+	if !ok {
+		return nil
+	}
+	for _, ast := range s.workspace.asts() {
+		for _, node := range ast.Nodes {
+			section, ok := node.(*parser.RequestSection)
+			if !ok || section.ID.Text != "@"+ref.id() {
+				continue
+			}
+			return &location{
+				URI:   ast.Filename,
+				Range: toRange(string(ast.Bytes()), section.ID.Span),
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Server) hover(ctx context.Context, params textDocumentPositionParams) *hover {
+	ref, r, ok := s.referenceAtPosition(params.TextDocument.URI, params.Position)
+	if !ok {
+		return nil
+	}
+	markdown := func(format string, a ...interface{}) *hover {
+		return &hover{
+			Contents: markupContent{
+				Kind:  "markdown",
+				Value: fmt.Sprintf(format, a...),
+			},
+			Range: &r,
+		}
+	}
+	if n, err := strconv.Atoi(ref.key); err == nil {
+		return markdown("positional argument `@%d` from the command line", n)
+	}
+	if ref.path() == "" || s.hits == nil {
+		return nil
+	}
+	hit, err := s.hits.LoadLatestHitForID(ctx, ref.id())
+	if err != nil {
+		return markdown("no cached response for `@%s`", ref.id())
+	}
+	value := gjson.GetBytes(hit.Response.Body, ref.path())
+	if !value.Exists() {
+		return markdown("`%s` not found in the last response of `@%s`",
+			ref.path(), ref.id())
+	}
+	raw := []byte(value.Raw)
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, raw, "", "  "); err == nil {
+		raw = buf.Bytes()
+	}
+	return markdown("```json\n%s\n```\nlast response of `@%s` at %s", raw,
+		ref.id(), time.Unix(hit.CreatedAt, 0).Format(time.RFC3339))
+}
@@ -0,0 +1,238 @@
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/hbagdi/hit/pkg/model"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeHits map[string]model.Hit
+
+func (f fakeHits) LoadLatestHitForID(_ context.Context, id string) (model.Hit, error) {
+	hit, ok := f[id]
+	if !ok {
+		return model.Hit{}, sql.ErrNoRows
+	}
+	return hit, nil
+}
+
+type testClient struct {
+	t      *testing.T
+	writer io.Writer
+	reader *textproto.Reader
+	nextID int
+}
+
+func (c *testClient) send(id *int, method string, params interface{}) {
+	c.t.Helper()
+	m := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  method,
+		"params":  params,
+	}
+	if id != nil {
+		m["id"] = *id
+	}
+	body, err := json.Marshal(m)
+	require.NoError(c.t, err)
+	_, err = fmt.Fprintf(c.writer, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	require.NoError(c.t, err)
+}
+
+func (c *testClient) receive() map[string]json.RawMessage {
+	c.t.Helper()
+	header, err := c.reader.ReadMIMEHeader()
+	require.NoError(c.t, err)
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	require.NoError(c.t, err)
+	body := make([]byte, length)
+	_, err = io.ReadFull(c.reader.R, body)
+	require.NoError(c.t, err)
+	var m map[string]json.RawMessage
+	require.NoError(c.t, json.Unmarshal(body, &m))
+	return m
+}
+
+func (c *testClient) notify(method string, params interface{}) {
+	c.t.Helper()
+	c.send(nil, method, params)
+}
+
+// call sends a request and decodes its result into result.
+func (c *testClient) call(method string, params interface{}, result interface{}) {
+	c.t.Helper()
+	c.nextID++
+	id := c.nextID
+	c.send(&id, method, params)
+	m := c.receive()
+	require.Equal(c.t, strconv.Itoa(id), string(m["id"]), "unexpected message: %v", m)
+	require.NoError(c.t, json.Unmarshal(m["result"], result))
+}
+
+// diagnostics receives a publishDiagnostics notification.
+func (c *testClient) diagnostics() publishDiagnosticsParams {
+	c.t.Helper()
+	m := c.receive()
+	require.Equal(c.t, `"textDocument/publishDiagnostics"`, string(m["method"]))
+	var params publishDiagnosticsParams
+	require.NoError(c.t, json.Unmarshal(m["params"], &params))
+	return params
+}
+
+const globalFile = `@_global
+~
+baseURL: https://nodes.yolo42.com
+version: 1
+~
+
+@create-node
+POST /v1/node
+~y2j
+title: "@1"
+~
+`
+
+const nodesFile = `@get-node
+GET /v1/node/@create-node.id
+
+@get-parent
+GET /v1/node/@create-node.parent.id/@missing.id
+`
+
+func setup(t *testing.T) (*testClient, string) {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "global.hit"),
+		[]byte(globalFile), 0o600))
+
+	clientReader, serverWriter := io.Pipe()
+	serverReader, clientWriter := io.Pipe()
+	server := NewServer(Opts{
+		In:  serverReader,
+		Out: serverWriter,
+		Hits: fakeHits{
+			"create-node": model.Hit{
+				CreatedAt: 1667000000,
+				Response: model.Response{
+					Body: []byte(`{"id":"42","parent":{"id":"7","title":"root"}}`),
+				},
+			},
+		},
+	})
+	errCh := make(chan error)
+	go func() {
+		errCh <- server.Serve(context.Background())
+	}()
+	c := &testClient{
+		t:      t,
+		writer: clientWriter,
+		reader: textproto.NewReader(bufio.NewReader(clientReader)),
+	}
+	t.Cleanup(func() {
+		c.notify("exit", nil)
+		require.NoError(t, <-errCh)
+	})
+
+	var result initializeResult
+	c.call("initialize", map[string]string{"rootUri": pathToURI(dir)}, &result)
+	require.True(t, result.Capabilities.DefinitionProvider)
+	c.notify("initialized", struct{}{})
+	return c, dir
+}
+
+func TestServer(t *testing.T) {
+	c, dir := setup(t)
+	uri := pathToURI(filepath.Join(dir, "nodes.hit"))
+	document := textDocumentIdentifier{URI: uri}
+
+	c.notify("textDocument/didOpen", map[string]interface{}{
+		"textDocument": map[string]string{"uri": uri, "text": nodesFile},
+	})
+	diagnostics := c.diagnostics()
+	require.Equal(t, uri, diagnostics.URI)
+	require.Equal(t, []diagnostic{
+		{
+			Range: textRange{
+				Start: position{Line: 4, Character: 36},
+				End:   position{Line: 4, Character: 47},
+			},
+			Severity: severityError,
+			Source:   "hit",
+			Message:  "reference '@missing.id' to undefined request '@missing'",
+		},
+	}, diagnostics.Diagnostics)
+
+	t.Run("completes request IDs", func(t *testing.T) {
+		var items []completionItem
+		c.call("textDocument/completion", textDocumentPositionParams{
+			TextDocument: document,
+			Position:     position{Line: 1, Character: 15},
+		}, &items)
+		require.Equal(t, []completionItem{
+			{Label: "create-node", Kind: completionKindReference, Detail: "POST /v1/node"},
+			{Label: "get-node", Kind: completionKindReference, Detail: "GET /v1/node/@create-node.id"},
+			{
+				Label: "get-parent", Kind: completionKindReference,
+				Detail: "GET /v1/node/@create-node.parent.id/@missing.id",
+			},
+		}, items)
+	})
+	t.Run("completes cached JSON paths", func(t *testing.T) {
+		var items []completionItem
+		c.call("textDocument/completion", textDocumentPositionParams{
+			TextDocument: document,
+			Position:     position{Line: 4, Character: 33},
+		}, &items)
+		require.Equal(t, []completionItem{
+			{Label: "id", Kind: completionKindField, Detail: "String"},
+			{Label: "title", Kind: completionKindField, Detail: "String"},
+		}, items)
+	})
+	t.Run("goes to the definition of a reference", func(t *testing.T) {
+		var loc location
+		c.call("textDocument/definition", textDocumentPositionParams{
+			TextDocument: document,
+			Position:     position{Line: 1, Character: 20},
+		}, &loc)
+		require.Equal(t, location{
+			URI: pathToURI(filepath.Join(dir, "global.hit")),
+			Range: textRange{
+				Start: position{Line: 6, Character: 0},
+				End:   position{Line: 6, Character: 12},
+			},
+		}, loc)
+	})
+	t.Run("shows the cached value on hover", func(t *testing.T) {
+		var h hover
+		c.call("textDocument/hover", textDocumentPositionParams{
+			TextDocument: document,
+			Position:     position{Line: 4, Character: 20},
+		}, &h)
+		require.Equal(t, textRange{
+			Start: position{Line: 4, Character: 13},
+			End:   position{Line: 4, Character: 35},
+		}, *h.Range)
+		require.Contains(t, h.Contents.Value, "```json\n\"7\"\n```")
+	})
+	t.Run("reports parse errors", func(t *testing.T) {
+		c.notify("textDocument/didChange", map[string]interface{}{
+			"textDocument":   document,
+			"contentChanges": []contentChange{{Text: "@get-node\nGET\n"}},
+		})
+		diagnostics := c.diagnostics()
+		require.Len(t, diagnostics.Diagnostics, 1)
+		require.Equal(t, "invalid request line", diagnostics.Diagnostics[0].Message)
+		require.Equal(t, 1, diagnostics.Diagnostics[0].Range.Start.Line)
+	})
+}
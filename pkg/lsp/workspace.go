@@ -0,0 +1,163 @@
+package lsp
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/hbagdi/hit/pkg/parser"
+)
+
+// workspace holds the hit files of a directory along with the documents
+// opened by the client, which take precedence over the files on disk.
+type workspace struct {
+	root string
+	docs map[string]string
+}
+
+func pathToURI(path string) string {
+	u := url.URL{Scheme: "file", Path: filepath.ToSlash(path)}
+	return u.String()
+}
+
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return ""
+	}
+	return filepath.FromSlash(u.Path)
+}
+
+// asts parses every document of the workspace. The filename of each tree
+// is the URI of the document.
+func (w *workspace) asts() []*parser.AST {
+	seen := map[string]bool{}
+	var res []*parser.AST
+	if w.root != "" {
+		filenames, _ := filepath.Glob(filepath.Join(w.root, "*.hit"))
+		for _, filename := range filenames {
+			uri := pathToURI(filename)
+			seen[uri] = true
+			src, ok := w.docs[uri]
+			if !ok {
+				b, err := os.ReadFile(filename)
+				if err != nil {
+					continue
+				}
+				src = string(b)
+			}
+			res = append(res, parser.ParseAST(uri, []byte(src)))
+		}
+	}
+	uris := make([]string, 0, len(w.docs))
+	for uri := range w.docs {
+		if !seen[uri] {
+			uris = append(uris, uri)
+		}
+	}
+	sort.Strings(uris)
+	for _, uri := range uris {
+		res = append(res, parser.ParseAST(uri, []byte(w.docs[uri])))
+	}
+	return res
+}
+
+// requestSections returns all request definitions in asts.
+func requestSections(asts []*parser.AST) []*parser.RequestSection {
+	var res []*parser.RequestSection
+	for _, ast := range asts {
+		for _, node := range ast.Nodes {
+			if section, ok := node.(*parser.RequestSection); ok {
+				res = append(res, section)
+			}
+		}
+	}
+	return res
+}
+
+// toPosition converts a position in src into an LSP position.
+func toPosition(src string, p parser.Pos) position {
+	lineStart := p.Offset - (p.Column - 1)
+	if lineStart < 0 || p.Offset > len(src) {
+		return position{}
+	}
+	return position{
+		Line:      p.Line - 1,
+		Character: len(utf16.Encode([]rune(src[lineStart:p.Offset]))),
+	}
+}
+
+func toRange(src string, s parser.Span) textRange {
+	return textRange{Start: toPosition(src, s.Start), End: toPosition(src, s.End)}
+}
+
+// fromPosition converts an LSP position in src into a position and returns
+// the text of the line containing it.
+func fromPosition(src string, pos position) (parser.Pos, string) {
+	offset := 0
+	for i := 0; i < pos.Line; i++ {
+		n := strings.IndexByte(src[offset:], '\n')
+		if n < 0 {
+			return parser.Pos{}, ""
+		}
+		offset += n + 1
+	}
+	line := src[offset:]
+	if n := strings.IndexByte(line, '\n'); n >= 0 {
+		line = line[:n]
+	}
+	line = strings.TrimSuffix(line, "\r")
+
+	column, units := 0, 0
+	for column < len(line) && units < pos.Character {
+		r, size := utf8.DecodeRuneInString(line[column:])
+		units += len(utf16.Encode([]rune{r}))
+		column += size
+	}
+	return parser.Pos{
+		Offset: offset + column,
+		Line:   pos.Line + 1,
+		Column: column + 1,
+	}, line
+}
+
+var referenceRegex = regexp.MustCompile(`@[^\s"'/?&,@]+`)
+
+// reference is a reference such as '@1' or '@request-id.path' in a line.
+type reference struct {
+	// key is the reference without the leading '@'.
+	key string
+	// start and end are the byte offsets of the reference in the line.
+	start, end int
+}
+
+func (r reference) id() string {
+	return strings.SplitN(r.key, ".", 2)[0] //nolint:gomnd
+}
+
+func (r reference) path() string {
+	splits := strings.SplitN(r.key, ".", 2) //nolint:gomnd
+	if len(splits) == 1 {
+		return ""
+	}
+	return splits[1]
+}
+
+// referenceAt returns the reference in line at the zero-based byte column.
+func referenceAt(line string, column int) (reference, bool) {
+	for _, match := range referenceRegex.FindAllStringIndex(line, -1) {
+		if match[0] <= column && column <= match[1] {
+			return reference{
+				key:   line[match[0]+1 : match[1]],
+				start: match[0],
+				end:   match[1],
+			}, true
+		}
+	}
+	return reference{}, false
+}
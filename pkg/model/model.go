@@ -1,24 +1,56 @@
 package model
 
 import (
+	"fmt"
 	"net/http"
 	"net/url"
+	"strings"
+	"time"
 )
 
 type Hit struct {
 	ID           int
 	HitRequestID string
 	CreatedAt    int64
-	Request      Request
+	// Kind is the kind of the hit, HitKindHTTP if empty.
+	Kind    HitKind
+	Request Request
 	// RequestError  RequestError
 	// ResponseError ResponseError
 	Response Response
-	// Latency is NYI.
-	Latency Latency
-	// Network is NYI.
-	Network Network
+	// Redirects holds the redirect responses followed to receive Response,
+	// in the order they were received.
+	Redirects []Redirect
+	Latency   Latency
+	Network   Network
+	// TLS holds the parameters negotiated for HTTPS requests.
+	TLS TLS
+	// Proxy is the URL of the proxy the request was sent through, without
+	// the password.
+	Proxy string
+	// Attempts holds the outcome of every attempt to execute the request,
+	// the last one resulted in Response.
+	Attempts []Attempt
+	// Messages holds the messages sent and received during a WebSocket
+	// session, in the order they were sent or received.
+	Messages []Message
 }
 
+// HitKind is the kind of exchange recorded by a hit.
+type HitKind string
+
+const (
+	// HitKindHTTP is an HTTP request and its response.
+	HitKindHTTP HitKind = "http"
+	// HitKindWebSocket is a WebSocket session, the response is the response
+	// to the upgrade request.
+	HitKindWebSocket HitKind = "websocket"
+	// HitKindGRPC is a gRPC call. The code and status of the response are
+	// the gRPC status, its headers the header and trailer metadata and its
+	// body the JSON encoding of the response message.
+	HitKindGRPC HitKind = "grpc"
+)
+
 // type RequestError struct {
 // 	 Message string
 // }
@@ -27,6 +59,16 @@ type Hit struct {
 // 	 Message string
 // }
 
+const (
+	// MethodWebSocket is the method of requests which open a WebSocket
+	// session.
+	MethodWebSocket = "WS"
+	// MethodGRPC is the method of gRPC calls, the path of such requests is
+	// the full name of the method called, such as
+	// '/grpc.health.v1.Health/Check'.
+	MethodGRPC = "GRPC"
+)
+
 type Request struct {
 	Proto       string
 	Scheme      string
@@ -36,6 +78,137 @@ type Request struct {
 	QueryString string
 	Header      http.Header
 	Body        []byte
+	// Messages are the messages sent once a WebSocket session is opened.
+	Messages [][]byte
+	// Digest holds the credentials sent in response to a digest
+	// authentication challenge of the server, if any.
+	Digest *Credentials
+	// OAuth2 configures how the OAuth2 access token sent with the request
+	// is fetched, if any.
+	OAuth2 *OAuth2
+	// Signing configures how the request is signed, if at all.
+	Signing *Signing
+	// Secrets are credentials sent with the request, they are masked when
+	// the request is printed.
+	Secrets []string
+}
+
+// OAuth2 configures how an OAuth2 access token is fetched from the token
+// endpoint TokenURL.
+type OAuth2 struct {
+	TokenURL     string
+	Grant        string
+	ClientID     string
+	ClientSecret string
+	// Username and Password are the credentials of the password grant.
+	Username string
+	Password string
+	// RefreshToken is the token of the refresh_token grant.
+	RefreshToken string
+	Scopes       []string
+}
+
+// OAuth2 grants.
+const (
+	GrantClientCredentials = "client_credentials"
+	GrantPassword          = "password"
+	GrantRefreshToken      = "refresh_token"
+)
+
+// Token is an OAuth2 token.
+type Token struct {
+	AccessToken  string
+	RefreshToken string
+	// Expiry is the time the access token expires, it is zero if the token
+	// doesn't expire.
+	Expiry time.Time
+}
+
+// Cookie is a cookie of the cookie jar.
+type Cookie struct {
+	Domain string
+	Path   string
+	Name   string
+	Value  string
+	// Expires is the time the cookie expires, it is zero for session
+	// cookies.
+	Expires  time.Time
+	Secure   bool
+	HTTPOnly bool
+	// HostOnly is set if the cookie is only sent to Domain and not to its
+	// subdomains.
+	HostOnly bool
+}
+
+// Scope is the scope of stored cookies and variables: they are shared by the
+// requests of a project directory and environment.
+type Scope struct {
+	Directory   string
+	Environment string
+}
+
+// Credentials are a username and a password.
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// Signing configures how a request is signed. Either AWS or HMAC is set.
+type Signing struct {
+	AWS  *AWSSigning
+	HMAC *HMACSigning
+}
+
+// AWSSigning holds the credentials and scope of AWS Signature Version 4
+// signing.
+type AWSSigning struct {
+	Region          string
+	Service         string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// HMACSigning configures signing with an HMAC, see parser.HMACSignOptions.
+type HMACSigning struct {
+	Key             string
+	Algorithm       string
+	Encoding        string
+	Header          string
+	Prefix          string
+	TimestampHeader string
+	TimestampFormat string
+	Template        string
+}
+
+// SchemeHTTPUnix is the scheme of URLs of HTTP services listening on a Unix
+// socket. The host of such URLs is the percent-encoded path of the socket,
+// such as 'http+unix://%2Fvar%2Frun%2Fdocker.sock/info'.
+const SchemeHTTPUnix = "http+unix"
+
+// ParseURL parses rawURL like url.Parse. Unlike url.Parse, it accepts the
+// percent-encoded socket path in the host of URLs with the 'http+unix'
+// scheme, which is decoded into the host of the result.
+func ParseURL(rawURL string) (*url.URL, error) {
+	prefix := SchemeHTTPUnix + "://"
+	if !strings.HasPrefix(rawURL, prefix) {
+		return url.Parse(rawURL)
+	}
+	rest := rawURL[len(prefix):]
+	end := strings.IndexAny(rest, "/?#")
+	if end < 0 {
+		end = len(rest)
+	}
+	socket, err := url.PathUnescape(rest[:end])
+	if err != nil {
+		return nil, fmt.Errorf("parse %q: invalid socket path: %v", rawURL, err)
+	}
+	u, err := url.Parse(prefix + "localhost" + rest[end:])
+	if err != nil {
+		return nil, err
+	}
+	u.Host = socket
+	return u, nil
 }
 
 func (r Request) URL() string {
@@ -54,16 +227,71 @@ type Response struct {
 	Status string
 	Header http.Header
 	Body   []byte
+	// BodyFile is the file the body was written to instead of Body, if any.
+	BodyFile string
+	BodySize int64
 }
 
+// Event is an event of a Server-Sent Events stream.
+type Event struct {
+	ID   string
+	Type string
+	Data string
+}
+
+// Message is a message of a WebSocket session.
+type Message struct {
+	// Sent is true for messages sent to the server and false for messages
+	// received from it.
+	Sent   bool
+	Time   time.Time
+	Binary bool
+	Data   []byte
+}
+
+// Redirect is a redirect response followed while executing a request.
+type Redirect struct {
+	// URL is the URL of the request that was redirected.
+	URL  string
+	Code int
+	// Location is the URL the request was redirected to.
+	Location string
+}
+
+// Attempt is the outcome of an attempt to execute a request.
+type Attempt struct {
+	// Code is the response code, it is zero if the attempt failed with
+	// Error.
+	Code     int
+	Error    string
+	Duration time.Duration
+	// Delay is the time waited before the next attempt.
+	Delay time.Duration
+}
+
+// Latency holds the time spent in each phase of a request. Phases that
+// were skipped, such as DNS resolution for a reused connection, are zero.
 type Latency struct {
-	DNSResolution int
-	TCPConnection int
-	TLSConnection int
-	// TTFB int ?
+	DNSResolution time.Duration
+	TCPConnection time.Duration
+	TLSConnection time.Duration
+	// TTFB is the time between writing the request and receiving the
+	// first byte of the response.
+	TTFB time.Duration
+	// Total is the time between starting the request and reading the
+	// complete response.
+	Total time.Duration
+}
+
+// TLS holds the parameters of a TLS connection.
+type TLS struct {
+	// Version is the TLS version such as 'TLS 1.3'.
+	Version     string
+	CipherSuite string
 }
 
 type Network struct {
+	// DNSServer is NYI.
 	DNSServer string
 	IPInUse   string
 	PortInUse int
@@ -0,0 +1,26 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseURL(t *testing.T) {
+	u, err := ParseURL("http+unix://%2Fvar%2Frun%2Fdocker.sock/v1.41/info?all=1")
+	require.NoError(t, err)
+	require.Equal(t, "http+unix", u.Scheme)
+	require.Equal(t, "/var/run/docker.sock", u.Host)
+	require.Equal(t, "/v1.41/info", u.Path)
+	require.Equal(t, "all=1", u.RawQuery)
+
+	r := Request{Scheme: u.Scheme, Host: u.Host, Path: u.Path, QueryString: u.RawQuery}
+	require.Equal(t, "http+unix://%2Fvar%2Frun%2Fdocker.sock/v1.41/info?all=1", r.URL())
+
+	u, err = ParseURL("https://example.com/a")
+	require.NoError(t, err)
+	require.Equal(t, "example.com", u.Host)
+
+	_, err = ParseURL("http+unix://%zz/")
+	require.Error(t, err)
+}
@@ -0,0 +1,30 @@
+package parser
+
+import "encoding/json"
+
+// Assertions are the expectations on the response of a request, they are
+// checked by 'hit test'.
+type Assertions struct {
+	// Status is the expected response code.
+	Status *int `json:"status,omitempty"`
+	// Headers are the expected values of response headers.
+	Headers map[string]string `json:"headers,omitempty"`
+	// Body are assertions on the JSON response body.
+	Body []BodyAssertion `json:"body,omitempty"`
+	// MaxLatency is the time the response must be received within such
+	// as '500ms'.
+	MaxLatency *Duration `json:"maxLatency,omitempty"`
+}
+
+// BodyAssertion is an assertion on the value at a gjson path of a JSON
+// response body such as 'items.0.name'. At least one of Equals, Matches and
+// Exists is set.
+type BodyAssertion struct {
+	Path string `json:"path"`
+	// Equals is the JSON value expected at Path.
+	Equals json.RawMessage `json:"equals,omitempty"`
+	// Matches is a regular expression the value at Path must match.
+	Matches string `json:"matches,omitempty"`
+	// Exists asserts whether there is a value at Path.
+	Exists *bool `json:"exists,omitempty"`
+}
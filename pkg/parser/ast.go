@@ -0,0 +1,547 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Pos is a position in a hit file. Line and Column are 1-based, Column and
+// Offset are counted in bytes.
+type Pos struct {
+	Offset int
+	Line   int
+	Column int
+}
+
+// Span is the half-open range [Start, End) covered by a node.
+type Span struct {
+	Start Pos
+	End   Pos
+}
+
+// Contains reports whether p lies within s. The end of the span is
+// considered to be within it so that a cursor placed right after a token
+// matches the token.
+func (s Span) Contains(p Pos) bool {
+	return s.Start.Offset <= p.Offset && p.Offset <= s.End.Offset
+}
+
+// Line is a single line of a hit file.
+type Line struct {
+	// Span covers Text, the line terminator is not included.
+	Span Span
+	// Text is the content of the line without the line terminator.
+	Text string
+	// EOL is the line terminator: "\n", "\r\n" or "" for the last line of
+	// a file that doesn't end in a newline.
+	EOL string
+}
+
+func (l Line) isComment() bool {
+	return strings.HasPrefix(l.Text, "#")
+}
+
+// Token is a part of a line, such as a request ID or a header name.
+type Token struct {
+	Span Span
+	Text string
+}
+
+// Error is a syntax error in a hit file.
+type Error struct {
+	Span    Span
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Span.Start.Line, e.Span.Start.Column,
+		e.Message)
+}
+
+// AST is a lossless syntax tree of a hit file. Writing out the lines of all
+// nodes in order reproduces the source byte-for-byte.
+type AST struct {
+	Filename string
+	Nodes    []Node
+	// Errors holds syntax errors found while building the tree, in the
+	// order of their occurrence.
+	Errors []*Error
+}
+
+// Bytes returns the source of the tree.
+func (a *AST) Bytes() []byte {
+	var buf bytes.Buffer
+	for _, node := range a.Nodes {
+		for _, line := range node.Lines() {
+			buf.WriteString(line.Text)
+			buf.WriteString(line.EOL)
+		}
+	}
+	return buf.Bytes()
+}
+
+// Node is a top-level element of a hit file.
+type Node interface {
+	Span() Span
+	// Lines returns all source lines of the node, including comments.
+	Lines() []Line
+}
+
+type lines []Line
+
+func (l lines) Lines() []Line {
+	return l
+}
+
+func (l lines) Span() Span {
+	if len(l) == 0 {
+		return Span{}
+	}
+	return Span{Start: l[0].Span.Start, End: l[len(l)-1].Span.End}
+}
+
+// Blank is an empty line outside of a section.
+type Blank struct {
+	lines
+}
+
+// Comment is a line starting with '#'.
+type Comment struct {
+	lines
+}
+
+// Text is a line outside of any section that is neither blank nor a
+// comment.
+type Text struct {
+	lines
+}
+
+// Block is a section of lines delimited by a line starting with '~' and a
+// line containing only '~'.
+type Block struct {
+	Span Span
+	// Open is the line starting the block.
+	Open Line
+	// Name is the text following '~' in the opening line, such as the body
+	// encoding.
+	Name Token
+	// Content holds the lines between the opening and closing lines,
+	// including comments.
+	Content []Line
+	// Close is the line ending the block, nil if the block is not
+	// terminated.
+	Close *Line
+}
+
+// Text returns the content of the block without comments.
+func (b *Block) Text() []string {
+	var res []string
+	for _, line := range b.Content {
+		if line.isComment() {
+			continue
+		}
+		res = append(res, line.Text)
+	}
+	return res
+}
+
+// GlobalSection is the '@_global' section of a hit file.
+type GlobalSection struct {
+	lines
+	Header Token
+	// Block holds the YAML configuration, nil if the section has none.
+	Block    *Block
+	Comments []*Comment
+}
+
+// FlowSection is a '@_flow name' section of a hit file, a named sequence
+// of requests.
+type FlowSection struct {
+	lines
+	Header Token
+	// Name is the name of the flow following '@_flow'.
+	Name Token
+	// Block holds the YAML definition, nil if the section has none.
+	Block    *Block
+	Comments []*Comment
+}
+
+// RequestLine is the line containing the method and path of a request.
+type RequestLine struct {
+	Span   Span
+	Method Token
+	Path   Token
+}
+
+// Header is a header line of a request.
+type Header struct {
+	Span  Span
+	Name  Token
+	Value Token
+}
+
+// RequestSection is a request definition in a hit file.
+type RequestSection struct {
+	lines
+	// ID is the request ID including the leading '@'.
+	ID Token
+	// RequestLine is nil if the request has no request line.
+	RequestLine *RequestLine
+	Headers     []*Header
+	Blocks      []*Block
+	Comments    []*Comment
+}
+
+// Body returns the body block of the request, nil if the request has no
+// body.
+func (r *RequestSection) Body() *Block {
+	if len(r.Blocks) == 0 {
+		return nil
+	}
+	block := r.Blocks[len(r.Blocks)-1]
+	if settingsBlocks[block.Name.Text] {
+		return nil
+	}
+	return block
+}
+
+// Block returns the settings block name of the request, nil if the request
+// has no such block.
+func (r *RequestSection) Block(name string) *Block {
+	for _, block := range r.Blocks {
+		if block.Name.Text == name && settingsBlocks[name] {
+			return block
+		}
+	}
+	return nil
+}
+
+const (
+	optionsBlock = "options"
+	assertBlock  = "assert"
+	captureBlock = "capture"
+)
+
+// settingsBlocks are the names of blocks holding YAML settings of a request.
+// Settings blocks follow the headers and precede the body of a request.
+var settingsBlocks = map[string]bool{
+	optionsBlock: true,
+	assertBlock:  true,
+	captureBlock: true,
+}
+
+// ParseAST parses src into a syntax tree. The tree always covers the
+// complete source, syntax errors are recorded in AST.Errors.
+func ParseAST(filename string, src []byte) *AST {
+	p := &astParser{
+		lines: splitLines(src),
+		ast:   &AST{Filename: filename},
+	}
+	p.parse()
+	return p.ast
+}
+
+func splitLines(src []byte) []Line {
+	var (
+		res    []Line
+		offset int
+		n      = 1
+	)
+	for offset < len(src) {
+		text := src[offset:]
+		eol := ""
+		if i := bytes.IndexByte(text, '\n'); i >= 0 {
+			text = text[:i]
+			eol = "\n"
+		}
+		if bytes.HasSuffix(text, []byte("\r")) {
+			text = text[:len(text)-1]
+			eol = "\r" + eol
+		}
+		res = append(res, Line{
+			Span: Span{
+				Start: Pos{Offset: offset, Line: n, Column: 1},
+				End: Pos{
+					Offset: offset + len(text),
+					Line:   n,
+					Column: len(text) + 1,
+				},
+			},
+			Text: string(text),
+			EOL:  eol,
+		})
+		offset += len(text) + len(eol)
+		n++
+	}
+	return res
+}
+
+type astParser struct {
+	lines []Line
+	i     int
+	ast   *AST
+}
+
+func (p *astParser) errorf(span Span, format string, a ...interface{}) {
+	p.ast.Errors = append(p.ast.Errors, &Error{
+		Span:    span,
+		Message: fmt.Sprintf(format, a...),
+	})
+}
+
+func (p *astParser) done() bool {
+	return p.i >= len(p.lines)
+}
+
+func (p *astParser) parse() {
+	for !p.done() {
+		line := p.lines[p.i]
+		switch {
+		case line.Text == "":
+			p.ast.Nodes = append(p.ast.Nodes, &Blank{lines{line}})
+			p.i++
+		case line.isComment():
+			p.ast.Nodes = append(p.ast.Nodes, &Comment{lines{line}})
+			p.i++
+		case line.Text == "@_global":
+			p.ast.Nodes = append(p.ast.Nodes, p.global())
+		case line.Text == flowHeader || strings.HasPrefix(line.Text, flowHeader+" "):
+			p.ast.Nodes = append(p.ast.Nodes, p.flow())
+		case strings.HasPrefix(line.Text, "@"):
+			p.ast.Nodes = append(p.ast.Nodes, p.request())
+		default:
+			p.errorf(line.Span, "unexpected line: '%v'", line.Text)
+			p.ast.Nodes = append(p.ast.Nodes, &Text{lines{line}})
+			p.i++
+		}
+	}
+}
+
+// skipComments consumes comment lines and returns them.
+func (p *astParser) skipComments() []*Comment {
+	var res []*Comment
+	for !p.done() && p.lines[p.i].isComment() {
+		res = append(res, &Comment{lines{p.lines[p.i]}})
+		p.i++
+	}
+	return res
+}
+
+func (p *astParser) global() *GlobalSection {
+	start := p.i
+	header := p.lines[p.i]
+	res := &GlobalSection{
+		Header: Token{Span: header.Span, Text: header.Text},
+	}
+	p.i++
+	res.Block, res.Comments = p.sectionBlock(header, "@_global")
+	res.lines = p.lines[start:p.i]
+	return res
+}
+
+const flowHeader = "@_flow"
+
+var flowNameRegex = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9-_]*$`)
+
+func (p *astParser) flow() *FlowSection {
+	start := p.i
+	header := p.lines[p.i]
+	res := &FlowSection{
+		Header: Token{Span: header.Span, Text: header.Text},
+	}
+	name := strings.TrimLeft(header.Text[len(flowHeader):], " ")
+	res.Name = subToken(header, len(header.Text)-len(name), len(header.Text))
+	if !flowNameRegex.MatchString(name) {
+		p.errorf(header.Span, "invalid flow name: '%v'", name)
+	}
+	p.i++
+	res.Block, res.Comments = p.sectionBlock(header, flowHeader)
+	res.lines = p.lines[start:p.i]
+	return res
+}
+
+// sectionBlock parses the YAML block following the header of a section
+// such as '@_global'. The block is nil if the section has none.
+func (p *astParser) sectionBlock(header Line, section string) (*Block, []*Comment) {
+	comments := p.skipComments()
+	if p.done() || p.lines[p.i].Text != "~" {
+		p.errorf(header.Span, "expected '~' in the %s section", section)
+		return nil, comments
+	}
+	open := p.lines[p.i]
+	p.i++
+	block := &Block{
+		Open: open,
+		Name: Token{Span: Span{Start: open.Span.End, End: open.Span.End}},
+	}
+	for {
+		if p.done() || p.lines[p.i].Text == "" {
+			p.errorf(header.Span, "expected '~' to terminate %s section", section)
+			break
+		}
+		line := p.lines[p.i]
+		p.i++
+		if line.Text == "~" {
+			block.Close = &line
+			break
+		}
+		if line.isComment() {
+			comments = append(comments, &Comment{lines{line}})
+		}
+		block.Content = append(block.Content, line)
+	}
+	block.Span = Span{Start: open.Span.Start, End: p.lines[p.i-1].Span.End}
+	return block, comments
+}
+
+func (p *astParser) request() *RequestSection {
+	start := p.i
+	header := p.lines[p.i]
+	res := &RequestSection{
+		ID: Token{Span: header.Span, Text: header.Text},
+	}
+	if !idRegex.MatchString(header.Text) {
+		p.errorf(header.Span, "invalid id: '%v'", header.Text)
+	}
+	p.i++
+
+	// a request spans until the next blank line
+	var content []int
+	for !p.done() && p.lines[p.i].Text != "" {
+		if p.lines[p.i].isComment() {
+			res.Comments = append(res.Comments, &Comment{lines{p.lines[p.i]}})
+		} else {
+			content = append(content, p.i)
+		}
+		p.i++
+	}
+	res.lines = p.lines[start:p.i]
+
+	if len(content) == 0 {
+		p.errorf(header.Span, "no request data")
+		return res
+	}
+	res.RequestLine = p.requestLine(p.lines[content[0]])
+
+	i := 1
+	for ; i < len(content); i++ {
+		line := p.lines[content[i]]
+		if strings.HasPrefix(line.Text, "~") {
+			break
+		}
+		res.Headers = append(res.Headers, p.header(line))
+	}
+	for i < len(content) {
+		line := p.lines[content[i]]
+		name := line.Text[1:]
+		if !settingsBlocks[name] {
+			break
+		}
+		if res.Block(name) != nil {
+			p.errorf(line.Span, "duplicate ~%s block", name)
+		}
+		var block *Block
+		block, i = p.settingsBlock(content, i)
+		res.Blocks = append(res.Blocks, block)
+	}
+	if i == len(content) {
+		return res
+	}
+	res.Blocks = append(res.Blocks, p.body(content[i:]))
+	return res
+}
+
+// settingsBlock parses the settings block opened by content[i]. It returns
+// the block and the index in content following it.
+func (p *astParser) settingsBlock(content []int, i int) (*Block, int) {
+	open := p.lines[content[i]]
+	res := &Block{
+		Open: open,
+		Name: subToken(open, 1, len(open.Text)),
+	}
+	for j := i + 1; j < len(content); j++ {
+		line := p.lines[content[j]]
+		if line.Text == "~" {
+			res.Span = Span{Start: open.Span.Start, End: line.Span.End}
+			res.Content = p.lines[content[i]+1 : content[j]]
+			res.Close = &line
+			return res, j + 1
+		}
+	}
+	p.errorf(open.Span, "expected '~' to terminate ~%s block", res.Name.Text)
+	last := content[len(content)-1]
+	res.Span = Span{Start: open.Span.Start, End: p.lines[last].Span.End}
+	res.Content = p.lines[content[i]+1 : last+1]
+	return res, len(content)
+}
+
+var requestLineRegex = regexp.MustCompile(`^([a-zA-Z]+) (\/.*)$`)
+
+func (p *astParser) requestLine(line Line) *RequestLine {
+	res := &RequestLine{Span: line.Span}
+	matches := requestLineRegex.FindStringSubmatchIndex(line.Text)
+	if matches == nil {
+		p.errorf(line.Span, "invalid request line")
+		return res
+	}
+	res.Method = subToken(line, matches[2], matches[3])
+	res.Path = subToken(line, matches[4], matches[5])
+	return res
+}
+
+func (p *astParser) header(line Line) *Header {
+	res := &Header{Span: line.Span}
+	i := strings.Index(line.Text, ":")
+	if i < 0 {
+		p.errorf(line.Span, "invalid header line: '%v'", line.Text)
+		res.Name = subToken(line, 0, len(line.Text))
+		return res
+	}
+	res.Name = subToken(line, 0, i)
+	res.Value = subToken(line, i+1, len(line.Text))
+	return res
+}
+
+// body parses the body block of a request. The block spans from the first
+// line in indexes to the last one.
+func (p *astParser) body(indexes []int) *Block {
+	first, last := indexes[0], indexes[len(indexes)-1]
+	open := p.lines[first]
+	res := &Block{
+		Span:    Span{Start: open.Span.Start, End: p.lines[last].Span.End},
+		Open:    open,
+		Name:    subToken(open, 1, len(open.Text)),
+		Content: p.lines[first+1 : last],
+	}
+	if len(indexes) == 1 {
+		p.errorf(open.Span, "invalid input: expected body")
+		res.Content = nil
+		return res
+	}
+	closing := p.lines[last]
+	if closing.Text != "~" {
+		p.errorf(closing.Span, "invalid end of body: '%s', expected '~'",
+			closing.Text)
+		res.Content = p.lines[first+1 : last+1]
+		return res
+	}
+	res.Close = &closing
+	return res
+}
+
+// subToken returns the token for the bytes [start, end) of line.
+func subToken(line Line, start, end int) Token {
+	pos := func(i int) Pos {
+		return Pos{
+			Offset: line.Span.Start.Offset + i,
+			Line:   line.Span.Start.Line,
+			Column: line.Span.Start.Column + i,
+		}
+	}
+	return Token{
+		Span: Span{Start: pos(start), End: pos(end)},
+		Text: line.Text[start:end],
+	}
+}
@@ -0,0 +1,304 @@
+package parser
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testFile = `# workspace for nodes
+@_global
+~
+# base URL of the service
+baseURL: https://nodes.yolo42.com
+version: 1
+~
+
+
+@create-node
+POST /v1/node
+# set by the gateway
+x-request-id:42
+~y2j
+# title of the node
+title: "@1"
+~
+
+@get-node
+GET /v1/node/@1
+`
+
+func TestParseASTRoundTrip(t *testing.T) {
+	for _, src := range []string{
+		testFile,
+		"",
+		"\n\n",
+		"@get\nGET /foo",
+		"@get\r\nGET /foo\r\nfoo:bar\r\n\r\n",
+		"# only a comment\n",
+		"@_global\n~\nversion: 1\n",
+		"@bad id\nGET\n/foo\n\nstray text\n",
+		"@post\nPOST /foo\n~\nbody\n",
+	} {
+		ast := ParseAST("test.hit", []byte(src))
+		require.Equal(t, src, string(ast.Bytes()))
+	}
+}
+
+func TestParseASTSpans(t *testing.T) {
+	ast := ParseAST("test.hit", []byte(testFile))
+	require.Empty(t, ast.Errors)
+
+	var requests []*RequestSection
+	var global *GlobalSection
+	comments := 0
+	for _, node := range ast.Nodes {
+		switch n := node.(type) {
+		case *RequestSection:
+			requests = append(requests, n)
+		case *GlobalSection:
+			global = n
+		case *Comment:
+			comments++
+		}
+	}
+	require.Equal(t, 1, comments)
+	require.NotNil(t, global)
+	require.Len(t, global.Comments, 1)
+	require.Equal(t, 3, global.Block.Span.Start.Line)
+	require.Equal(t, 7, global.Block.Span.End.Line)
+	require.Len(t, requests, 2)
+
+	r := requests[0]
+	require.Equal(t, "@create-node", r.ID.Text)
+	require.Equal(t, Pos{Offset: 108, Line: 10, Column: 1}, r.ID.Span.Start)
+	require.Equal(t, "POST", r.RequestLine.Method.Text)
+	require.Equal(t, "/v1/node", r.RequestLine.Path.Text)
+	require.Equal(t, 11, r.RequestLine.Path.Span.Start.Line)
+	require.Equal(t, 6, r.RequestLine.Path.Span.Start.Column)
+	require.Len(t, r.Headers, 1)
+	require.Equal(t, "x-request-id", r.Headers[0].Name.Text)
+	require.Equal(t, "42", r.Headers[0].Value.Text)
+	require.Equal(t, 14, r.Headers[0].Value.Span.Start.Column)
+	require.Len(t, r.Comments, 2)
+
+	body := r.Body()
+	require.NotNil(t, body)
+	require.Equal(t, "y2j", body.Name.Text)
+	require.Equal(t, 14, body.Span.Start.Line)
+	require.Equal(t, 17, body.Span.End.Line)
+	require.Equal(t, []string{`title: "@1"`}, body.Text())
+	require.Nil(t, requests[1].Body())
+}
+
+func TestParseASTErrors(t *testing.T) {
+	ast := ParseAST("test.hit", []byte("@bad id\nGET\n/foo\n\nstray text\n"))
+	require.Len(t, ast.Errors, 4)
+	require.Equal(t, "1:1: invalid id: '@bad id'", ast.Errors[0].Error())
+	require.Equal(t, "2:1: invalid request line", ast.Errors[1].Error())
+	require.Equal(t, "3:1: invalid header line: '/foo'", ast.Errors[2].Error())
+	require.Equal(t, "5:1: unexpected line: 'stray text'", ast.Errors[3].Error())
+
+	_, err := ast.File()
+	require.EqualError(t, err, "1:1: invalid id: '@bad id'")
+}
+
+func TestASTFile(t *testing.T) {
+	file, err := ParseAST("test.hit", []byte(testFile)).File()
+	require.NoError(t, err)
+	require.Equal(t, File{
+		Global: Global{
+			Version: 1,
+			Options: Options{BaseURL: "https://nodes.yolo42.com"},
+		},
+		Requests: []Request{
+			{
+				ID:           "create-node",
+				Method:       "POST",
+				Path:         "/v1/node",
+				Headers:      map[string][]string{"x-request-id": {"42"}},
+				BodyEncoding: "y2j",
+				Body:         []string{`title: "@1"`},
+			},
+			{
+				ID:     "get-node",
+				Method: "GET",
+				Path:   "/v1/node/@1",
+			},
+		},
+	}, file)
+}
+
+func TestParseOptionsBlock(t *testing.T) {
+	src := `@create-node
+POST /v1/node
+~options
+# give slow backends time
+timeout: 30s
+followRedirects: true
+~
+~y2j
+title: foo
+~
+
+@get-node
+GET /v1/node/@1
+~options
+maxRedirects: 3
+~
+`
+	ast := ParseAST("test.hit", []byte(src))
+	require.Empty(t, ast.Errors)
+	require.Equal(t, src, string(ast.Bytes()))
+	section, ok := ast.Nodes[0].(*RequestSection)
+	require.True(t, ok)
+	require.Equal(t, "options", section.Block("options").Name.Text)
+	require.Equal(t, "y2j", section.Body().Name.Text)
+
+	file, err := ast.File()
+	require.NoError(t, err)
+	timeout := Duration(30 * time.Second)
+	follow := true
+	maxRedirects := 3
+	require.Equal(t, Options{Timeout: &timeout, FollowRedirects: &follow},
+		file.Requests[0].Options)
+	require.Equal(t, []string{"title: foo"}, file.Requests[0].Body)
+	require.Equal(t, Options{MaxRedirects: &maxRedirects}, file.Requests[1].Options)
+	require.Nil(t, file.Requests[1].Body)
+
+	_, err = ParseAST("test.hit", []byte("@get\nGET /\n~options\ntimeout: 1\n~\n")).File()
+	require.EqualError(t, err, "3:1: parse ~options block: error unmarshaling "+
+		"JSON: invalid duration 1: expected a string such as '10s'")
+
+	ast = ParseAST("test.hit", []byte("@get\nGET /\n~options\ntimeout: 1s\n"))
+	require.Len(t, ast.Errors, 1)
+	require.Equal(t, "3:1: expected '~' to terminate ~options block",
+		ast.Errors[0].Error())
+}
+
+func TestParseAssertBlock(t *testing.T) {
+	src := `@get-node
+GET /v1/node/@1
+~options
+timeout: 30s
+~
+~assert
+status: 200
+headers:
+  Content-Type: application/json
+body:
+- path: id
+  exists: true
+- path: tags.0
+  equals: {"name": "x"}
+- path: name
+  matches: ^node-
+maxLatency: 500ms
+~
+`
+	ast := ParseAST("test.hit", []byte(src))
+	require.Empty(t, ast.Errors)
+	require.Equal(t, src, string(ast.Bytes()))
+	section, ok := ast.Nodes[0].(*RequestSection)
+	require.True(t, ok)
+	require.Nil(t, section.Body())
+
+	file, err := ast.File()
+	require.NoError(t, err)
+	status := 200
+	exists := true
+	maxLatency := Duration(500 * time.Millisecond)
+	require.Equal(t, &Assertions{
+		Status:  &status,
+		Headers: map[string]string{"Content-Type": "application/json"},
+		Body: []BodyAssertion{
+			{Path: "id", Exists: &exists},
+			{Path: "tags.0", Equals: []byte(`{"name":"x"}`)},
+			{Path: "name", Matches: "^node-"},
+		},
+		MaxLatency: &maxLatency,
+	}, file.Requests[0].Assertions)
+	require.NotNil(t, file.Requests[0].Options.Timeout)
+
+	_, err = ParseAST("test.hit", []byte("@get\nGET /\n~assert\nstatus: ok\n~\n")).File()
+	require.ErrorContains(t, err, "3:1: parse ~assert block: ")
+}
+
+func TestParseCaptureBlock(t *testing.T) {
+	src := `@login
+POST /login
+~capture
+token:
+  body: data.token
+session:
+  header: X-Session
+version:
+  regex: 'v(\d+)'
+~
+~y2j
+username: admin
+~
+`
+	ast := ParseAST("test.hit", []byte(src))
+	require.Empty(t, ast.Errors)
+	require.Equal(t, src, string(ast.Bytes()))
+
+	file, err := ast.File()
+	require.NoError(t, err)
+	require.Equal(t, map[string]Capture{
+		"token":   {Body: "data.token"},
+		"session": {Header: "X-Session"},
+		"version": {Regex: `v(\d+)`},
+	}, file.Requests[0].Captures)
+	require.Equal(t, []string{"username: admin"}, file.Requests[0].Body)
+	require.Nil(t, file.Requests[0].Assertions)
+
+	_, err = ParseAST("test.hit", []byte("@get\nGET /\n~capture\n- body\n~\n")).File()
+	require.ErrorContains(t, err, "3:1: parse ~capture block: ")
+}
+
+func TestParseFlowSection(t *testing.T) {
+	src := `@_flow smoke
+# create and fetch a node
+~
+continueOnFailure: true
+steps:
+- "@login admin"
+- "@create-node"
+~
+
+@login
+POST /login
+`
+	ast := ParseAST("test.hit", []byte(src))
+	require.Empty(t, ast.Errors)
+	require.Equal(t, src, string(ast.Bytes()))
+	section, ok := ast.Nodes[0].(*FlowSection)
+	require.True(t, ok)
+	require.Equal(t, "smoke", section.Name.Text)
+	require.Equal(t, 8, section.Name.Span.Start.Column)
+	require.Len(t, section.Comments, 1)
+	require.Equal(t, 3, section.Block.Span.Start.Line)
+	require.Equal(t, 8, section.Block.Span.End.Line)
+
+	file, err := ast.File()
+	require.NoError(t, err)
+	require.Equal(t, []Flow{{
+		Name: "smoke",
+		Steps: []Step{
+			{ID: "login", Args: []string{"admin"}},
+			{ID: "create-node", Args: []string{}},
+		},
+		ContinueOnFailure: true,
+	}}, file.Flows)
+	require.Len(t, file.Requests, 1)
+
+	ast = ParseAST("test.hit", []byte("@_flow bad name\n~\nsteps: []\n~\n"))
+	require.Len(t, ast.Errors, 1)
+	require.Equal(t, "1:1: invalid flow name: 'bad name'", ast.Errors[0].Error())
+
+	_, err = ParseAST("test.hit", []byte("@_flow smoke\n~\nsteps:\n- login\n~\n")).File()
+	require.EqualError(t, err, "2:1: invalid step 'login': expected a request "+
+		"ID such as '@login' followed by its arguments")
+}
@@ -0,0 +1,16 @@
+package parser
+
+// Capture is the source of a variable set from the response of a request,
+// exactly one of Body, Header and Regex is set. Captured variables are
+// referenced as '@var.name'.
+type Capture struct {
+	// Body is a gjson path of a value of the JSON response body such as
+	// 'data.token'.
+	Body string `json:"body,omitempty"`
+	// Header is the name of a response header.
+	Header string `json:"header,omitempty"`
+	// Regex is a regular expression matched against the response body.
+	// The first submatch is captured, or the whole match if the expression
+	// has no group.
+	Regex string `json:"regex,omitempty"`
+}
@@ -0,0 +1,72 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Flow is a named sequence of requests defined in a '@_flow' section.
+type Flow struct {
+	Name  string
+	Steps []Step
+	// ContinueOnFailure executes the remaining steps once a step failed,
+	// they are skipped otherwise.
+	ContinueOnFailure bool
+}
+
+// Step is a request of a flow.
+type Step struct {
+	// ID is the ID of the request without the leading '@'.
+	ID string
+	// Args are the positional arguments of the request.
+	Args []string
+	// Literals is the number of Args from the first one onwards which are
+	// values, even if they begin with '@'.
+	Literals int
+}
+
+// String returns the step as it is written in a flow, such as
+// '@login admin'.
+func (s Step) String() string {
+	return strings.Join(append([]string{"@" + s.ID}, s.Args...), " ")
+}
+
+// flowDefinition is the YAML definition of a flow. Steps are written as on
+// the command line, a request ID followed by its arguments.
+type flowDefinition struct {
+	Steps             []string `json:"steps"`
+	ContinueOnFailure bool     `json:"continueOnFailure"`
+}
+
+func flow(n *FlowSection) (Flow, error) {
+	res := Flow{Name: n.Name.Text}
+	if n.Block == nil {
+		return res, nil
+	}
+	var def flowDefinition
+	if err := unmarshalBlock(n.Block, &def); err != nil {
+		return Flow{}, &Error{
+			Span:    n.Block.Span,
+			Message: fmt.Sprintf("parse %s section: %v", flowHeader, err),
+		}
+	}
+	res.ContinueOnFailure = def.ContinueOnFailure
+	for _, s := range def.Steps {
+		step, err := ParseStep(s)
+		if err != nil {
+			return Flow{}, &Error{Span: n.Block.Span, Message: err.Error()}
+		}
+		res.Steps = append(res.Steps, step)
+	}
+	return res, nil
+}
+
+// ParseStep parses a step such as '@login admin'.
+func ParseStep(s string) (Step, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 || !idRegex.MatchString(fields[0]) {
+		return Step{}, fmt.Errorf("invalid step '%v': expected a request ID "+
+			"such as '@login' followed by its arguments", s)
+	}
+	return Step{ID: fields[0][1:], Args: fields[1:]}, nil
+}
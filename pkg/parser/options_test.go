@@ -0,0 +1,64 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptionsMergeTLS(t *testing.T) {
+	insecure := true
+	global := Options{TLS: &TLSOptions{CAFile: "ca.pem", MinVersion: "1.2"}}
+	request := Options{TLS: &TLSOptions{MinVersion: "1.3", InsecureSkipVerify: &insecure}}
+
+	merged := global.Merge(request)
+	require.Equal(t, &TLSOptions{
+		CAFile:             "ca.pem",
+		MinVersion:         "1.3",
+		InsecureSkipVerify: &insecure,
+	}, merged.TLS)
+	// merging must not modify the options merged into
+	require.Equal(t, &TLSOptions{CAFile: "ca.pem", MinVersion: "1.2"}, global.TLS)
+
+	require.Equal(t, global.TLS, global.Merge(Options{}).TLS)
+	require.Equal(t, request.TLS, Options{}.Merge(request).TLS)
+}
+
+func TestOptionsMergeResolve(t *testing.T) {
+	global := Options{Resolve: map[string]string{
+		"api.example.com:443": "10.0.0.1",
+		"auth.example.com":    "10.0.0.2",
+	}}
+	request := Options{Resolve: map[string]string{"api.example.com:443": "10.0.0.3"}}
+	require.Equal(t, map[string]string{
+		"api.example.com:443": "10.0.0.3",
+		"auth.example.com":    "10.0.0.2",
+	}, global.Merge(request).Resolve)
+	require.Equal(t, "10.0.0.1", global.Resolve["api.example.com:443"])
+}
+
+func TestSize(t *testing.T) {
+	for s, expected := range map[string]Size{
+		"512":    512,
+		"512B":   512,
+		"2KB":    2048,
+		"1.5 mb": 1572864,
+		"1GB":    1 << 30,
+	} {
+		size, err := ParseSize(s)
+		require.NoError(t, err)
+		require.Equal(t, expected, size, s)
+	}
+	_, err := ParseSize("ten")
+	require.EqualError(t, err, "invalid size 'ten': expected a size such as '10MB'")
+
+	require.Equal(t, "512 B", Size(512).String())
+	require.Equal(t, "1.5 MB", Size(1572864).String())
+
+	var opts Options
+	require.NoError(t, yaml.Unmarshal([]byte("maxBodySize: 2KB"), &opts))
+	require.Equal(t, Size(2048), *opts.MaxBodySize)
+	require.NoError(t, yaml.Unmarshal([]byte("maxBodySize: 100"), &opts))
+	require.Equal(t, Size(100), *opts.MaxBodySize)
+}
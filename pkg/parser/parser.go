@@ -1,12 +1,10 @@
 package parser
 
 import (
-	"bufio"
 	"bytes"
 	"fmt"
 	"os"
 	"regexp"
-	"strings"
 
 	"github.com/ghodss/yaml"
 )
@@ -16,12 +14,16 @@ var idRegex = regexp.MustCompile(`^@[a-zA-Z][a-z-A-Z0-9-_]+$`)
 type File struct {
 	Global   Global
 	Requests []Request
+	Flows    []Flow
 }
 
 type Global struct {
-	BaseURL string            `json:"baseURL"` //nolint:tagliatelle
 	Version int               `json:"version"`
 	Headers map[string]string `json:"headers"`
+	Options
+	// Environments are named sets of options, such as 'staging', which
+	// override the global options when selected.
+	Environments map[string]Options `json:"environments"`
 }
 
 type Request struct {
@@ -31,185 +33,116 @@ type Request struct {
 	Path         string
 	BodyEncoding string
 	Body         []string
+	Options      Options
+	// Assertions are checked against the response by 'hit test', nil if
+	// the request has no '~assert' block.
+	Assertions *Assertions
+	// Captures are the variables set from a successful response by name,
+	// nil if the request has no '~capture' block.
+	Captures map[string]Capture
 }
 
+// Parse parses the hit file filename.
 func Parse(filename string) (File, error) {
-	f, err := os.Open(filename)
+	src, err := os.ReadFile(filename)
 	if err != nil {
 		return File{}, err
 	}
-	defer f.Close()
+	return ParseAST(filename, src).File()
+}
 
+// File converts the syntax tree into a File. It returns the first syntax
+// error of the tree, if any.
+func (a *AST) File() (File, error) {
+	if len(a.Errors) > 0 {
+		return File{}, a.Errors[0]
+	}
 	var res File
-	r := bufio.NewReader(f)
-	sc := &scanner{bufio.NewScanner(r)}
-	for {
-		scanned, line := sc.Line()
-		if !scanned {
-			break
-		}
-
-		switch {
-		case line == "":
-			continue
-		case line == "@_global":
-			err := global(sc, &res.Global)
+	for _, node := range a.Nodes {
+		switch n := node.(type) {
+		case *GlobalSection:
+			err := global(n, &res.Global)
 			if err != nil {
 				return File{}, err
 			}
-		case strings.HasPrefix(line, "@"):
-			if !idRegex.MatchString(line) {
-				return File{}, fmt.Errorf("invalid id: '%v'", line)
-			}
-			id := line[1:]
-			req, err := request(id, sc)
+		case *RequestSection:
+			req, err := request(n)
 			if err != nil {
 				return File{}, err
 			}
 			res.Requests = append(res.Requests, req)
-		default:
-			return File{}, err
+		case *FlowSection:
+			f, err := flow(n)
+			if err != nil {
+				return File{}, err
+			}
+			res.Flows = append(res.Flows, f)
 		}
 	}
 	return res, nil
 }
 
-func request(id string, sc *scanner) (Request, error) {
-	var res Request
-	res.ID = id
-	var lines []string
-	for {
-		scanned, line := sc.Line()
-		if !scanned {
-			break
-		}
-		if line == "" {
-			break
-		}
-		lines = append(lines, line)
-	}
-	l := len(lines)
-	if l == 0 {
-		return Request{}, fmt.Errorf("no request data")
+func request(n *RequestSection) (Request, error) {
+	res := Request{
+		ID:     n.ID.Text[1:],
+		Method: n.RequestLine.Method.Text,
+		Path:   n.RequestLine.Path.Text,
 	}
-	i := 0
-	var err error
-	res.Method, res.Path, err = getMethodAndPath(lines[i])
-	if err != nil {
-		return Request{}, err
+	if len(n.Headers) > 0 {
+		res.Headers = map[string][]string{}
+		for _, h := range n.Headers {
+			res.Headers[h.Name.Text] = []string{h.Value.Text}
+		}
 	}
-	i++
-	if i == l {
-		return res, nil
+	if body := n.Body(); body != nil {
+		res.BodyEncoding = body.Name.Text
+		res.Body = body.Text()
 	}
-	// headers?
-	var headerLines []string
-	for i < l {
-		line := lines[i]
-		if strings.HasPrefix(line, "~") {
-			break
+	if block := n.Block(optionsBlock); block != nil {
+		if err := unmarshalBlock(block, &res.Options); err != nil {
+			return Request{}, &Error{
+				Span:    block.Span,
+				Message: fmt.Sprintf("parse ~%s block: %v", optionsBlock, err),
+			}
 		}
-		headerLines = append(headerLines, line)
-		i++
 	}
-	if len(headerLines) > 0 {
-		headers, err := parseHeaders(headerLines)
-		if err != nil {
-			return Request{}, err
+	if block := n.Block(assertBlock); block != nil {
+		res.Assertions = &Assertions{}
+		if err := unmarshalBlock(block, res.Assertions); err != nil {
+			return Request{}, &Error{
+				Span:    block.Span,
+				Message: fmt.Sprintf("parse ~%s block: %v", assertBlock, err),
+			}
 		}
-		res.Headers = headers
-	}
-	if i == l {
-		return res, nil
-	}
-
-	// has body
-	if i == l-1 {
-		return Request{}, fmt.Errorf("invalid input: expected body")
 	}
-	encodingLine := lines[i]
-	if encodingLine[0] != '~' {
-		return Request{}, fmt.Errorf("invalid input line: '%s', "+
-			"expected '~'", encodingLine)
-	}
-	if lines[l-1] != "~" {
-		return Request{}, fmt.Errorf("invalid end of body: '%s', "+
-			"expected '~'", lines[l-1])
-	}
-
-	if len(encodingLine) > 1 {
-		res.BodyEncoding = encodingLine[1:]
-	}
-	i++
-	// remaining body
-	res.Body = lines[i : l-1]
-
-	return res, nil
-}
-
-const kvSplitCount = 2
-
-func parseHeaders(lines []string) (map[string][]string, error) {
-	res := map[string][]string{}
-	for _, line := range lines {
-		kv := strings.SplitN(line, ":", kvSplitCount)
-		if len(kv) != kvSplitCount {
-			return nil, fmt.Errorf("invalid header line: '%v'", line)
+	if block := n.Block(captureBlock); block != nil {
+		if err := unmarshalBlock(block, &res.Captures); err != nil {
+			return Request{}, &Error{
+				Span:    block.Span,
+				Message: fmt.Sprintf("parse ~%s block: %v", captureBlock, err),
+			}
 		}
-		res[kv[0]] = []string{kv[1]}
 	}
 	return res, nil
 }
 
-var requestLineRegex = regexp.MustCompile(`^([a-zA-Z]+) (\/.*)$`)
-
-func getMethodAndPath(s string) (string, string, error) {
-	matches := requestLineRegex.FindStringSubmatch(s)
-	if len(matches) != 3 { //nolint:gomnd
-		return "", "", fmt.Errorf("invalid request line")
+func global(n *GlobalSection, g *Global) error {
+	err := unmarshalBlock(n.Block, g)
+	if err != nil {
+		return &Error{
+			Span:    n.Block.Span,
+			Message: fmt.Sprintf("parse @_global section: %v", err),
+		}
 	}
-	return matches[1], matches[2], nil
+	return nil
 }
 
-func global(sc *scanner, g *Global) error {
+// unmarshalBlock unmarshals the YAML content of block into v.
+func unmarshalBlock(block *Block, v interface{}) error {
 	var buf bytes.Buffer
-
-	scanned, line := sc.Line()
-	if !scanned || line != "~" {
-		return fmt.Errorf("expected '~' in the @_global section")
-	}
-
-	for {
-		scanned, line := sc.Line()
-		if !scanned || line == "" {
-			return fmt.Errorf("expected '~' to terminate @_global section")
-		}
-		if line == "~" {
-			break
-		}
+	for _, line := range block.Text() {
 		buf.WriteString(line)
 		buf.WriteByte('\n')
 	}
-
-	err := yaml.Unmarshal(buf.Bytes(), g)
-	if err != nil {
-		return fmt.Errorf("parse @_global section: %w", err)
-	}
-	return nil
-}
-
-type scanner struct {
-	sc *bufio.Scanner
-}
-
-func (s *scanner) Line() (bool, string) {
-	if scanned := s.sc.Scan(); !scanned {
-		return false, ""
-	}
-	line := s.sc.Text()
-	// eat comments
-	if strings.HasPrefix(line, "#") {
-		return s.Line()
-	}
-	return true, line
+	return yaml.Unmarshal(buf.Bytes(), v)
 }
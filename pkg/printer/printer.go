@@ -4,12 +4,18 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
 	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/fatih/color"
 	"github.com/hbagdi/hit/pkg/model"
+	"github.com/hbagdi/hit/pkg/parser"
 	"github.com/nwidger/jsoncolor"
 )
 
@@ -23,6 +29,8 @@ type Mode int
 const (
 	ModeColorConsole = iota
 	ModeBrowser
+	// ModePlain prints without colors, such as to files.
+	ModePlain
 )
 
 type Opts struct {
@@ -43,10 +51,23 @@ func (p Printer) Print(hit model.Hit) error {
 	if err != nil {
 		return err
 	}
-	err = p.printResponse(hit.Response)
-	if err != nil {
-		return err
+	p.printAttempts(hit.Attempts)
+	p.printRedirects(hit.Redirects)
+	if hit.Kind == model.HitKindWebSocket {
+		p.printResponseHead(hit.Response)
+		fmt.Fprintln(p.writer)
+		for _, message := range hit.Messages {
+			if err := p.StreamMessage(message); err != nil {
+				return err
+			}
+		}
+	} else {
+		err = p.printResponse(hit.Response)
+		if err != nil {
+			return err
+		}
 	}
+	p.printTiming(hit)
 	return nil
 }
 
@@ -64,6 +85,13 @@ func (c tvColor) SprintfFunc() func(format string, a ...interface{}) string {
 	}
 }
 
+// plainColor prints without color.
+type plainColor struct{}
+
+func (plainColor) SprintfFunc() func(format string, a ...interface{}) string {
+	return fmt.Sprintf
+}
+
 type colorName int
 
 const (
@@ -106,11 +134,49 @@ func (p Printer) colorPrinterFor(name colorName) colorPrinter {
 		return consoleColors[name]
 	case ModeBrowser:
 		return browserColors[name]
+	case ModePlain:
+		return plainColor{}
 	default:
 		panic(fmt.Sprintf("invalid mode: %v", p.mode))
 	}
 }
 
+// masked replaces credentials in printed requests.
+const masked = "*****"
+
+// maskCredentials returns header with the credentials of the Authorization
+// headers and secrets masked.
+func maskCredentials(header http.Header, secrets []string) http.Header {
+	res := make(http.Header, len(header))
+	for k, values := range header {
+		for _, v := range values {
+			switch http.CanonicalHeaderKey(k) {
+			case "Authorization", "Proxy-Authorization":
+				// the scheme is kept
+				if scheme, _, ok := strings.Cut(v, " "); ok {
+					v = scheme + " " + masked
+				} else {
+					v = masked
+				}
+			default:
+				v = maskSecrets(v, secrets)
+			}
+			res[k] = append(res[k], v)
+		}
+	}
+	return res
+}
+
+// maskSecrets returns s with every secret replaced.
+func maskSecrets(s string, secrets []string) string {
+	for _, secret := range secrets {
+		if secret != "" {
+			s = strings.ReplaceAll(s, secret, masked)
+		}
+	}
+	return s
+}
+
 func (p Printer) printRequest(r model.Request) error {
 	path := r.Path
 	if r.QueryString != "" {
@@ -118,7 +184,7 @@ func (p Printer) printRequest(r model.Request) error {
 		if err != nil {
 			return fmt.Errorf("unescape query params: %v", err)
 		}
-		path += "?" + q
+		path += "?" + maskSecrets(q, r.Secrets)
 	}
 
 	requestLine := p.colorPrinterFor(white).SprintfFunc()("%s %s %s\n",
@@ -127,7 +193,7 @@ func (p Printer) printRequest(r model.Request) error {
 	if err != nil {
 		return err
 	}
-	p.printHeaders(r.Header)
+	p.printHeaders(maskCredentials(r.Header, r.Secrets))
 	fmt.Fprintln(p.writer)
 
 	if err := p.printBody(r.Body); err != nil {
@@ -137,13 +203,156 @@ func (p Printer) printRequest(r model.Request) error {
 	return nil
 }
 
+func (p Printer) printRedirects(redirects []model.Redirect) {
+	if len(redirects) == 0 {
+		return
+	}
+	sprintf := p.colorPrinterFor(grey).SprintfFunc()
+	for _, r := range redirects {
+		fmt.Fprint(p.writer, sprintf("%d %s -> %s\n", r.Code, r.URL, r.Location))
+	}
+	fmt.Fprintln(p.writer)
+}
+
+// printAttempts prints the attempts which were retried.
+func (p Printer) printAttempts(attempts []model.Attempt) {
+	if len(attempts) <= 1 {
+		return
+	}
+	sprintf := p.colorPrinterFor(grey).SprintfFunc()
+	for i, a := range attempts[:len(attempts)-1] {
+		outcome := a.Error
+		if outcome == "" {
+			outcome = strconv.Itoa(a.Code)
+		}
+		fmt.Fprint(p.writer, sprintf("attempt %d: %s in %s, retried after %s\n",
+			i+1, outcome, milliseconds(a.Duration), milliseconds(a.Delay)))
+	}
+	fmt.Fprintln(p.writer)
+}
+
 func (p Printer) printResponse(resp model.Response) error {
+	p.printResponseHead(resp)
+	if resp.BodyFile != "" {
+		fmt.Fprintln(p.writer)
+		fmt.Fprint(p.writer, p.colorPrinterFor(grey).SprintfFunc()(
+			"body saved to %s (%v)\n", resp.BodyFile, parser.Size(resp.BodySize)))
+		return nil
+	}
+	if !utf8.Valid(resp.Body) {
+		fmt.Fprintln(p.writer)
+		fmt.Fprint(p.writer, p.colorPrinterFor(grey).SprintfFunc()(
+			"<binary body of %v, use --output to save it>\n",
+			parser.Size(len(resp.Body))))
+		return nil
+	}
+	return p.printBody(resp.Body)
+}
+
+func (p Printer) printResponseHead(resp model.Response) {
 	res := p.colorPrinterFor(white).SprintfFunc()("%s %s\n", resp.Proto, resp.Status)
 	fmt.Fprintf(p.writer, "%s", res)
 
 	p.printHeaders(resp.Header)
+}
 
-	return p.printBody(resp.Body)
+// StreamStart prints the request and the head of the response of a
+// streamed hit.
+func (p Printer) StreamStart(hit model.Hit) error {
+	if err := p.printRequest(hit.Request); err != nil {
+		return err
+	}
+	p.printAttempts(hit.Attempts)
+	p.printRedirects(hit.Redirects)
+	p.printResponseHead(hit.Response)
+	fmt.Fprintln(p.writer)
+	return nil
+}
+
+// StreamChunk prints a chunk of a streamed response.
+func (p Printer) StreamChunk(chunk []byte) error {
+	_, err := fmt.Fprint(p.writer, p.colorPrinterFor(white).SprintfFunc()("%s", chunk))
+	return err
+}
+
+// StreamEvent prints an event of an event stream.
+func (p Printer) StreamEvent(event model.Event) error {
+	var meta string
+	if event.Type != "" {
+		meta += "event: " + event.Type
+	}
+	if event.ID != "" {
+		if meta != "" {
+			meta += ", "
+		}
+		meta += "id: " + event.ID
+	}
+	if meta != "" {
+		fmt.Fprint(p.writer, p.colorPrinterFor(grey).SprintfFunc()("%s\n", meta))
+	}
+	if err := p.printBody([]byte(event.Data)); err != nil {
+		return err
+	}
+	if !isJSON([]byte(event.Data)) {
+		fmt.Fprintln(p.writer)
+	}
+	_, err := fmt.Fprintln(p.writer)
+	return err
+}
+
+// StreamMessage prints a message of a WebSocket session with the time it
+// was sent or received.
+func (p Printer) StreamMessage(message model.Message) error {
+	direction := "<"
+	if message.Sent {
+		direction = ">"
+	}
+	fmt.Fprint(p.writer, p.colorPrinterFor(grey).SprintfFunc()("%s %s ",
+		message.Time.Format("15:04:05.000"), direction))
+	if message.Binary {
+		_, err := fmt.Fprint(p.writer, p.colorPrinterFor(grey).SprintfFunc()(
+			"<binary message of %v>\n", parser.Size(len(message.Data))))
+		return err
+	}
+	_, err := fmt.Fprint(p.writer, p.colorPrinterFor(white).SprintfFunc()(
+		"%s\n", message.Data))
+	return err
+}
+
+// StreamEnd prints the summary of a streamed hit once the stream ended.
+func (p Printer) StreamEnd(hit model.Hit) {
+	p.printTiming(hit)
+}
+
+func milliseconds(d time.Duration) string {
+	return fmt.Sprintf("%.2fms", float64(d)/float64(time.Millisecond))
+}
+
+func (p Printer) printTiming(hit model.Hit) {
+	l := hit.Latency
+	if l.Total == 0 {
+		return
+	}
+	timing := fmt.Sprintf("dns: %s, connect: %s, tls: %s, ttfb: %s, total: %s",
+		milliseconds(l.DNSResolution), milliseconds(l.TCPConnection),
+		milliseconds(l.TLSConnection), milliseconds(l.TTFB),
+		milliseconds(l.Total))
+	if hit.Kind == model.HitKindGRPC {
+		// the phases of gRPC calls are not traced
+		timing = "total: " + milliseconds(l.Total)
+	}
+	if hit.Network.IPInUse != "" {
+		timing += " (" + net.JoinHostPort(hit.Network.IPInUse,
+			strconv.Itoa(hit.Network.PortInUse)) + ")"
+	}
+	if hit.Proxy != "" {
+		timing += "\nproxy: " + hit.Proxy
+	}
+	if hit.TLS.Version != "" {
+		timing += fmt.Sprintf("\n%s, %s", hit.TLS.Version, hit.TLS.CipherSuite)
+	}
+	fmt.Fprintln(p.writer)
+	fmt.Fprint(p.writer, p.colorPrinterFor(grey).SprintfFunc()("%s\n", timing))
 }
 
 func isJSON(b []byte) bool {
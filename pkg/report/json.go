@@ -0,0 +1,64 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+type jsonReport struct {
+	Passed     int        `json:"passed"`
+	Failed     int        `json:"failed"`
+	DurationMS int64      `json:"durationMs"`
+	Cases      []jsonCase `json:"cases"`
+}
+
+type jsonCase struct {
+	ID         string          `json:"id"`
+	Passed     bool            `json:"passed"`
+	DurationMS int64           `json:"durationMs"`
+	Error      string          `json:"error,omitempty"`
+	Assertions []jsonAssertion `json:"assertions"`
+	Excerpt    string          `json:"excerpt,omitempty"`
+}
+
+type jsonAssertion struct {
+	Assertion string `json:"assertion"`
+	Passed    bool   `json:"passed"`
+	Message   string `json:"message,omitempty"`
+}
+
+// writeJSON writes cases as a JSON summary.
+func writeJSON(w io.Writer, cases []Case) error {
+	passed, failed, duration := summary(cases)
+	report := jsonReport{
+		Passed:     passed,
+		Failed:     failed,
+		DurationMS: duration.Milliseconds(),
+		Cases:      []jsonCase{},
+	}
+	for _, c := range cases {
+		jc := jsonCase{
+			ID:         c.ID,
+			Passed:     c.Passed(),
+			DurationMS: c.Duration.Milliseconds(),
+			Error:      c.Error,
+			Assertions: []jsonAssertion{},
+			Excerpt:    c.Excerpt,
+		}
+		for _, a := range c.Assertions {
+			jc.Assertions = append(jc.Assertions, jsonAssertion{
+				Assertion: a.Assertion,
+				Passed:    a.Passed,
+				Message:   a.Message,
+			})
+		}
+		report.Cases = append(report.Cases, jc)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return fmt.Errorf("write JSON report: %v", err)
+	}
+	return nil
+}
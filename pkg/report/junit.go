@@ -0,0 +1,111 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/hbagdi/hit/pkg/assertion"
+)
+
+// suiteName is the name of the test suite of JUnit reports.
+const suiteName = "hit"
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Name     string       `xml:"name,attr"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Errors   int          `xml:"errors,attr"`
+	Time     string       `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Errors   int         `xml:"errors,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Error     *junitFailure `xml:"error,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// junitTime formats d in seconds.
+func junitTime(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+// writeJUnit writes cases as a JUnit XML report. Requests which failed to
+// execute are errors, requests with failed assertions failures.
+func writeJUnit(w io.Writer, cases []Case) error {
+	_, _, duration := summary(cases)
+	suite := junitSuite{
+		Name:  suiteName,
+		Tests: len(cases),
+		Time:  junitTime(duration),
+	}
+	for _, c := range cases {
+		jc := junitCase{
+			Name:      c.ID,
+			ClassName: suiteName,
+			Time:      junitTime(c.Duration),
+		}
+		details := strings.Join(c.Failures(), "\n")
+		if c.Excerpt != "" {
+			details += "\n\n" + c.Excerpt
+		}
+		switch {
+		case c.Error != "":
+			suite.Errors++
+			jc.Error = &junitFailure{
+				Message: c.Error,
+				Type:    "error",
+				Text:    c.Error,
+			}
+		case !c.Passed():
+			suite.Failures++
+			jc.Failure = &junitFailure{
+				Message: fmt.Sprintf("%d of %d assertion(s) failed",
+					assertion.Failed(c.Assertions), len(c.Assertions)),
+				Type: "assertion",
+				Text: details,
+			}
+		}
+		suite.Cases = append(suite.Cases, jc)
+	}
+	suites := junitSuites{
+		Name:     suiteName,
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Errors:   suite.Errors,
+		Time:     suite.Time,
+		Suites:   []junitSuite{suite},
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suites); err != nil {
+		return fmt.Errorf("write JUnit report: %v", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,115 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/hbagdi/hit/pkg/assertion"
+	"github.com/hbagdi/hit/pkg/model"
+	"github.com/hbagdi/hit/pkg/printer"
+)
+
+// Formats of reports.
+const (
+	FormatJUnit = "junit"
+	FormatTAP   = "tap"
+	FormatJSON  = "json"
+)
+
+// maxExcerptSize is the maximum size of the excerpt of a failed case.
+const maxExcerptSize = 4 << 10
+
+// Case is the outcome of testing a request.
+type Case struct {
+	// ID is the ID of the request including the leading '@'.
+	ID       string
+	Duration time.Duration
+	// Error is the error executing the request, if any.
+	Error      string
+	Assertions []assertion.Result
+	// Excerpt is the printed request and response of a failed case.
+	Excerpt string
+}
+
+// NewCase returns the case of request id executed in duration. The hit of
+// the request is nil if it failed with err.
+func NewCase(id string, duration time.Duration, hit *model.Hit, err error,
+	results []assertion.Result,
+) Case {
+	res := Case{ID: id, Duration: duration, Assertions: results}
+	if err != nil {
+		res.Error = err.Error()
+	}
+	if hit != nil && !res.Passed() {
+		res.Excerpt = excerpt(*hit)
+	}
+	return res
+}
+
+// Passed returns true if the request was executed and every assertion
+// passed.
+func (c Case) Passed() bool {
+	return c.Error == "" && assertion.Failed(c.Assertions) == 0
+}
+
+// Failures returns the messages of the failed assertions of c.
+func (c Case) Failures() []string {
+	var res []string
+	for _, r := range c.Assertions {
+		if !r.Passed {
+			res = append(res, r.Assertion+": "+r.Message)
+		}
+	}
+	return res
+}
+
+// Write writes the report of cases in format to w.
+func Write(w io.Writer, format string, cases []Case) error {
+	switch format {
+	case FormatJUnit:
+		return writeJUnit(w, cases)
+	case FormatTAP:
+		return writeTAP(w, cases)
+	case FormatJSON:
+		return writeJSON(w, cases)
+	default:
+		return fmt.Errorf("invalid report format '%v': only '%v', '%v' or "+
+			"'%v' is supported", format, FormatJUnit, FormatTAP, FormatJSON)
+	}
+}
+
+// ValidFormat returns an error if format is not a format of reports.
+func ValidFormat(format string) error {
+	return Write(io.Discard, format, nil)
+}
+
+// excerpt returns hit as it is printed, without colors and truncated.
+// Credentials are masked.
+func excerpt(hit model.Hit) string {
+	var buf bytes.Buffer
+	p := printer.NewPrinter(printer.Opts{Mode: printer.ModePlain, Writer: &buf})
+	if err := p.Print(hit); err != nil {
+		return ""
+	}
+	res := bytes.TrimSpace(buf.Bytes())
+	if len(res) > maxExcerptSize {
+		res = append(res[:maxExcerptSize:maxExcerptSize], "\n... (truncated)"...)
+	}
+	// truncation may have split a character
+	return strings.ToValidUTF8(string(res), "")
+}
+
+func summary(cases []Case) (passed, failed int, duration time.Duration) {
+	for _, c := range cases {
+		if c.Passed() {
+			passed++
+		} else {
+			failed++
+		}
+		duration += c.Duration
+	}
+	return passed, failed, duration
+}
@@ -0,0 +1,145 @@
+package report
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/hbagdi/hit/pkg/assertion"
+	"github.com/hbagdi/hit/pkg/model"
+	"github.com/stretchr/testify/require"
+)
+
+func testCases() []Case {
+	hit := &model.Hit{
+		Request: model.Request{
+			Method: "GET",
+			Path:   "/nodes/1",
+			Proto:  "HTTP/1.1",
+			Header: http.Header{"Authorization": {"Bearer secret"}},
+		},
+		Response: model.Response{
+			Proto:  "HTTP/1.1",
+			Status: "404 Not Found",
+			Body:   []byte("not found"),
+		},
+	}
+	return []Case{
+		NewCase("@login", 12*time.Millisecond, &model.Hit{}, nil, []assertion.Result{
+			{Assertion: "status == 200", Passed: true},
+		}),
+		NewCase("@get-node", 8*time.Millisecond, hit, nil, []assertion.Result{
+			{Assertion: "status == 200", Message: "got 404"},
+			{Assertion: "body id == 1", Message: "response body is not JSON"},
+		}),
+		NewCase("@delete-node", 1500*time.Millisecond, nil,
+			errors.New("execute request: connection refused"), nil),
+	}
+}
+
+const excerptText = `GET /nodes/1 HTTP/1.1
+Authorization: Bearer *****
+
+
+HTTP/1.1 404 Not Found
+not found`
+
+func TestNewCase(t *testing.T) {
+	cases := testCases()
+	require.True(t, cases[0].Passed())
+	require.Empty(t, cases[0].Excerpt)
+	require.False(t, cases[1].Passed())
+	require.Equal(t, excerptText, cases[1].Excerpt)
+	require.Equal(t, []string{
+		"status == 200: got 404",
+		"body id == 1: response body is not JSON",
+	}, cases[1].Failures())
+	require.False(t, cases[2].Passed())
+}
+
+func TestWriteJUnit(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, FormatJUnit, testCases()))
+	require.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites name="hit" tests="3" failures="1" errors="1" time="1.520">
+  <testsuite name="hit" tests="3" failures="1" errors="1" time="1.520">
+    <testcase name="@login" classname="hit" time="0.012"></testcase>
+    <testcase name="@get-node" classname="hit" time="0.008">
+      <failure message="2 of 2 assertion(s) failed" type="assertion">status == 200: got 404&#xA;body id == 1: response body is not JSON&#xA;&#xA;GET /nodes/1 HTTP/1.1&#xA;Authorization: Bearer *****&#xA;&#xA;&#xA;HTTP/1.1 404 Not Found&#xA;not found</failure>
+    </testcase>
+    <testcase name="@delete-node" classname="hit" time="1.500">
+      <error message="execute request: connection refused" type="error">execute request: connection refused</error>
+    </testcase>
+  </testsuite>
+</testsuites>
+`, buf.String())
+}
+
+func TestWriteTAP(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, FormatTAP, testCases()))
+	require.Equal(t, `TAP version 13
+1..3
+ok 1 - @login
+not ok 2 - @get-node
+  ---
+  duration_ms: 8
+  failures:
+    - "status == 200: got 404"
+    - "body id == 1: response body is not JSON"
+  excerpt: |
+    GET /nodes/1 HTTP/1.1
+    Authorization: Bearer *****
+
+
+    HTTP/1.1 404 Not Found
+    not found
+  ...
+not ok 3 - @delete-node
+  ---
+  duration_ms: 1500
+  error: "execute request: connection refused"
+  ...
+# passed 1
+# failed 2
+`, buf.String())
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, FormatJSON, testCases()[1:]))
+	require.JSONEq(t, `{
+  "passed": 0,
+  "failed": 2,
+  "durationMs": 1508,
+  "cases": [
+    {
+      "id": "@get-node",
+      "passed": false,
+      "durationMs": 8,
+      "assertions": [
+        {"assertion": "status == 200", "passed": false, "message": "got 404"},
+        {"assertion": "body id == 1", "passed": false,
+         "message": "response body is not JSON"}
+      ],
+      "excerpt": `+strconv.Quote(excerptText)+`
+    },
+    {
+      "id": "@delete-node",
+      "passed": false,
+      "durationMs": 1500,
+      "error": "execute request: connection refused",
+      "assertions": []
+    }
+  ]
+}`, buf.String())
+}
+
+func TestWriteInvalidFormat(t *testing.T) {
+	require.EqualError(t, ValidFormat("xml"), "invalid report format 'xml': "+
+		"only 'junit', 'tap' or 'json' is supported")
+	require.NoError(t, ValidFormat(FormatTAP))
+}
@@ -0,0 +1,50 @@
+package report
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// writeTAP writes cases as a TAP version 13 report. The details of failed
+// cases are written as YAML diagnostics.
+func writeTAP(w io.Writer, cases []Case) error {
+	b := bufio.NewWriter(w)
+	fmt.Fprintln(b, "TAP version 13")
+	fmt.Fprintf(b, "1..%d\n", len(cases))
+	for i, c := range cases {
+		if c.Passed() {
+			fmt.Fprintf(b, "ok %d - %v\n", i+1, c.ID)
+			continue
+		}
+		fmt.Fprintf(b, "not ok %d - %v\n", i+1, c.ID)
+		fmt.Fprintln(b, "  ---")
+		fmt.Fprintf(b, "  duration_ms: %d\n", c.Duration.Milliseconds())
+		if c.Error != "" {
+			fmt.Fprintf(b, "  error: %v\n", strconv.Quote(c.Error))
+		}
+		if failures := c.Failures(); len(failures) > 0 {
+			fmt.Fprintln(b, "  failures:")
+			for _, f := range failures {
+				fmt.Fprintf(b, "    - %v\n", strconv.Quote(f))
+			}
+		}
+		if c.Excerpt != "" {
+			fmt.Fprintln(b, "  excerpt: |")
+			for _, line := range strings.Split(c.Excerpt, "\n") {
+				if line == "" {
+					// block scalars may contain empty lines
+					fmt.Fprintln(b)
+					continue
+				}
+				fmt.Fprintf(b, "    %v\n", line)
+			}
+		}
+		fmt.Fprintln(b, "  ...")
+	}
+	passed, failed, _ := summary(cases)
+	fmt.Fprintf(b, "# passed %d\n# failed %d\n", passed, failed)
+	return b.Flush()
+}
@@ -0,0 +1,155 @@
+package request
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/hbagdi/hit/pkg/model"
+	"github.com/hbagdi/hit/pkg/parser"
+)
+
+// Authentication types of parser.AuthOptions.
+const (
+	authBasic  = "basic"
+	authBearer = "bearer"
+	authDigest = "digest"
+	authAPIKey = "apiKey"
+	authOAuth2 = "oauth2"
+	authNone   = "none"
+)
+
+// authentication is the outcome of applying parser.AuthOptions to a request.
+type authentication struct {
+	// header is the header set, if any.
+	header, headerValue string
+	// query is the query parameter set, if any.
+	query, queryValue string
+	digest            *model.Credentials
+	oauth2            *model.OAuth2
+	secrets           []string
+}
+
+// authenticate resolves the credentials of auth and returns how the request
+// is authenticated.
+func authenticate(auth parser.AuthOptions, resolver Resolver) (authentication, error) {
+	var (
+		res authentication
+		err error
+	)
+	value := func(name, v string) string {
+		if err != nil {
+			return ""
+		}
+		if v == "" {
+			err = fmt.Errorf("%v auth requires '%v'", auth.Type, name)
+			return ""
+		}
+		if v[0] == '@' {
+			v, err = resolveValue(v, resolver)
+		}
+		return v
+	}
+	switch auth.Type {
+	case authBasic:
+		username, password := value("username", auth.Username), auth.Password
+		if len(password) > 0 && password[0] == '@' {
+			password = value("password", password)
+		}
+		credentials := base64.StdEncoding.EncodeToString(
+			[]byte(username + ":" + password))
+		res.header, res.headerValue = "Authorization", "Basic "+credentials
+		res.secrets = []string{credentials}
+		if password != "" {
+			res.secrets = append(res.secrets, password)
+		}
+	case authBearer:
+		token := value("token", auth.Token)
+		res.header, res.headerValue = "Authorization", "Bearer "+token
+		res.secrets = []string{token}
+	case authDigest:
+		res.digest = &model.Credentials{
+			Username: value("username", auth.Username),
+			Password: value("password", auth.Password),
+		}
+		res.secrets = []string{res.digest.Password}
+	case authAPIKey:
+		key := value("key", auth.Key)
+		switch {
+		case auth.Header != "" && auth.Query != "":
+			return authentication{}, fmt.Errorf("apiKey auth requires " +
+				"either 'header' or 'query', not both")
+		case auth.Header != "":
+			res.header, res.headerValue = auth.Header, key
+		case auth.Query != "":
+			res.query, res.queryValue = auth.Query, key
+		default:
+			return authentication{}, fmt.Errorf("apiKey auth requires " +
+				"'header' or 'query'")
+		}
+		res.secrets = []string{key}
+	case authOAuth2:
+		if auth.OAuth2 == nil {
+			return authentication{}, fmt.Errorf("oauth2 auth requires 'oauth2'")
+		}
+		o := auth.OAuth2
+		res.oauth2 = &model.OAuth2{
+			TokenURL: value("oauth2.tokenURL", o.TokenURL),
+			Grant:    o.Grant,
+			ClientID: value("oauth2.clientID", o.ClientID),
+			Scopes:   o.Scopes,
+		}
+		if o.ClientSecret != "" {
+			res.oauth2.ClientSecret = value("oauth2.clientSecret", o.ClientSecret)
+		}
+		switch o.Grant {
+		case "", model.GrantClientCredentials:
+			res.oauth2.Grant = model.GrantClientCredentials
+		case model.GrantPassword:
+			res.oauth2.Username = value("username", auth.Username)
+			res.oauth2.Password = value("password", auth.Password)
+		case model.GrantRefreshToken:
+			res.oauth2.RefreshToken = value("oauth2.refreshToken", o.RefreshToken)
+		default:
+			return authentication{}, fmt.Errorf("invalid OAuth2 grant '%v': "+
+				"only '%v', '%v' or '%v' is supported", o.Grant,
+				model.GrantClientCredentials, model.GrantPassword, model.GrantRefreshToken)
+		}
+		for _, secret := range []string{
+			res.oauth2.ClientSecret, res.oauth2.Password, res.oauth2.RefreshToken,
+		} {
+			if secret != "" {
+				res.secrets = append(res.secrets, secret)
+			}
+		}
+	case "", authNone:
+	default:
+		return authentication{}, fmt.Errorf("invalid auth type '%v': only "+
+			"'%v', '%v', '%v', '%v', '%v' or '%v' is supported", auth.Type,
+			authBasic, authBearer, authDigest, authAPIKey, authOAuth2, authNone)
+	}
+	if err != nil {
+		return authentication{}, fmt.Errorf("resolve auth: %v", err)
+	}
+	return res, nil
+}
+
+// apply authenticates a request with headers and the encoded query. Headers
+// set by the request take precedence. It returns the new query.
+func (a authentication) apply(headers http.Header, query string) (string, error) {
+	if a.header != "" && headers.Get(a.header) == "" {
+		headers.Set(a.header, a.headerValue)
+	}
+	if a.query == "" {
+		return query, nil
+	}
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return "", err
+	}
+	if !values.Has(a.query) {
+		values.Set(a.query, a.queryValue)
+	}
+	return values.Encode(), nil
+}
@@ -8,7 +8,7 @@ import (
 )
 
 type BodyResolver struct {
-	resolver resolver
+	resolver Resolver
 	res      interface{}
 	err      error
 }
@@ -63,7 +63,7 @@ func (r *BodyResolver) deRefJSON(j gjson.Result) (interface{}, error) {
 	switch j.Type {
 	case gjson.String:
 		v := j.String()
-		if v[0] != '@' {
+		if v == "" || v[0] != '@' {
 			return v, nil
 		}
 		return r.resolver.Resolve(v)
@@ -1,6 +1,7 @@
 package request
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -21,17 +22,43 @@ type Options struct {
 	GlobalContext parser.Global
 	Cache         cachePkg.Cache
 	Args          []string
+	// Literals is the number of Args from '@1' onwards which are values,
+	// even if they begin with '@'.
+	Literals int
+	// Resolver overrides the default resolver which resolves references
+	// using Args and Cache.
+	Resolver Resolver
+	// Auth authenticates the request, if set.
+	Auth *parser.AuthOptions
+	// Sign configures how the request is signed, if set. Requests are
+	// signed by the sign package once they are generated.
+	Sign *parser.SignOptions
+	// Variables are the captured variables referenced as '@var.name'.
+	Variables map[string]interface{}
 }
 
 func Generate(request parser.Request, opts Options) (model.Request, error) {
-	resolver := newCacheResolver(opts.Cache, opts.Args)
+	var resolver Resolver = newCacheResolver(opts.Cache, opts.Args, opts.Literals,
+		opts.Variables)
+	if opts.Resolver != nil {
+		resolver = opts.Resolver
+	}
 
 	urlComponents, err := genURL(request, opts.GlobalContext, resolver)
 	if err != nil {
 		return model.Request{}, err
 	}
 
-	body, cType, err := resolveBody(request, resolver)
+	var (
+		body     []byte
+		cType    contentType
+		messages [][]byte
+	)
+	if request.Method == model.MethodWebSocket {
+		messages, err = resolveMessages(request, resolver)
+	} else {
+		body, cType, err = resolveBody(request, resolver)
+	}
 	if err != nil {
 		return model.Request{}, err
 	}
@@ -42,6 +69,29 @@ func Generate(request parser.Request, opts Options) (model.Request, error) {
 		}
 	}
 
+	var auth authentication
+	if opts.Auth != nil {
+		auth, err = authenticate(*opts.Auth, resolver)
+		if err != nil {
+			return model.Request{}, err
+		}
+	}
+	urlComponents.query, err = auth.apply(headers, urlComponents.query)
+	if err != nil {
+		return model.Request{}, err
+	}
+
+	var (
+		sign        *model.Signing
+		signSecrets []string
+	)
+	if opts.Sign != nil {
+		sign, signSecrets, err = signing(*opts.Sign, resolver)
+		if err != nil {
+			return model.Request{}, err
+		}
+	}
+
 	for k, v := range opts.GlobalContext.Headers {
 		if headers.Get(k) == "" {
 			headers.Add(k, v)
@@ -50,7 +100,12 @@ func Generate(request parser.Request, opts Options) (model.Request, error) {
 	if headers.Get("host") == "" {
 		// TODO(hbagdi): attempt to clean host or error out if host is not
 		// valid
-		headers.Set("host", urlComponents.host)
+		host := urlComponents.host
+		if urlComponents.scheme == model.SchemeHTTPUnix {
+			// the host is the path of the socket
+			host = "localhost"
+		}
+		headers.Set("host", host)
 	}
 	if headers.Get("user-agent") == "" {
 		headers.Add("user-agent", "hit/"+version.Version)
@@ -60,7 +115,10 @@ func Generate(request parser.Request, opts Options) (model.Request, error) {
 	case contentTypeNone:
 	// no body encoding, proceed without any implicit content-type header
 	case contentTypeJSON:
-		headers.Set("content-type", "application/json")
+		// the JSON body of gRPC calls is encoded into a protobuf message
+		if request.Method != model.MethodGRPC {
+			headers.Set("content-type", "application/json")
+		}
 	case contentTypeInvalid:
 		return model.Request{}, fmt.Errorf("invalid content-type")
 	}
@@ -73,6 +131,11 @@ func Generate(request parser.Request, opts Options) (model.Request, error) {
 		QueryString: urlComponents.query,
 		Header:      headers,
 		Body:        body,
+		Messages:    messages,
+		Digest:      auth.digest,
+		OAuth2:      auth.oauth2,
+		Signing:     sign,
+		Secrets:     append(auth.secrets, signSecrets...),
 	}, nil
 }
 
@@ -80,8 +143,8 @@ type urlComponents struct {
 	scheme, host, path, query string
 }
 
-func genURL(request parser.Request, global parser.Global, resolver resolver) (urlComponents, error) {
-	res, err := url.Parse(global.BaseURL + request.Path)
+func genURL(request parser.Request, global parser.Global, resolver Resolver) (urlComponents, error) {
+	res, err := model.ParseURL(global.BaseURL + request.Path)
 	if err != nil {
 		return urlComponents{}, err
 	}
@@ -103,7 +166,7 @@ func genURL(request parser.Request, global parser.Global, resolver resolver) (ur
 	}, nil
 }
 
-func resolvePath(path string, resolver resolver) (string, error) {
+func resolvePath(path string, resolver Resolver) (string, error) {
 	if !strings.Contains(path, "@") {
 		return path, nil
 	}
@@ -126,7 +189,7 @@ func resolvePath(path string, resolver resolver) (string, error) {
 	return resolvedPath, nil
 }
 
-func resolveQueryParams(qp url.Values, resolver resolver) (url.Values, error) {
+func resolveQueryParams(qp url.Values, resolver Resolver) (url.Values, error) {
 	res := url.Values{}
 	for k, v := range qp {
 		for _, value := range v {
@@ -144,7 +207,7 @@ func resolveQueryParams(qp url.Values, resolver resolver) (url.Values, error) {
 	return res, nil
 }
 
-func resolveValue(key string, resolver resolver) (string, error) {
+func resolveValue(key string, resolver Resolver) (string, error) {
 	resolvedValue, err := resolver.Resolve(key)
 	if err != nil {
 		return "", err
@@ -179,7 +242,7 @@ const (
 	contentTypeJSON
 )
 
-func resolveBody(request parser.Request, resolver resolver) ([]byte, contentType, error) {
+func resolveBody(request parser.Request, resolver Resolver) ([]byte, contentType, error) {
 	if len(request.Body) == 0 {
 		return nil, contentTypeNone, nil
 	}
@@ -190,7 +253,7 @@ func resolveBody(request parser.Request, resolver resolver) ([]byte, contentType
 	case encodingY2J:
 		jsonBytes, err := yaml.YAMLToJSON(parsedBody)
 		if err != nil {
-			return nil, contentTypeNone, err
+			return nil, contentTypeNone, fmt.Errorf("invalid y2j body: %w", err)
 		}
 		r := &BodyResolver{resolver: resolver}
 		preparedBody, err := r.Resolve(jsonBytes)
@@ -202,3 +265,41 @@ func resolveBody(request parser.Request, resolver resolver) ([]byte, contentType
 		return parsedBody, contentTypeNone, nil
 	}
 }
+
+// resolveMessages returns the messages of a WebSocket request. The elements
+// of a y2j body holding a list are JSON messages, any other y2j body is a
+// single message. Every line of a body without encoding is a text message.
+func resolveMessages(request parser.Request, resolver Resolver) ([][]byte, error) {
+	if len(request.Body) == 0 {
+		return nil, nil
+	}
+	var res [][]byte
+	if request.BodyEncoding == encodingY2J {
+		body, _, err := resolveBody(request, resolver)
+		if err != nil {
+			return nil, err
+		}
+		var list []json.RawMessage
+		if err := json.Unmarshal(body, &list); err != nil {
+			return [][]byte{body}, nil
+		}
+		for _, message := range list {
+			res = append(res, message)
+		}
+		return res, nil
+	}
+	for _, line := range request.Body {
+		if line == "" {
+			continue
+		}
+		if line[0] == '@' {
+			value, err := resolveValue(line, resolver)
+			if err != nil {
+				return nil, err
+			}
+			line = value
+		}
+		res = append(res, []byte(line))
+	}
+	return res, nil
+}
@@ -3,24 +3,39 @@ package request
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/hbagdi/hit/pkg/cache"
 )
 
-type resolver interface {
+// Resolver resolves a reference such as '@1', '@request-id.path' or
+// '@var.name' into a value.
+type Resolver interface {
 	Resolve(string) (interface{}, error)
 }
 
-func newCacheResolver(cache cache.Cache, args []string) cacheResolver {
+// VariablePrefix is the prefix of references to captured variables such as
+// '@var.token'.
+const VariablePrefix = "var."
+
+func newCacheResolver(cache cache.Cache, args []string, literals int,
+	variables map[string]interface{},
+) cacheResolver {
 	return cacheResolver{
-		cache: cache,
-		args:  args,
+		cache:     cache,
+		args:      args,
+		literals:  literals,
+		variables: variables,
 	}
 }
 
 type cacheResolver struct {
-	args  []string
-	cache cache.Cache
+	args []string
+	// literals is the number of arguments from '@1' onwards which are
+	// values, never references.
+	literals  int
+	cache     cache.Cache
+	variables map[string]interface{}
 }
 
 func (r cacheResolver) Resolve(key string) (interface{}, error) {
@@ -39,11 +54,20 @@ func (r cacheResolver) Resolve(key string) (interface{}, error) {
 			return nil, fmt.Errorf("positional argument must be greater than 0")
 		}
 		v := r.args[n]
-		if v[0] != '@' {
+		if len(v) == 0 || v[0] != '@' || n <= r.literals {
 			return typedValue(v), nil
 		}
 		key = v[1:]
 	}
+	if strings.HasPrefix(key, VariablePrefix) {
+		name := strings.TrimPrefix(key, VariablePrefix)
+		v, ok := r.variables[name]
+		if !ok {
+			return nil, fmt.Errorf("variable '%v' is not set: it is set by "+
+				"a ~capture block once its request succeeds", name)
+		}
+		return v, nil
+	}
 	return r.cache.Get(key)
 }
 
@@ -0,0 +1,154 @@
+package request
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/hbagdi/hit/pkg/model"
+	"github.com/hbagdi/hit/pkg/parser"
+)
+
+// Signing types of parser.SignOptions.
+const (
+	signAWSV4 = "awsv4"
+	signHMAC  = "hmac"
+	signNone  = "none"
+)
+
+// Defaults of parser.HMACSignOptions.
+const (
+	defaultHMACAlgorithm       = "sha256"
+	defaultHMACEncoding        = "hex"
+	defaultHMACHeader          = "X-Signature"
+	defaultHMACTimestampHeader = "X-Timestamp"
+	defaultHMACTimestampFormat = "unix"
+	defaultHMACTemplate        = "{method}\n{path}\n{timestamp}\n{body}"
+)
+
+var (
+	hmacAlgorithms       = []string{"sha256", "sha512", "sha1"}
+	hmacEncodings        = []string{"hex", "base64"}
+	hmacTimestampFormats = []string{"unix", "unixMillis", "rfc3339"}
+)
+
+// signing resolves the credentials of sign and returns how the request is
+// signed, if at all, and the secrets used.
+func signing(sign parser.SignOptions, resolver Resolver) (*model.Signing, []string, error) {
+	var err error
+	// value resolves v, falling back to the first environment variable
+	// set in env
+	value := func(name, v string, env ...string) string {
+		if err != nil {
+			return ""
+		}
+		for _, key := range env {
+			if v != "" {
+				break
+			}
+			v = os.Getenv(key)
+		}
+		if v == "" {
+			err = fmt.Errorf("%v signing requires '%v'", sign.Type, name)
+			return ""
+		}
+		if v[0] == '@' {
+			v, err = resolveValue(v, resolver)
+		}
+		return v
+	}
+	var (
+		res     model.Signing
+		secrets []string
+	)
+	switch sign.Type {
+	case signAWSV4:
+		if sign.AWS == nil {
+			return nil, nil, fmt.Errorf("awsv4 signing requires 'aws'")
+		}
+		o := sign.AWS
+		res.AWS = &model.AWSSigning{
+			Region:          value("aws.region", o.Region, "AWS_REGION", "AWS_DEFAULT_REGION"),
+			Service:         value("aws.service", o.Service),
+			AccessKeyID:     value("aws.accessKeyID", o.AccessKeyID, "AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: value("aws.secretAccessKey", o.SecretAccessKey, "AWS_SECRET_ACCESS_KEY"),
+		}
+		if o.SessionToken != "" || os.Getenv("AWS_SESSION_TOKEN") != "" {
+			res.AWS.SessionToken = value("aws.sessionToken", o.SessionToken,
+				"AWS_SESSION_TOKEN")
+		}
+		secrets = []string{res.AWS.SecretAccessKey}
+		if res.AWS.SessionToken != "" {
+			secrets = append(secrets, res.AWS.SessionToken)
+		}
+	case signHMAC:
+		if sign.HMAC == nil {
+			return nil, nil, fmt.Errorf("hmac signing requires 'hmac'")
+		}
+		o := sign.HMAC
+		res.HMAC = &model.HMACSigning{
+			Key:             value("hmac.key", o.Key),
+			Algorithm:       orDefault(o.Algorithm, defaultHMACAlgorithm),
+			Encoding:        orDefault(o.Encoding, defaultHMACEncoding),
+			Header:          orDefault(o.Header, defaultHMACHeader),
+			Prefix:          o.Prefix,
+			TimestampHeader: orDefault(o.TimestampHeader, defaultHMACTimestampHeader),
+			TimestampFormat: orDefault(o.TimestampFormat, defaultHMACTimestampFormat),
+			Template:        orDefault(o.Template, defaultHMACTemplate),
+		}
+		for _, check := range []struct {
+			name, value string
+			valid       []string
+		}{
+			{"algorithm", res.HMAC.Algorithm, hmacAlgorithms},
+			{"encoding", res.HMAC.Encoding, hmacEncodings},
+			{"timestampFormat", res.HMAC.TimestampFormat, hmacTimestampFormats},
+		} {
+			if !contains(check.valid, check.value) {
+				return nil, nil, fmt.Errorf("invalid hmac %v '%v': only %v "+
+					"is supported", check.name, check.value, quoted(check.valid))
+			}
+		}
+		secrets = []string{res.HMAC.Key}
+	case "", signNone:
+		return nil, nil, nil
+	default:
+		return nil, nil, fmt.Errorf("invalid signing type '%v': only '%v', "+
+			"'%v' or '%v' is supported", sign.Type, signAWSV4, signHMAC, signNone)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("resolve signing: %v", err)
+	}
+	return &res, secrets, nil
+}
+
+func orDefault(v, def string) string {
+	if v == "" {
+		return def
+	}
+	return v
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+// quoted lists values as "'a', 'b' or 'c'".
+func quoted(values []string) string {
+	res := ""
+	for i, v := range values {
+		switch {
+		case i == 0:
+		case i == len(values)-1:
+			res += " or "
+		default:
+			res += ", "
+		}
+		res += "'" + v + "'"
+	}
+	return res
+}
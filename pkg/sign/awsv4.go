@@ -0,0 +1,157 @@
+package sign
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hbagdi/hit/pkg/model"
+)
+
+const (
+	awsV4Algorithm  = "AWS4-HMAC-SHA256"
+	awsV4TimeFormat = "20060102T150405Z"
+	awsV4DateFormat = "20060102"
+	// awsS3Service is the service whose paths are not escaped twice and
+	// which requires the hash of the payload in a header.
+	awsS3Service = "s3"
+)
+
+// awsV4Unsigned are headers which are not signed as they may be changed on
+// the way to the service.
+var awsV4Unsigned = map[string]bool{
+	"authorization":   true,
+	"user-agent":      true,
+	"x-amzn-trace-id": true,
+	"expect":          true,
+}
+
+// signAWSV4 signs req with AWS Signature Version 4 at time now. The
+// signature is sent in the Authorization header.
+func signAWSV4(req *model.Request, config model.AWSSigning, now time.Time) {
+	now = now.UTC()
+	payloadHash := sha256Hex(req.Body)
+	req.Header = req.Header.Clone()
+	req.Header.Set("X-Amz-Date", now.Format(awsV4TimeFormat))
+	if config.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", config.SessionToken)
+	}
+	if config.Service == awsS3Service {
+		req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	}
+
+	headers, signedHeaders := awsV4CanonicalHeaders(req.Header)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		awsV4CanonicalURI(req, config.Service),
+		awsV4CanonicalQuery(req.QueryString),
+		headers,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := strings.Join([]string{
+		now.Format(awsV4DateFormat), config.Region, config.Service, "aws4_request",
+	}, "/")
+	stringToSign := strings.Join([]string{
+		awsV4Algorithm,
+		now.Format(awsV4TimeFormat),
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := []byte("AWS4" + config.SecretAccessKey)
+	for _, part := range []string{
+		now.Format(awsV4DateFormat), config.Region, config.Service, "aws4_request",
+	} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"%v Credential=%v/%v, SignedHeaders=%v, Signature=%v", awsV4Algorithm,
+		config.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// awsV4CanonicalURI returns the escaped path of req. Paths are escaped once
+// more except for S3.
+func awsV4CanonicalURI(req *model.Request, service string) string {
+	path := escapedPath(req)
+	if service == awsS3Service {
+		return path
+	}
+	return awsV4Escape(path, false)
+}
+
+// awsV4CanonicalQuery returns the query parameters of query sorted by key
+// and value.
+func awsV4CanonicalQuery(query string) string {
+	values, err := url.ParseQuery(query)
+	if err != nil || len(values) == 0 {
+		return ""
+	}
+	var params []string
+	for key, vs := range values {
+		for _, v := range vs {
+			params = append(params, awsV4Escape(key, true)+"="+awsV4Escape(v, true))
+		}
+	}
+	sort.Strings(params)
+	return strings.Join(params, "&")
+}
+
+// awsV4CanonicalHeaders returns the canonical form of the signed headers
+// and their names separated by ';'.
+func awsV4CanonicalHeaders(header http.Header) (string, string) {
+	values := map[string]string{}
+	var names []string
+	for key, vs := range header {
+		name := strings.ToLower(key)
+		if awsV4Unsigned[name] {
+			continue
+		}
+		trimmed := make([]string, len(vs))
+		for i, v := range vs {
+			trimmed[i] = strings.Join(strings.Fields(v), " ")
+		}
+		if _, ok := values[name]; !ok {
+			names = append(names, name)
+		} else {
+			values[name] += ","
+		}
+		values[name] += strings.Join(trimmed, ",")
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name + ":" + values[name] + "\n")
+	}
+	return b.String(), strings.Join(names, ";")
+}
+
+// awsV4Escape percent-encodes every byte of s except unreserved characters
+// and, unless encodeSlash is set, '/'.
+func awsV4Escape(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,67 @@
+package sign
+
+import (
+	"crypto/hmac"
+	"crypto/sha1" //nolint:gosec
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hbagdi/hit/pkg/model"
+)
+
+var hmacHashes = map[string]func() hash.Hash{
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+	"sha1":   sha1.New,
+}
+
+// signHMAC sets the timestamp header of req to now and the signature header
+// to the HMAC of the message described by the template of config.
+func signHMAC(req *model.Request, config model.HMACSigning, now time.Time) error {
+	newHash, ok := hmacHashes[config.Algorithm]
+	if !ok {
+		return fmt.Errorf("invalid hmac algorithm '%v'", config.Algorithm)
+	}
+	timestamp := hmacTimestamp(now, config.TimestampFormat)
+	message := strings.NewReplacer(
+		"{method}", req.Method,
+		"{host}", req.Header.Get("Host"),
+		"{path}", escapedPath(req),
+		"{query}", req.QueryString,
+		"{timestamp}", timestamp,
+		"{body}", string(req.Body),
+		"{bodySHA256}", sha256Hex(req.Body),
+	).Replace(config.Template)
+
+	mac := hmac.New(newHash, []byte(config.Key))
+	mac.Write([]byte(message))
+	var signature string
+	if config.Encoding == "base64" {
+		signature = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	} else {
+		signature = hex.EncodeToString(mac.Sum(nil))
+	}
+
+	req.Header = req.Header.Clone()
+	req.Header.Set(config.TimestampHeader, timestamp)
+	req.Header.Set(config.Header, config.Prefix+signature)
+	return nil
+}
+
+func hmacTimestamp(now time.Time, format string) string {
+	switch format {
+	case "unixMillis":
+		return strconv.FormatInt(now.UnixNano()/int64(time.Millisecond), 10)
+	case "rfc3339":
+		return now.UTC().Format(time.RFC3339)
+	default:
+		return strconv.FormatInt(now.Unix(), 10)
+	}
+}
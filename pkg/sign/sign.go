@@ -0,0 +1,40 @@
+package sign
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"time"
+
+	"github.com/hbagdi/hit/pkg/model"
+)
+
+// Sign signs req as configured by its Signing at time now. It is the last
+// stage before sending a request, signatures cover its final method, URL,
+// headers and body.
+func Sign(req *model.Request, now time.Time) error {
+	if req.Signing == nil {
+		return nil
+	}
+	switch {
+	case req.Signing.AWS != nil:
+		signAWSV4(req, *req.Signing.AWS, now)
+	case req.Signing.HMAC != nil:
+		return signHMAC(req, *req.Signing.HMAC, now)
+	}
+	return nil
+}
+
+// escapedPath returns the path of req as it is sent.
+func escapedPath(req *model.Request) string {
+	path := (&url.URL{Path: req.Path}).EscapedPath()
+	if path == "" {
+		return "/"
+	}
+	return path
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,88 @@
+package sign
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hbagdi/hit/pkg/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignAWSV4(t *testing.T) {
+	// examples of the AWS Signature Version 4 test suite
+	now := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+	signing := &model.Signing{AWS: &model.AWSSigning{
+		Region:          "us-east-1",
+		Service:         "service",
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	}}
+	for _, tc := range []struct {
+		name, method, query, signature string
+	}{
+		{
+			name:      "get-vanilla",
+			method:    "GET",
+			signature: "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		},
+		{
+			name:      "get-vanilla-query-order-key-case",
+			method:    "GET",
+			query:     "Param2=value2&Param1=value1",
+			signature: "b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500",
+		},
+		{
+			name:      "post-vanilla",
+			method:    "POST",
+			signature: "5da7c1a2acd57cee7505fc6676e4e544621c30862966e37dddb68e92efbe5d6b",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := model.Request{
+				Method:      tc.method,
+				Scheme:      "https",
+				Host:        "example.amazonaws.com",
+				Path:        "/",
+				QueryString: tc.query,
+				Header: http.Header{
+					"Host":       {"example.amazonaws.com"},
+					"User-Agent": {"hit/dev"},
+				},
+				Signing: signing,
+			}
+			require.Nil(t, Sign(&req, now))
+			require.Equal(t, "20150830T123600Z", req.Header.Get("X-Amz-Date"))
+			require.Equal(t, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/"+
+				"us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, "+
+				"Signature="+tc.signature, req.Header.Get("Authorization"))
+		})
+	}
+}
+
+func TestSignHMAC(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	req := model.Request{
+		Method: "POST",
+		Path:   "/",
+		Header: http.Header{"Host": {"localhost"}},
+		Body:   []byte("The quick brown fox jumps over the lazy dog"),
+		Signing: &model.Signing{HMAC: &model.HMACSigning{
+			Key:             "key",
+			Algorithm:       "sha256",
+			Encoding:        "hex",
+			Header:          "Authorization",
+			Prefix:          "HMAC ",
+			TimestampHeader: "X-Timestamp",
+			TimestampFormat: "unix",
+			Template:        "{body}",
+		}},
+	}
+	require.Nil(t, Sign(&req, now))
+	require.Equal(t, "1700000000", req.Header.Get("X-Timestamp"))
+	require.Equal(t, "HMAC f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8",
+		req.Header.Get("Authorization"))
+
+	require.Equal(t, "1700000000000", hmacTimestamp(now, "unixMillis"))
+	require.Equal(t, "2023-11-14T22:13:20Z", hmacTimestamp(now, "rfc3339"))
+}
@@ -0,0 +1,136 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/hbagdi/hit/pkg/cmd"
+	"github.com/hbagdi/hit/pkg/test/util"
+	"github.com/stretchr/testify/require"
+)
+
+func handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, `{"token": "t1"}`)
+	})
+	mux.HandleFunc("/nodes/t1", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, `{"id": 42, "name": "node-42", "tags": ["a", "b"]}`)
+	})
+	mux.HandleFunc("/nodes/missing", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = fmt.Fprint(w, "not found")
+	})
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {})
+	return mux
+}
+
+// durations are replaced as they vary.
+var durationRegex = regexp.MustCompile(`\((\d+ms|0s)\)`)
+
+func runTest(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	capture := util.NewStdCapture()
+	defer capture.Cleanup()
+	err := cmd.Run(context.Background(), append([]string{
+		"test-binary-name", "test",
+	}, args...)...)
+	capture.Stop()
+	return durationRegex.ReplaceAllString(string(capture.Stdout()), "(X)"), err
+}
+
+func TestAssertions(t *testing.T) {
+	util.StartServer(t, "127.0.0.1:40313", handler())
+
+	t.Run("selected requests pass", func(t *testing.T) {
+		out, err := runTest(t, "@login", "@get-node")
+		require.Nil(t, err)
+		require.Equal(t, `PASS @login (X)
+  ok   status == 200
+  ok   body token exists
+PASS @get-node (X)
+  ok   status == 200
+  ok   header Content-Type == "application/json"
+  ok   body id == 42
+  ok   body name matches "^node-"
+  ok   body tags == ["a","b"]
+  ok   body deleted does not exist
+  ok   latency < 5s
+
+2 passed, 0 failed
+`, out)
+	})
+	t.Run("all requests are run and failures are reported", func(t *testing.T) {
+		out, err := runTest(t)
+		require.EqualError(t, err, "test: 1 of 4 request(s) failed")
+		require.Contains(t, out, `FAIL @missing-node (X)
+  fail status == 200: got 404
+  fail body id == 42: response body is not JSON
+PASS @health (X)
+
+3 passed, 1 failed
+`)
+	})
+	t.Run("unknown requests fail", func(t *testing.T) {
+		out, err := runTest(t, "@unknown")
+		require.EqualError(t, err, "test: 1 of 1 request(s) failed")
+		require.Equal(t, "FAIL @unknown (X)\n  error: request 'unknown' "+
+			"not found\n\n0 passed, 1 failed\n", out)
+	})
+	t.Run("JUnit report is written to the standard output", func(t *testing.T) {
+		out, err := runTest(t, "--report", "junit", "@login", "@missing-node")
+		require.EqualError(t, err, "test: 1 of 2 request(s) failed")
+		require.True(t, strings.HasPrefix(out, `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites name="hit" tests="2" failures="1" errors="0" `), out)
+		require.Regexp(t, `<testcase name="@login" classname="hit" time="[0-9.]+"></testcase>`, out)
+		require.Contains(t, out, `<failure message="2 of 2 assertion(s) failed" `+
+			`type="assertion">status == 200: got 404&#xA;body id == 42: response `+
+			`body is not JSON&#xA;&#xA;GET /nodes/missing HTTP/1.1&#xA;`)
+		require.Contains(t, out, `HTTP/1.1 404 Not Found&#xA;`)
+		require.NotContains(t, out, "passed")
+	})
+	t.Run("TAP report is written to a file", func(t *testing.T) {
+		filename := filepath.Join(t.TempDir(), "report.tap")
+		out, err := runTest(t, "--report", "tap", "--report-file", filename,
+			"@login", "@health")
+		require.Nil(t, err)
+		require.Contains(t, out, "2 passed, 0 failed\n")
+		tap, err := os.ReadFile(filename)
+		require.Nil(t, err)
+		require.Equal(t, "TAP version 13\n1..2\nok 1 - @login\nok 2 - @health\n"+
+			"# passed 2\n# failed 0\n", string(tap))
+	})
+	t.Run("JSON report", func(t *testing.T) {
+		out, err := runTest(t, "--report", "json", "@health")
+		require.Nil(t, err)
+		var summary struct {
+			Passed int `json:"passed"`
+			Cases  []struct {
+				ID string `json:"id"`
+			} `json:"cases"`
+		}
+		require.Nil(t, json.Unmarshal([]byte(out), &summary))
+		require.Equal(t, 1, summary.Passed)
+		require.Equal(t, "@health", summary.Cases[0].ID)
+	})
+	t.Run("invalid report format fails", func(t *testing.T) {
+		_, err := runTest(t, "--report", "xml")
+		require.EqualError(t, err, "invalid report format 'xml': only "+
+			"'junit', 'tap' or 'json' is supported")
+		_, err = runTest(t, "--report-file", "report.xml")
+		require.EqualError(t, err, "--report-file requires --report")
+	})
+	t.Run("requests must begin with '@'", func(t *testing.T) {
+		_, err := runTest(t, "login")
+		require.EqualError(t, err, "request must begin with '@' character")
+	})
+}
@@ -0,0 +1,166 @@
+package core
+
+import (
+	"context"
+	"crypto/md5" //nolint:gosec
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/hbagdi/hit/pkg/cache"
+	"github.com/hbagdi/hit/pkg/cmd"
+	"github.com/hbagdi/hit/pkg/db"
+	"github.com/hbagdi/hit/pkg/executor"
+	"github.com/hbagdi/hit/pkg/log"
+	"github.com/hbagdi/hit/pkg/model"
+	"github.com/hbagdi/hit/pkg/test/util"
+	"github.com/stretchr/testify/require"
+)
+
+var c cache.Cache
+
+func init() {
+	store, err := db.NewStore(context.Background(),
+		db.StoreOpts{Logger: log.Logger})
+	if err != nil {
+		panic(fmt.Errorf("init test db: %v", err))
+	}
+	c = cache.GetDBCache(store)
+}
+
+var digestParamRegex = regexp.MustCompile(`(\w+)="?([^",]*)"?`)
+
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s)) //nolint:gosec
+	return hex.EncodeToString(sum[:])
+}
+
+// validDigest verifies the digest authorization of r for the credentials
+// hit:secret.
+func validDigest(r *http.Request) bool {
+	params := map[string]string{}
+	for _, m := range digestParamRegex.FindAllStringSubmatch(r.Header.Get("Authorization"), -1) {
+		params[m[1]] = m[2]
+	}
+	if params["uri"] != r.URL.RequestURI() || params["opaque"] != "opaque" {
+		return false
+	}
+	ha1 := md5Hex("hit:hit-test:secret")
+	ha2 := md5Hex(r.Method + ":" + params["uri"])
+	return params["response"] == md5Hex(ha1+":nonce:"+params["nc"]+":"+
+		params["cnonce"]+":auth:"+ha2)
+}
+
+func handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/basic", func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok || username != "hit" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = fmt.Fprint(w, username)
+	})
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"token":"login-token"}`))
+	})
+	mux.HandleFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, "%s|%s|%s", r.Header.Get("Authorization"),
+			r.Header.Get("X-API-Key"), r.URL.RawQuery)
+	})
+	mux.HandleFunc("/digest", func(w http.ResponseWriter, r *http.Request) {
+		if !validDigest(r) {
+			w.Header().Set("WWW-Authenticate", `Digest realm="hit-test", `+
+				`qop="auth", nonce="nonce", opaque="opaque"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = fmt.Fprint(w, "authenticated")
+	})
+	return mux
+}
+
+func TestAuth(t *testing.T) {
+	util.StartServer(t, "127.0.0.1:40309", handler())
+
+	e, err := executor.NewExecutor(&executor.Opts{Cache: c})
+	require.Nil(t, err)
+	require.Nil(t, e.LoadFiles())
+	execute := func(t *testing.T, id string, args ...string) (model.Hit, error) {
+		t.Helper()
+		req, err := e.BuildRequest(id, &executor.RequestOpts{
+			Params: append([]string{"@" + id}, args...),
+		})
+		if err != nil {
+			return model.Hit{}, err
+		}
+		return e.Execute(context.Background(), id, req)
+	}
+	body := func(t *testing.T, id string, args ...string) string {
+		t.Helper()
+		hit, err := execute(t, id, args...)
+		require.Nil(t, err)
+		return string(hit.Response.Body)
+	}
+
+	t.Run("basic auth", func(t *testing.T) {
+		require.Equal(t, "hit", body(t, "basic"))
+	})
+	t.Run("bearer token is resolved", func(t *testing.T) {
+		_, err := execute(t, "login")
+		require.Nil(t, err)
+		require.Equal(t, "Bearer login-token||", body(t, "bearer"))
+	})
+	t.Run("digest challenge is answered", func(t *testing.T) {
+		hit, err := execute(t, "digest")
+		require.Nil(t, err)
+		require.Equal(t, http.StatusOK, hit.Response.Code)
+		require.Equal(t, "authenticated", string(hit.Response.Body))
+		require.Regexp(t, `^Digest username="hit"`, hit.Request.Header.Get("Authorization"))
+
+		hit, err = execute(t, "digest-wrong-password")
+		require.Nil(t, err)
+		require.Equal(t, http.StatusUnauthorized, hit.Response.Code)
+	})
+	t.Run("API key in a header or the query", func(t *testing.T) {
+		require.Equal(t, "|header-key|", body(t, "api-key-header", "header-key"))
+		require.Equal(t, "||api_key=query-key&page=1", body(t, "api-key-query"))
+	})
+	t.Run("request headers take precedence", func(t *testing.T) {
+		require.Equal(t, "Bearer explicit||", body(t, "explicit-header"))
+	})
+	t.Run("auth is disabled per request", func(t *testing.T) {
+		require.Equal(t, "||", body(t, "no-auth"))
+	})
+	t.Run("invalid auth type fails", func(t *testing.T) {
+		_, err := execute(t, "invalid")
+		require.EqualError(t, err, "failed to build request: invalid auth "+
+			"type 'oauth': only 'basic', 'bearer', 'digest', 'apiKey', "+
+			"'oauth2' or 'none' is supported")
+	})
+	t.Run("credentials are masked when printed", func(t *testing.T) {
+		for id, expected := range map[string]string{
+			"bearer":        "Authorization: Bearer *****",
+			"basic":         "Authorization: Basic *****",
+			"digest":        "Authorization: Digest *****",
+			"api-key-query": "GET /echo?api_key=*****&page=1",
+		} {
+			capture := util.NewStdCapture()
+			err := cmd.Run(context.Background(), "test-binary-name", "@"+id)
+			capture.Stop()
+			require.Nil(t, err)
+			out := string(capture.Stdout())
+			capture.Cleanup()
+			// the response echoes the credentials
+			out = out[:strings.Index(out, "\nHTTP/1.1 ")]
+			require.Contains(t, out, expected, id)
+			require.NotContains(t, out, "login-token", id)
+			require.NotContains(t, out, "aGl0OnNlY3JldA==", id)
+			require.NotContains(t, out, "query-key", id)
+		}
+	})
+}
@@ -0,0 +1,83 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hbagdi/hit/pkg/cmd"
+	"github.com/hbagdi/hit/pkg/test/util"
+	"github.com/stretchr/testify/require"
+)
+
+func handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		user := r.URL.Query().Get("user")
+		if user == "bad" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = fmt.Fprint(w, `{"data": {"token": "stolen"}, "version": "v0"}`)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Session", "s-"+user)
+		_, _ = fmt.Fprintf(w, `{"data": {"token": "token-%v"}, "version": "v3"}`, user)
+	})
+	mux.HandleFunc("/me/", func(w http.ResponseWriter, r *http.Request) {
+		user := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer token-")
+		_, _ = fmt.Fprintf(w, "hello %v session %v version %v", user,
+			strings.TrimPrefix(r.URL.Path, "/me/"), r.URL.Query().Get("version"))
+	})
+	return mux
+}
+
+func run(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	capture := util.NewStdCapture()
+	defer capture.Cleanup()
+	err := cmd.Run(context.Background(), append([]string{"test-binary-name"},
+		args...)...)
+	capture.Stop()
+	return string(capture.Stdout()), err
+}
+
+func TestCapture(t *testing.T) {
+	util.StartServer(t, "127.0.0.1:40314", handler())
+
+	t.Run("captured variables are referenced by later requests", func(t *testing.T) {
+		_, err := run(t, "@login", "alice")
+		require.Nil(t, err)
+		out, err := run(t, "@me")
+		require.Nil(t, err)
+		require.Contains(t, out, "hello alice session s-alice version 3")
+	})
+	t.Run("failed responses are not captured", func(t *testing.T) {
+		out, err := run(t, "@login", "bad")
+		require.Nil(t, err)
+		require.Contains(t, out, "401 Unauthorized")
+		out, err = run(t, "@me")
+		require.Nil(t, err)
+		require.Contains(t, out, "hello alice session s-alice version 3")
+	})
+	t.Run("variables are scoped by environment", func(t *testing.T) {
+		_, err := run(t, "--env", "staging", "@login", "bob")
+		require.Nil(t, err)
+		out, err := run(t, "--env", "staging", "@me")
+		require.Nil(t, err)
+		require.Contains(t, out, "hello bob session s-bob version 3")
+		out, err = run(t, "@me")
+		require.Nil(t, err)
+		require.Contains(t, out, "hello alice session s-alice version 3")
+	})
+	t.Run("missing values fail the request", func(t *testing.T) {
+		_, err := run(t, "@broken")
+		require.EqualError(t, err, "capture variables: capture 'missing': "+
+			"'data.missing' not found in response body")
+	})
+	t.Run("unset variables fail the request", func(t *testing.T) {
+		_, err := run(t, "@unset")
+		require.ErrorContains(t, err, "variable 'never' is not set")
+	})
+}
@@ -0,0 +1,69 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/hbagdi/hit/pkg/cache"
+	"github.com/hbagdi/hit/pkg/db"
+	"github.com/hbagdi/hit/pkg/executor"
+	"github.com/hbagdi/hit/pkg/log"
+	"github.com/hbagdi/hit/pkg/test/util"
+	"github.com/stretchr/testify/require"
+)
+
+var c cache.Cache
+
+func init() {
+	store, err := db.NewStore(context.Background(),
+		db.StoreOpts{Logger: log.Logger})
+	if err != nil {
+		panic(fmt.Errorf("init test db: %v", err))
+	}
+	c = cache.GetDBCache(store)
+}
+
+func TestConnections(t *testing.T) {
+	util.StartServer(t, "127.0.0.1:40318", http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprintf(w, `{"client":%q}`, r.RemoteAddr)
+		}))
+
+	newExecutor := func(t *testing.T) *executor.Executor {
+		t.Helper()
+		e, err := executor.NewExecutor(&executor.Opts{Cache: c})
+		require.Nil(t, err)
+		require.Nil(t, e.LoadFiles())
+		t.Cleanup(func() { _ = e.Close() })
+		return e
+	}
+	execute := func(t *testing.T, e *executor.Executor, id string) string {
+		t.Helper()
+		req, err := e.BuildRequest(id, nil)
+		require.Nil(t, err)
+		hit, err := e.Execute(context.Background(), id, req)
+		require.Nil(t, err)
+		return string(hit.Response.Body)
+	}
+
+	t.Run("connections are reused by requests", func(t *testing.T) {
+		e := newExecutor(t)
+		client := execute(t, e, "get")
+		require.Equal(t, client, execute(t, e, "get"))
+		require.Equal(t, client, execute(t, e, "get-timeout"))
+	})
+	t.Run("transport options take other connections", func(t *testing.T) {
+		e := newExecutor(t)
+		client := execute(t, e, "get")
+		require.NotEqual(t, client, execute(t, e, "get-no-proxy"))
+		require.Equal(t, client, execute(t, e, "get"))
+	})
+	t.Run("close closes the connections", func(t *testing.T) {
+		e := newExecutor(t)
+		client := execute(t, e, "get")
+		require.Nil(t, e.Close())
+		require.NotEqual(t, client, execute(t, e, "get"))
+	})
+}
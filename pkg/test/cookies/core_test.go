@@ -0,0 +1,149 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hbagdi/hit/pkg/cache"
+	"github.com/hbagdi/hit/pkg/cmd"
+	"github.com/hbagdi/hit/pkg/db"
+	"github.com/hbagdi/hit/pkg/executor"
+	"github.com/hbagdi/hit/pkg/log"
+	"github.com/hbagdi/hit/pkg/model"
+	"github.com/hbagdi/hit/pkg/test/util"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	store *db.Store
+	c     cache.Cache
+)
+
+func init() {
+	var err error
+	store, err = db.NewStore(context.Background(),
+		db.StoreOpts{Logger: log.Logger})
+	if err != nil {
+		panic(fmt.Errorf("init test db: %v", err))
+	}
+	c = cache.GetDBCache(store)
+}
+
+func handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "s1",
+			Path: "/", HttpOnly: true})
+		http.SetCookie(w, &http.Cookie{Name: "theme", Value: "dark",
+			Path: "/", MaxAge: 3600})
+		http.SetCookie(w, &http.Cookie{Name: "rejected", Value: "x",
+			Domain: "example.com"})
+	})
+	mux.HandleFunc("/redirect-login", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "s2", Path: "/"})
+		http.Redirect(w, r, "/me", http.StatusFound)
+	})
+	mux.HandleFunc("/me", func(w http.ResponseWriter, r *http.Request) {
+		session, err := r.Cookie("session")
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = fmt.Fprint(w, session.Value)
+	})
+	mux.HandleFunc("/logout", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Path: "/", MaxAge: -1})
+	})
+	return mux
+}
+
+func TestCookies(t *testing.T) {
+	util.StartServer(t, "127.0.0.1:40312", handler())
+	for _, environment := range []string{"", "staging"} {
+		scope, err := executor.Scope(environment)
+		require.Nil(t, err)
+		_, err = store.ClearCookies(context.Background(), scope)
+		require.Nil(t, err)
+	}
+
+	// every request uses a new executor as if hit was invoked again
+	execute := func(t *testing.T, id, environment string) model.Hit {
+		t.Helper()
+		e, err := executor.NewExecutor(&executor.Opts{
+			Cache:       c,
+			Environment: environment,
+		})
+		require.Nil(t, err)
+		require.Nil(t, e.LoadFiles())
+		req, err := e.BuildRequest(id, nil)
+		require.Nil(t, err)
+		hit, err := e.Execute(context.Background(), id, req)
+		require.Nil(t, err)
+		return hit
+	}
+
+	t.Run("cookies persist across invocations", func(t *testing.T) {
+		hit := execute(t, "me", "")
+		require.Equal(t, http.StatusUnauthorized, hit.Response.Code)
+		execute(t, "login", "")
+		hit = execute(t, "me", "")
+		require.Equal(t, http.StatusOK, hit.Response.Code)
+		require.Equal(t, "s1", string(hit.Response.Body))
+		require.Equal(t, "session=s1; theme=dark", hit.Request.Header.Get("Cookie"))
+	})
+	t.Run("cookies can be disabled per request", func(t *testing.T) {
+		hit := execute(t, "me-without-cookies", "")
+		require.Equal(t, http.StatusUnauthorized, hit.Response.Code)
+		require.Empty(t, hit.Request.Header.Get("Cookie"))
+	})
+	t.Run("cookies are scoped per environment", func(t *testing.T) {
+		hit := execute(t, "me", "staging")
+		require.Equal(t, http.StatusUnauthorized, hit.Response.Code)
+	})
+	t.Run("cookies set before a redirect are sent", func(t *testing.T) {
+		hit := execute(t, "redirect-login", "staging")
+		require.Equal(t, http.StatusOK, hit.Response.Code)
+		require.Equal(t, "s2", string(hit.Response.Body))
+		hit = execute(t, "me", "")
+		require.Equal(t, "s1", string(hit.Response.Body))
+	})
+	t.Run("expired cookies are removed", func(t *testing.T) {
+		execute(t, "logout", "")
+		hit := execute(t, "me", "")
+		require.Equal(t, http.StatusUnauthorized, hit.Response.Code)
+		require.Equal(t, "theme=dark", hit.Request.Header.Get("Cookie"))
+	})
+	t.Run("cookies are listed and cleared", func(t *testing.T) {
+		run := func(args ...string) string {
+			t.Helper()
+			capture := util.NewStdCapture()
+			defer capture.Cleanup()
+			err := cmd.Run(context.Background(), append([]string{
+				"test-binary-name", "cookies",
+			}, args...)...)
+			capture.Stop()
+			require.Nil(t, err)
+			return string(capture.Stdout())
+		}
+		lines := strings.Split(strings.TrimSpace(run("list")), "\n")
+		require.Len(t, lines, 2)
+		require.Regexp(t, `^DOMAIN\s+PATH\s+NAME\s+VALUE\s+EXPIRES$`, lines[0])
+		require.Regexp(t, `^127\.0\.0\.1\s+/\s+theme\s+dark\s+\d{4}-`, lines[1])
+
+		lines = strings.Split(strings.TrimSpace(run("list", "--env", "staging")), "\n")
+		require.Len(t, lines, 2)
+		require.Regexp(t, `^127\.0\.0\.1\s+/\s+session\s+s2\s+session$`, lines[1])
+
+		require.Equal(t, "cleared 1 cookie(s)\n", run("clear", "--env", "staging"))
+		lines = strings.Split(strings.TrimSpace(run("list", "--env", "staging")), "\n")
+		require.Len(t, lines, 1)
+	})
+	t.Run("invalid cookies command fails", func(t *testing.T) {
+		err := cmd.Run(context.Background(), "test-binary-name", "cookies", "drop")
+		require.EqualError(t, err, "invalid cookies command 'drop': expected "+
+			"'list' or 'clear'")
+	})
+}
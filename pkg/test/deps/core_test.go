@@ -0,0 +1,106 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hbagdi/hit/pkg/cmd"
+	"github.com/hbagdi/hit/pkg/test/util"
+	"github.com/stretchr/testify/require"
+)
+
+var logins, creates int64
+
+func handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&logins, 1)
+		_, _ = fmt.Fprint(w, `{"token": "t1"}`)
+	})
+	mux.HandleFunc("/nodes", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer t1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		n := atomic.AddInt64(&creates, 1)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = fmt.Fprintf(w, `{"id": %d}`, n)
+	})
+	mux.HandleFunc("/nodes/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, "node %v", r.URL.Path)
+	})
+	mux.HandleFunc("/audit", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/broken", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	return mux
+}
+
+func run(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	capture := util.NewStdCapture()
+	defer capture.Cleanup()
+	err := cmd.Run(context.Background(), append([]string{"test-binary-name"},
+		args...)...)
+	capture.Stop()
+	return string(capture.Stdout()), err
+}
+
+func TestDependencies(t *testing.T) {
+	util.StartServer(t, "127.0.0.1:40316", handler())
+
+	t.Run("dependencies are executed in order", func(t *testing.T) {
+		out, err := run(t, "--refresh-deps", "@deps-get-node")
+		require.Nil(t, err)
+		require.Contains(t, out, "GET /nodes/1 HTTP/1.1")
+		require.Contains(t, out, "node /nodes/1")
+		require.Equal(t, int64(1), atomic.LoadInt64(&logins))
+		require.Equal(t, int64(1), atomic.LoadInt64(&creates))
+	})
+	t.Run("cached dependencies are not executed", func(t *testing.T) {
+		out, err := run(t, "@deps-get-node")
+		require.Nil(t, err)
+		require.Contains(t, out, "GET /nodes/1 HTTP/1.1")
+		_, err = run(t, "@deps-audit")
+		require.Nil(t, err)
+		require.Equal(t, int64(1), atomic.LoadInt64(&logins))
+		require.Equal(t, int64(1), atomic.LoadInt64(&creates))
+	})
+	t.Run("declared dependencies are executed", func(t *testing.T) {
+		_, err := run(t, "--refresh-deps", "@deps-audit")
+		require.Nil(t, err)
+		require.Equal(t, int64(2), atomic.LoadInt64(&logins))
+		require.Equal(t, int64(1), atomic.LoadInt64(&creates))
+	})
+	t.Run("failed dependencies fail the request", func(t *testing.T) {
+		_, err := run(t, "--refresh-deps", "@deps-uses-broken")
+		require.EqualError(t, err, "dependency '@deps-broken': unsuccessful "+
+			"response: 500 Internal Server Error")
+	})
+	t.Run("dependencies taking positional arguments are not executed", func(t *testing.T) {
+		_, err := run(t, "--refresh-deps", "@deps-uses-node-by-id")
+		require.EqualError(t, err, "dependency '@deps-node-by-id' takes "+
+			"positional arguments and can't be executed automatically: "+
+			"execute '@deps-node-by-id' with its arguments first")
+	})
+	t.Run("graph is printed", func(t *testing.T) {
+		out, err := run(t, "deps", "@deps-get-node")
+		require.Nil(t, err)
+		require.Equal(t, `@deps-get-node
+  @deps-create-node (@deps-create-node.id, cached)
+    @deps-login (@var.token, cached)
+`, out)
+		out, err = run(t, "deps", "@deps-audit")
+		require.Nil(t, err)
+		require.Equal(t, "@deps-audit\n  @deps-login (dependsOn, cached)\n", out)
+	})
+	t.Run("cycles are detected", func(t *testing.T) {
+		_, err := run(t, "deps", "@cycle-a")
+		require.EqualError(t, err, "dependency cycle: @cycle-a -> @cycle-b -> @cycle-a")
+		_, err = run(t, "@cycle-a")
+		require.EqualError(t, err, "dependency cycle: @cycle-a -> @cycle-b -> @cycle-a")
+	})
+}
@@ -0,0 +1,190 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/hbagdi/hit/pkg/cache"
+	"github.com/hbagdi/hit/pkg/cmd"
+	"github.com/hbagdi/hit/pkg/db"
+	"github.com/hbagdi/hit/pkg/executor"
+	"github.com/hbagdi/hit/pkg/log"
+	"github.com/hbagdi/hit/pkg/model"
+	"github.com/hbagdi/hit/pkg/parser"
+	"github.com/hbagdi/hit/pkg/test/util"
+	"github.com/stretchr/testify/require"
+)
+
+var c cache.Cache
+
+func init() {
+	store, err := db.NewStore(context.Background(),
+		db.StoreOpts{Logger: log.Logger})
+	if err != nil {
+		panic(fmt.Errorf("init test db: %v", err))
+	}
+	c = cache.GetDBCache(store)
+}
+
+// image is a binary body that is not valid UTF-8.
+var image = append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0xff, 0x00}, 4096)...)
+
+func handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/images/logo.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Header().Set("Content-Length", strconv.Itoa(len(image)))
+		_, _ = w.Write(image)
+	})
+	mux.HandleFunc("/report", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Disposition", `attachment; filename="../report.csv"`)
+		_, _ = w.Write([]byte("id,name\n1,hit\n"))
+	})
+	mux.HandleFunc("/large", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "2048")
+		_, _ = w.Write(bytes.Repeat([]byte("a"), 2048))
+	})
+	mux.HandleFunc("/large-unknown-length", func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 4; i++ {
+			_, _ = w.Write(bytes.Repeat([]byte("a"), 512))
+			w.(http.Flusher).Flush()
+		}
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 4; i++ {
+			_, _ = w.Write([]byte("a"))
+			w.(http.Flusher).Flush()
+			time.Sleep(100 * time.Millisecond)
+		}
+	})
+	mux.HandleFunc("/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"name":"hit"}`))
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("index"))
+	})
+	return mux
+}
+
+func TestDownload(t *testing.T) {
+	util.StartServer(t, "127.0.0.1:40302", handler())
+
+	execute := func(t *testing.T, opts *executor.Opts, id string) (model.Hit, error) {
+		t.Helper()
+		opts.Cache = c
+		e, err := executor.NewExecutor(opts)
+		require.Nil(t, err)
+		require.Nil(t, e.LoadFiles())
+		req, err := e.BuildRequest(id, nil)
+		require.Nil(t, err)
+		return e.Execute(context.Background(), id, req)
+	}
+
+	t.Run("body is written to a file", func(t *testing.T) {
+		filename := filepath.Join(t.TempDir(), "logo.png")
+		var progress bytes.Buffer
+		hit, err := execute(t, &executor.Opts{
+			Options:  parser.Options{Output: filename},
+			Progress: &progress,
+		}, "image")
+		require.Nil(t, err)
+		require.Equal(t, filename, hit.Response.BodyFile)
+		require.Equal(t, int64(len(image)), hit.Response.BodySize)
+		require.Empty(t, hit.Response.Body)
+		content, err := os.ReadFile(filename)
+		require.Nil(t, err)
+		require.Equal(t, image, content)
+		require.Contains(t, progress.String(),
+			"downloading "+filename+": 8.0 KB of 8.0 KB (100%)\n")
+	})
+	t.Run("file name is derived in a directory", func(t *testing.T) {
+		dir := t.TempDir() + "/"
+		for id, name := range map[string]string{
+			"image":  "logo.png",
+			"report": "report.csv",
+			"root":   "root",
+		} {
+			hit, err := execute(t, &executor.Opts{
+				Options: parser.Options{Output: dir},
+			}, id)
+			require.Nil(t, err)
+			require.Equal(t, filepath.Join(dir, name), hit.Response.BodyFile)
+			require.FileExists(t, filepath.Join(dir, name))
+		}
+	})
+	t.Run("timeout only applies to the response headers", func(t *testing.T) {
+		filename := filepath.Join(t.TempDir(), "slow")
+		hit, err := execute(t, &executor.Opts{
+			Options: parser.Options{Output: filename},
+		}, "slow")
+		require.Nil(t, err)
+		require.Equal(t, int64(4), hit.Response.BodySize)
+		content, err := os.ReadFile(filename)
+		require.Nil(t, err)
+		require.Equal(t, "aaaa", string(content))
+	})
+	t.Run("body larger than the maximum size fails", func(t *testing.T) {
+		dir := t.TempDir()
+		for _, id := range []string{"large", "large-unknown-length"} {
+			_, err := execute(t, &executor.Opts{}, id)
+			require.ErrorContains(t, err,
+				"response body exceeds maxBodySize of 1.0 KB", id)
+
+			filename := filepath.Join(dir, id)
+			_, err = execute(t, &executor.Opts{
+				Options: parser.Options{Output: filename},
+			}, id)
+			require.ErrorContains(t, err,
+				"response body exceeds maxBodySize of 1.0 KB", id)
+			require.NoFileExists(t, filename)
+		}
+
+		size := parser.Size(4096)
+		hit, err := execute(t, &executor.Opts{
+			Options: parser.Options{MaxBodySize: &size},
+		}, "large")
+		require.Nil(t, err)
+		require.Len(t, hit.Response.Body, 2048)
+	})
+	t.Run("saved body is printed as a reference", func(t *testing.T) {
+		filename := filepath.Join(t.TempDir(), "logo.png")
+		capture := util.NewStdCapture()
+		defer capture.Cleanup()
+		err := cmd.Run(context.Background(), "test-binary-name",
+			"-o", filename, "@image")
+		capture.Stop()
+		require.Nil(t, err)
+		out := string(capture.Stdout())
+		require.Contains(t, out, "body saved to "+filename+" (8.0 KB)")
+		require.NotContains(t, out, "PNG")
+		require.FileExists(t, filename)
+	})
+	t.Run("binary body is not printed", func(t *testing.T) {
+		capture := util.NewStdCapture()
+		defer capture.Cleanup()
+		err := cmd.Run(context.Background(), "test-binary-name", "@image")
+		capture.Stop()
+		require.Nil(t, err)
+		out := string(capture.Stdout())
+		require.Contains(t, out, "<binary body of 8.0 KB, use --output to save it>")
+		require.NotContains(t, out, "PNG")
+	})
+	t.Run("JSON body is written as is", func(t *testing.T) {
+		filename := filepath.Join(t.TempDir(), "body.json")
+		_, err := execute(t, &executor.Opts{
+			Options: parser.Options{Output: filename},
+		}, "json")
+		require.Nil(t, err)
+		content, err := os.ReadFile(filename)
+		require.Nil(t, err)
+		require.Equal(t, `{"name":"hit"}`, string(content))
+	})
+}
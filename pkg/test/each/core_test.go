@@ -0,0 +1,147 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hbagdi/hit/pkg/cmd"
+	"github.com/hbagdi/hit/pkg/test/util"
+	"github.com/stretchr/testify/require"
+)
+
+type server struct {
+	mu      sync.Mutex
+	deleted []string
+	moved   []string
+}
+
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/nodes", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"items": [{"id": 1}, {"id": 2}, {"id": 3}]}`)
+	})
+	mux.HandleFunc("/nodes/", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		id := strings.Split(strings.TrimPrefix(r.URL.Path, "/nodes/"), "/")[0]
+		if r.Method == http.MethodDelete {
+			if id == "2" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			s.deleted = append(s.deleted, id)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		s.moved = append(s.moved, id+"->"+r.URL.Query().Get("to"))
+	})
+	return mux
+}
+
+// durations are replaced as they vary.
+var durationRegex = regexp.MustCompile(`\((\d+ms|0s)\)`)
+
+func run(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	capture := util.NewStdCapture()
+	defer capture.Cleanup()
+	err := cmd.Run(context.Background(), append([]string{"test-binary-name"},
+		args...)...)
+	capture.Stop()
+	return durationRegex.ReplaceAllString(string(capture.Stdout()), "(X)"), err
+}
+
+func TestEach(t *testing.T) {
+	s := &server{}
+	util.StartServer(t, "127.0.0.1:40317", s.handler())
+
+	t.Run("items of a cached response", func(t *testing.T) {
+		_, err := run(t, "@each-list-nodes")
+		require.Nil(t, err)
+		out, err := run(t, "--each", "@each-list-nodes.items.#.id",
+			"--concurrency", "2", "@each-delete-node")
+		require.EqualError(t, err, "each: 1 of 3 item(s) failed")
+		require.Contains(t, out, "PASS @each-delete-node 1 (X)\n")
+		require.Contains(t, out, "FAIL @each-delete-node 2 (X)\n"+
+			"  error: unsuccessful response: 404 Not Found\n")
+		require.Contains(t, out, "PASS @each-delete-node 3 (X)\n")
+		require.True(t, strings.HasSuffix(out, "\n2 passed, 1 failed, 0 skipped\n"))
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		sort.Strings(s.deleted)
+		require.Equal(t, []string{"1", "3"}, s.deleted)
+	})
+	t.Run("items of files", func(t *testing.T) {
+		out, err := run(t, "--each", "moves.json", "@each-move-node")
+		require.Nil(t, err)
+		require.Equal(t, "PASS @each-move-node 4 a (X)\n"+
+			"PASS @each-move-node 5 b (X)\n\n2 passed, 0 failed, 0 skipped\n", out)
+		out, err = run(t, "--each", "moves.csv", "@each-move-node")
+		require.Nil(t, err)
+		require.Equal(t, "PASS @each-move-node 6 c (X)\n"+
+			"PASS @each-move-node 7 d (X)\n\n2 passed, 0 failed, 0 skipped\n", out)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		require.Equal(t, []string{"4->a", "5->b", "6->c", "7->d"}, s.moved)
+	})
+	t.Run("items are values", func(t *testing.T) {
+		dir := t.TempDir()
+		csvFile := filepath.Join(dir, "empty.csv")
+		require.Nil(t, os.WriteFile(csvFile, []byte("8,\n"), 0o600))
+		jsonFile := filepath.Join(dir, "values.json")
+		require.Nil(t, os.WriteFile(jsonFile,
+			[]byte(`["", "@each-list-nodes.items.0.id"]`), 0o600))
+		s.mu.Lock()
+		s.moved = nil
+		s.mu.Unlock()
+
+		out, err := run(t, "--each", csvFile, "@each-move-node")
+		require.Nil(t, err)
+		require.True(t, strings.HasSuffix(out, "\n1 passed, 0 failed, 0 skipped\n"))
+		out, err = run(t, "--each", jsonFile, "@each-label-node")
+		require.Nil(t, err)
+		require.True(t, strings.HasSuffix(out, "\n2 passed, 0 failed, 0 skipped\n"))
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		require.Equal(t, []string{"8->", "1->", "1->@each-list-nodes.items.0.id"},
+			s.moved)
+	})
+	t.Run("many items at once", func(t *testing.T) {
+		const n = 500
+		ids := make([]string, n)
+		for i := range ids {
+			ids[i] = fmt.Sprintf(`["many-%d", "x"]`, i)
+		}
+		filename := filepath.Join(t.TempDir(), "many.json")
+		require.Nil(t, os.WriteFile(filename,
+			[]byte("["+strings.Join(ids, ",")+"]"), 0o600))
+		out, err := run(t, "--each", filename, "--concurrency", "64",
+			"@each-move-node")
+		require.Nil(t, err)
+		require.True(t, strings.HasSuffix(out,
+			fmt.Sprintf("\n%d passed, 0 failed, 0 skipped\n", n)))
+	})
+	t.Run("invalid items", func(t *testing.T) {
+		_, err := run(t, "--each", "@each-list-nodes.items", "@each-delete-node")
+		require.EqualError(t, err, "item 1 of '@each-list-nodes.items' is an "+
+			"object, expected a string, number or boolean: select the values "+
+			"of objects with a path such as 'items.#.id'")
+		_, err = run(t, "--each", "@each-list-nodes.missing", "@each-delete-node")
+		require.EqualError(t, err, "'@each-list-nodes.missing' is not an array")
+		_, err = run(t, "--each", "nodes.txt", "@each-delete-node")
+		require.EqualError(t, err, "invalid items 'nodes.txt': expected a "+
+			"reference such as '@request-id.path', a .json file or a .csv file")
+		_, err = run(t, "--concurrency", "2", "@each-delete-node")
+		require.EqualError(t, err, "--concurrency requires --each")
+		_, err = run(t, "--each", "moves.csv", "--concurrency", "0", "@each-delete-node")
+		require.EqualError(t, err, "--concurrency must be at least 1")
+	})
+}
@@ -0,0 +1,142 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hbagdi/hit/pkg/cmd"
+	"github.com/hbagdi/hit/pkg/test/util"
+	"github.com/stretchr/testify/require"
+)
+
+func handler() http.Handler {
+	var (
+		mu     sync.Mutex
+		nodes  = map[string]bool{}
+		nextID = 1
+	)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"token": "t-%v"}`, r.URL.Query().Get("user"))
+	})
+	mux.HandleFunc("/nodes", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer t-admin" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		id := fmt.Sprint(nextID)
+		nextID++
+		nodes[id] = true
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = fmt.Fprintf(w, `{"id": %v}`, id)
+	})
+	mux.HandleFunc("/nodes/", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		id := strings.TrimPrefix(r.URL.Path, "/nodes/")
+		if !nodes[id] {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Method == http.MethodDelete {
+			delete(nodes, id)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		_, _ = fmt.Fprintf(w, `{"id": %v}`, id)
+	})
+	return mux
+}
+
+// durations are replaced as they vary.
+var durationRegex = regexp.MustCompile(`\((\d+ms|0s)\)`)
+
+// summary returns the summary printed after the response of the last step
+// of a flow.
+func summary(out string) string {
+	const lastLine = "(127.0.0.1:40315)\n\n"
+	i := strings.LastIndex(out, lastLine)
+	return durationRegex.ReplaceAllString(out[i+len(lastLine):], "(X)")
+}
+
+func runFlow(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	capture := util.NewStdCapture()
+	defer capture.Cleanup()
+	err := cmd.Run(context.Background(), append([]string{
+		"test-binary-name", "flow",
+	}, args...)...)
+	capture.Stop()
+	return string(capture.Stdout()), err
+}
+
+func TestFlow(t *testing.T) {
+	util.StartServer(t, "127.0.0.1:40315", handler())
+
+	t.Run("steps see previous responses", func(t *testing.T) {
+		out, err := runFlow(t, "smoke")
+		require.Nil(t, err)
+		require.Contains(t, out, "==> [1/4] @login admin\nPOST /login?user=admin")
+		require.Contains(t, out, "==> [3/4] @get-node\nGET /nodes/1 HTTP/1.1")
+		require.Contains(t, out, "==> [4/4] @delete-node\nDELETE /nodes/1 HTTP/1.1")
+		require.Equal(t, `flow smoke:
+PASS @login admin (X)
+PASS @create-node (X)
+PASS @get-node (X)
+  ok   status == 200
+PASS @delete-node (X)
+
+4 passed, 0 failed, 0 skipped
+`, summary(out))
+	})
+	t.Run("failed step stops the flow", func(t *testing.T) {
+		out, err := runFlow(t, "broken")
+		require.EqualError(t, err, "flow: 1 of 3 step(s) failed")
+		require.NotContains(t, out, "==> [3/3]")
+		require.Equal(t, `flow broken:
+PASS @login admin (X)
+FAIL @get-missing-node (X)
+  error: unsuccessful response: 404 Not Found
+SKIP @create-node
+
+1 passed, 1 failed, 1 skipped
+`, summary(out))
+	})
+	t.Run("failed step continues when requested", func(t *testing.T) {
+		out, err := runFlow(t, "--continue-on-failure", "broken")
+		require.EqualError(t, err, "flow: 1 of 3 step(s) failed")
+		require.Contains(t, out, "==> [3/3] @create-node\nPOST /nodes")
+		require.Contains(t, out, "\n2 passed, 1 failed, 0 skipped\n")
+	})
+	t.Run("requests are executed in order", func(t *testing.T) {
+		out, err := runFlow(t, "@create-node", "@get-node", "@delete-node", "@get-node")
+		require.EqualError(t, err, "flow: 1 of 4 step(s) failed")
+		require.Equal(t, `PASS @create-node (X)
+PASS @get-node (X)
+  ok   status == 200
+PASS @delete-node (X)
+FAIL @get-node (X)
+  fail status == 200: got 404
+
+3 passed, 1 failed, 0 skipped
+`, summary(out))
+	})
+	t.Run("invalid arguments", func(t *testing.T) {
+		_, err := runFlow(t)
+		require.EqualError(t, err, "need a flow name or requests to execute")
+		_, err = runFlow(t, "missing")
+		require.EqualError(t, err, "flow 'missing' not found")
+		_, err = runFlow(t, "smoke", "broken")
+		require.EqualError(t, err, "expected a single flow name or requests "+
+			"beginning with '@' character")
+	})
+}
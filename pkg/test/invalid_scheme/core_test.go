@@ -28,5 +28,5 @@ func TestInvalidScheme(t *testing.T) {
 	require.Nil(t, err)
 	err = e.LoadFiles()
 	require.ErrorContains(t, err,
-		"invalid scheme 'ftp': only 'http' or 'https' is supported")
+		"invalid scheme 'ftp': only 'http', 'https' or 'http+unix' is supported")
 }
@@ -0,0 +1,44 @@
+package lint
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hbagdi/hit/pkg/cmd"
+	"github.com/hbagdi/hit/pkg/test/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLint(t *testing.T) {
+	c := util.NewStdCapture()
+	defer c.Cleanup()
+	err := cmd.Run(context.Background(), "test-binary-name", "lint")
+	c.Stop()
+	require.EqualError(t, err, "lint: found 13 problem(s)")
+
+	expected := []string{
+		"test.hit:36:1: @create-node: duplicate request ID, already defined in 'test.hit'",
+		"test.hit:8:1: @create-node: positional argument '@2' is never used but '@3' is",
+		"test.hit:19:15: @get-unknown: reference '@does-not-exist.id' to undefined " +
+			"request '@does-not-exist'",
+		"test.hit:22:1: @bad-method: invalid method 'FETCH'",
+		"test.hit:24:1: @bad-yaml: invalid y2j body: yaml: line 1: " +
+			"did not find expected ',' or ']'",
+		"test.hit:32:2: @unknown-encoding: unknown body encoding 'json'",
+		"test.hit:40:6: @bad-grpc-method: invalid gRPC method '/Check': " +
+			"expected '/package.Service/Method'",
+		"test.hit:44:2: @bad-assertion: invalid regular expression of 'id': " +
+			"error parsing regexp: missing closing ): `(`",
+		"test.hit:59:2: @bad-capture: capture 'id' requires exactly one of " +
+			"'body', 'header' or 'regex'",
+		"test.hit:58:26: @bad-capture: reference '@var.missing' to variable " +
+			"'missing' which no ~capture block sets",
+		"test.hit:66:24: @bad-deps: dependency '@setup' is not a defined request",
+		"test.hit:74:4: flow 'smoke': step '@delete-node' references undefined " +
+			"request '@delete-node'",
+		"test.hit:77:8: duplicate flow name 'smoke', already defined in 'test.hit'",
+	}
+	lines := strings.Split(strings.TrimSpace(string(c.Stdout())), "\n")
+	require.Equal(t, expected, lines)
+}
@@ -0,0 +1,182 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hbagdi/hit/pkg/cache"
+	"github.com/hbagdi/hit/pkg/cmd"
+	"github.com/hbagdi/hit/pkg/db"
+	"github.com/hbagdi/hit/pkg/executor"
+	"github.com/hbagdi/hit/pkg/log"
+	"github.com/hbagdi/hit/pkg/test/util"
+	"github.com/stretchr/testify/require"
+)
+
+var c cache.Cache
+
+func init() {
+	store, err := db.NewStore(context.Background(),
+		db.StoreOpts{Logger: log.Logger})
+	if err != nil {
+		panic(fmt.Errorf("init test db: %v", err))
+	}
+	c = cache.GetDBCache(store)
+}
+
+// tokenServer is an OAuth2 token endpoint which records the grants it
+// received.
+type tokenServer struct {
+	mu     sync.Mutex
+	grants []string
+}
+
+func (s *tokenServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		respond := func(code int, v map[string]interface{}) {
+			w.WriteHeader(code)
+			_ = json.NewEncoder(w).Encode(v)
+		}
+		if err := r.ParseForm(); err != nil {
+			respond(http.StatusBadRequest, map[string]interface{}{"error": "invalid_request"})
+			return
+		}
+		grant := r.PostForm.Get("grant_type")
+		s.grants = append(s.grants, grant)
+		n := len(s.grants)
+		clientID, secret, ok := r.BasicAuth()
+		if !ok {
+			clientID = r.PostForm.Get("client_id")
+		}
+		if !strings.HasPrefix(clientID, "client-") || (ok && secret != "secret") {
+			respond(http.StatusUnauthorized, map[string]interface{}{
+				"error":             "invalid_client",
+				"error_description": "bad secret",
+			})
+			return
+		}
+		expiresIn := 3600
+		if r.URL.Query().Get("expires_in") != "" {
+			_, _ = fmt.Sscan(r.URL.Query().Get("expires_in"), &expiresIn)
+		}
+		switch grant {
+		case "client_credentials":
+			respond(http.StatusOK, map[string]interface{}{
+				"access_token":  fmt.Sprintf("cc-%d-%s", n, r.PostForm.Get("scope")),
+				"refresh_token": fmt.Sprintf("refresh-%d", n),
+				"expires_in":    expiresIn,
+			})
+		case "password":
+			if r.PostForm.Get("username") != "hit" ||
+				r.PostForm.Get("password") != "secret" {
+				respond(http.StatusBadRequest, map[string]interface{}{"error": "invalid_grant"})
+				return
+			}
+			respond(http.StatusOK, map[string]interface{}{
+				"access_token": fmt.Sprintf("pw-%d", n),
+			})
+		case "refresh_token":
+			respond(http.StatusOK, map[string]interface{}{
+				"access_token": fmt.Sprintf("refreshed-%d-%s", n,
+					r.PostForm.Get("refresh_token")),
+				"expires_in": expiresIn,
+			})
+		default:
+			respond(http.StatusBadRequest, map[string]interface{}{"error": "unsupported_grant_type"})
+		}
+	})
+	mux.HandleFunc("/api", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, r.Header.Get("Authorization"))
+	})
+	return mux
+}
+
+func (s *tokenServer) received() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := s.grants
+	s.grants = nil
+	return res
+}
+
+func TestOAuth2(t *testing.T) {
+	server := &tokenServer{}
+	util.StartServer(t, "127.0.0.1:40310", server.handler())
+
+	// tokens are cached across runs, every run uses new clients
+	clientID := fmt.Sprintf("client-%d", time.Now().UnixNano())
+	execute := func(t *testing.T, id string) (string, error) {
+		t.Helper()
+		e, err := executor.NewExecutor(&executor.Opts{Cache: c})
+		require.Nil(t, err)
+		require.Nil(t, e.LoadFiles())
+		req, err := e.BuildRequest(id, &executor.RequestOpts{
+			Params: []string{"@" + id, clientID},
+		})
+		require.Nil(t, err)
+		hit, err := e.Execute(context.Background(), id, req)
+		return string(hit.Response.Body), err
+	}
+
+	t.Run("client credentials token is cached", func(t *testing.T) {
+		body, err := execute(t, "client-credentials")
+		require.Nil(t, err)
+		require.Equal(t, "Bearer cc-1-nodes:read nodes:write", body)
+		body, err = execute(t, "client-credentials")
+		require.Nil(t, err)
+		require.Equal(t, "Bearer cc-1-nodes:read nodes:write", body)
+		require.Equal(t, []string{"client_credentials"}, server.received())
+	})
+	t.Run("expiring token is refreshed", func(t *testing.T) {
+		body, err := execute(t, "expiring")
+		require.Nil(t, err)
+		require.Equal(t, "Bearer cc-1-", body)
+		body, err = execute(t, "expiring")
+		require.Nil(t, err)
+		require.Equal(t, "Bearer refreshed-2-refresh-1", body)
+		// the refresh token remains valid
+		body, err = execute(t, "expiring")
+		require.Nil(t, err)
+		require.Equal(t, "Bearer refreshed-3-refresh-1", body)
+		require.Equal(t, []string{"client_credentials", "refresh_token",
+			"refresh_token"}, server.received())
+	})
+	t.Run("password grant", func(t *testing.T) {
+		body, err := execute(t, "password-grant")
+		require.Nil(t, err)
+		require.Equal(t, "Bearer pw-1", body)
+		server.received()
+	})
+	t.Run("token endpoint error fails", func(t *testing.T) {
+		_, err := execute(t, "invalid-client")
+		require.EqualError(t, err, "fetch OAuth2 token: invalid_client: bad secret")
+		server.received()
+	})
+	t.Run("request headers take precedence", func(t *testing.T) {
+		body, err := execute(t, "explicit-header")
+		require.Nil(t, err)
+		require.Equal(t, "Bearer explicit", body)
+		require.Empty(t, server.received())
+	})
+	t.Run("token is masked when printed", func(t *testing.T) {
+		capture := util.NewStdCapture()
+		defer capture.Cleanup()
+		err := cmd.Run(context.Background(), "test-binary-name",
+			"@client-credentials", clientID)
+		capture.Stop()
+		require.Nil(t, err)
+		out := string(capture.Stdout())
+		require.Contains(t, out, "Authorization: Bearer *****")
+		require.Equal(t, 1, strings.Count(out, "cc-1"))
+	})
+}
@@ -0,0 +1,119 @@
+package core
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/hbagdi/hit/pkg/cache"
+	"github.com/hbagdi/hit/pkg/cmd"
+	"github.com/hbagdi/hit/pkg/db"
+	"github.com/hbagdi/hit/pkg/executor"
+	"github.com/hbagdi/hit/pkg/log"
+	"github.com/hbagdi/hit/pkg/model"
+	"github.com/hbagdi/hit/pkg/test/util"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+var c cache.Cache
+
+func init() {
+	store, err := db.NewStore(context.Background(),
+		db.StoreOpts{Logger: log.Logger})
+	if err != nil {
+		panic(fmt.Errorf("init test db: %v", err))
+	}
+	c = cache.GetDBCache(store)
+}
+
+// handler responds with the protocol of the request.
+var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	_, _ = fmt.Fprint(w, r.Proto)
+})
+
+// serverConfig returns a TLS configuration negotiating nextProtos.
+func serverConfig(t *testing.T, nextProtos ...string) *tls.Config {
+	cert, err := tls.LoadX509KeyPair("../tls/testdata/server.pem",
+		"../tls/testdata/server-key.pem")
+	require.Nil(t, err)
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   nextProtos,
+		MinVersion:   tls.VersionTLS12,
+	}
+}
+
+func TestProtocol(t *testing.T) {
+	// the server configures HTTP/2 itself as h2 is negotiated
+	util.StartTLSServer(t, "127.0.0.1:40306",
+		serverConfig(t, "h2", "http/1.1"), handler)
+	util.StartServer(t, "127.0.0.1:40307", h2c.NewHandler(handler, &http2.Server{}))
+	util.StartTLSServer(t, "127.0.0.1:40308", serverConfig(t, "http/1.1"), handler)
+
+	e, err := executor.NewExecutor(&executor.Opts{Cache: c})
+	require.Nil(t, err)
+	require.Nil(t, e.LoadFiles())
+	execute := func(t *testing.T, id string) (model.Hit, error) {
+		t.Helper()
+		req, err := e.BuildRequest(id, nil)
+		require.Nil(t, err)
+		return e.Execute(context.Background(), id, req)
+	}
+
+	for id, proto := range map[string]string{
+		"auto":  "HTTP/2.0",
+		"http1": "HTTP/1.1",
+		"http2": "HTTP/2.0",
+		"h2c":   "HTTP/2.0",
+	} {
+		id, proto := id, proto
+		t.Run(id+" protocol is used", func(t *testing.T) {
+			hit, err := execute(t, id)
+			require.Nil(t, err)
+			require.Equal(t, proto, string(hit.Response.Body))
+			require.Equal(t, proto, hit.Response.Proto)
+			require.Equal(t, proto, hit.Request.Proto)
+		})
+	}
+	t.Run("protocol must match the scheme", func(t *testing.T) {
+		_, err := execute(t, "h2c-over-tls")
+		require.EqualError(t, err, "protocol 'h2c' requires an 'http' URL")
+		_, err = execute(t, "http2-without-tls")
+		require.EqualError(t, err, "protocol 'http2' requires an 'https' URL, "+
+			"use 'h2c' for HTTP/2 without TLS")
+	})
+	t.Run("h2c protocol fails through a proxy", func(t *testing.T) {
+		_, err := execute(t, "h2c-proxied")
+		require.ErrorContains(t, err, "protocol 'h2c' does not support "+
+			"proxies, add '127.0.0.1' to noProxy to connect directly")
+		hit, err := execute(t, "h2c-not-proxied")
+		require.Nil(t, err)
+		require.Equal(t, "HTTP/2.0", hit.Response.Proto)
+		require.Empty(t, hit.Proxy)
+	})
+	t.Run("http2 protocol fails without HTTP/2 support", func(t *testing.T) {
+		_, err := execute(t, "http2-unsupported")
+		require.EqualError(t, err, "server did not negotiate HTTP/2, "+
+			"received HTTP/1.1")
+	})
+	t.Run("invalid protocol fails", func(t *testing.T) {
+		_, err := execute(t, "invalid")
+		require.EqualError(t, err, "invalid protocol 'spdy': only 'auto', "+
+			"'http1', 'http2' or 'h2c' is supported")
+	})
+	t.Run("protocol is set on the command line", func(t *testing.T) {
+		capture := util.NewStdCapture()
+		defer capture.Cleanup()
+		err := cmd.Run(context.Background(), "test-binary-name",
+			"--protocol", "http1", "@auto")
+		capture.Stop()
+		require.Nil(t, err)
+		out := string(capture.Stdout())
+		require.Contains(t, out, "GET /proto HTTP/1.1")
+		require.Contains(t, out, "HTTP/1.1 200 OK")
+	})
+}
@@ -0,0 +1,143 @@
+package core
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// socksServer is a SOCKS5 proxy supporting the CONNECT command with
+// username and password authentication.
+type socksServer struct {
+	username, password string
+
+	mu      sync.Mutex
+	targets []string
+}
+
+func (s *socksServer) connected() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.targets...)
+}
+
+func (s *socksServer) start(t *testing.T, addr string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatalf("listen on %v: %v", addr, err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				if err := s.serve(conn); err != nil {
+					t.Logf("socks: %v", err)
+				}
+			}()
+		}
+	}()
+}
+
+func readBytes(r io.Reader, n int) ([]byte, error) {
+	b := make([]byte, n)
+	_, err := io.ReadFull(r, b)
+	return b, err
+}
+
+func (s *socksServer) serve(conn net.Conn) error {
+	// greeting: version, methods
+	header, err := readBytes(conn, 2)
+	if err != nil {
+		return err
+	}
+	if _, err := readBytes(conn, int(header[1])); err != nil {
+		return err
+	}
+	if _, err := conn.Write([]byte{5, 2}); err != nil {
+		return err
+	}
+
+	// username and password authentication
+	header, err = readBytes(conn, 2)
+	if err != nil {
+		return err
+	}
+	username, err := readBytes(conn, int(header[1]))
+	if err != nil {
+		return err
+	}
+	n, err := readBytes(conn, 1)
+	if err != nil {
+		return err
+	}
+	password, err := readBytes(conn, int(n[0]))
+	if err != nil {
+		return err
+	}
+	if string(username) != s.username || string(password) != s.password {
+		_, _ = conn.Write([]byte{1, 1})
+		return errors.New("authentication failed")
+	}
+	if _, err := conn.Write([]byte{1, 0}); err != nil {
+		return err
+	}
+
+	// request: version, command, reserved, address type, address, port
+	header, err = readBytes(conn, 4)
+	if err != nil {
+		return err
+	}
+	var host string
+	switch header[3] {
+	case 1:
+		ip, err := readBytes(conn, net.IPv4len)
+		if err != nil {
+			return err
+		}
+		host = net.IP(ip).String()
+	case 3:
+		n, err := readBytes(conn, 1)
+		if err != nil {
+			return err
+		}
+		name, err := readBytes(conn, int(n[0]))
+		if err != nil {
+			return err
+		}
+		host = string(name)
+	default:
+		return errors.New("unsupported address type")
+	}
+	port, err := readBytes(conn, 2)
+	if err != nil {
+		return err
+	}
+	target := net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port))))
+	s.mu.Lock()
+	s.targets = append(s.targets, target)
+	s.mu.Unlock()
+
+	upstream, err := net.Dial("tcp", target)
+	if err != nil {
+		return err
+	}
+	defer upstream.Close()
+	if _, err := conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0}); err != nil {
+		return err
+	}
+	go func() {
+		_, _ = io.Copy(upstream, conn)
+	}()
+	_, err = io.Copy(conn, upstream)
+	return err
+}
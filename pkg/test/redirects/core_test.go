@@ -0,0 +1,127 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hbagdi/hit/pkg/cache"
+	"github.com/hbagdi/hit/pkg/cmd"
+	"github.com/hbagdi/hit/pkg/db"
+	"github.com/hbagdi/hit/pkg/executor"
+	"github.com/hbagdi/hit/pkg/log"
+	"github.com/hbagdi/hit/pkg/model"
+	"github.com/hbagdi/hit/pkg/parser"
+	"github.com/hbagdi/hit/pkg/test/util"
+	"github.com/stretchr/testify/require"
+)
+
+var c cache.Cache
+
+func init() {
+	store, err := db.NewStore(context.Background(),
+		db.StoreOpts{Logger: log.Logger})
+	if err != nil {
+		panic(fmt.Errorf("init test db: %v", err))
+	}
+	c = cache.GetDBCache(store)
+}
+
+func handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/redirect/", func(w http.ResponseWriter, r *http.Request) {
+		n, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/redirect/"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		location := fmt.Sprintf("/redirect/%d", n-1)
+		if n == 1 {
+			location = "/final"
+		}
+		http.Redirect(w, r, location, http.StatusFound)
+	})
+	mux.HandleFunc("/final", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"final":true}`))
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	})
+	return mux
+}
+
+func TestRedirectsAndTimeouts(t *testing.T) {
+	util.StartServer(t, "127.0.0.1:40291", handler())
+
+	e, err := executor.NewExecutor(&executor.Opts{Cache: c})
+	require.Nil(t, err)
+	require.Nil(t, e.LoadFiles())
+
+	execute := func(t *testing.T, e *executor.Executor, id string) (model.Hit, error) {
+		t.Helper()
+		req, err := e.BuildRequest(id, nil)
+		require.Nil(t, err)
+		return e.Execute(context.Background(), id, req)
+	}
+
+	t.Run("redirects are not followed by default", func(t *testing.T) {
+		hit, err := execute(t, e, "redirect")
+		require.Nil(t, err)
+		require.Equal(t, http.StatusFound, hit.Response.Code)
+		require.Empty(t, hit.Redirects)
+	})
+	t.Run("redirects are followed and recorded", func(t *testing.T) {
+		hit, err := execute(t, e, "follow")
+		require.Nil(t, err)
+		require.Equal(t, http.StatusOK, hit.Response.Code)
+		require.Equal(t, `{"final":true}`, string(hit.Response.Body))
+		require.Equal(t, []model.Redirect{
+			{
+				URL:      "http://127.0.0.1:40291/redirect/2",
+				Code:     http.StatusFound,
+				Location: "http://127.0.0.1:40291/redirect/1",
+			},
+			{
+				URL:      "http://127.0.0.1:40291/redirect/1",
+				Code:     http.StatusFound,
+				Location: "http://127.0.0.1:40291/final",
+			},
+		}, hit.Redirects)
+	})
+	t.Run("redirects beyond the maximum fail", func(t *testing.T) {
+		_, err := execute(t, e, "follow-max")
+		require.ErrorContains(t, err, "stopped after 1 redirects")
+	})
+	t.Run("request times out", func(t *testing.T) {
+		_, err := execute(t, e, "slow")
+		require.ErrorContains(t, err, "context deadline exceeded")
+	})
+	t.Run("command-line options override request options", func(t *testing.T) {
+		follow := true
+		e, err := executor.NewExecutor(&executor.Opts{
+			Cache:   c,
+			Options: parser.Options{FollowRedirects: &follow},
+		})
+		require.Nil(t, err)
+		require.Nil(t, e.LoadFiles())
+		hit, err := execute(t, e, "redirect")
+		require.Nil(t, err)
+		require.Equal(t, http.StatusOK, hit.Response.Code)
+		require.Len(t, hit.Redirects, 2)
+	})
+	t.Run("timeout flag on the command line", func(t *testing.T) {
+		capture := util.NewStdCapture()
+		defer capture.Cleanup()
+		err := cmd.Run(context.Background(), "test-binary-name",
+			"--timeout", "50ms", "@slow-default")
+		capture.Stop()
+		require.ErrorContains(t, err, "context deadline exceeded")
+	})
+}
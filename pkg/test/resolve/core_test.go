@@ -0,0 +1,97 @@
+package core
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/hbagdi/hit/pkg/cache"
+	"github.com/hbagdi/hit/pkg/cmd"
+	"github.com/hbagdi/hit/pkg/db"
+	"github.com/hbagdi/hit/pkg/executor"
+	"github.com/hbagdi/hit/pkg/log"
+	"github.com/hbagdi/hit/pkg/model"
+	"github.com/hbagdi/hit/pkg/test/util"
+	"github.com/stretchr/testify/require"
+)
+
+var c cache.Cache
+
+func init() {
+	store, err := db.NewStore(context.Background(),
+		db.StoreOpts{Logger: log.Logger})
+	if err != nil {
+		panic(fmt.Errorf("init test db: %v", err))
+	}
+	c = cache.GetDBCache(store)
+}
+
+func TestResolve(t *testing.T) {
+	cert, err := tls.LoadX509KeyPair("../tls/testdata/server.pem",
+		"../tls/testdata/server-key.pem")
+	require.Nil(t, err)
+	util.StartTLSServer(t, "127.0.0.1:40298", &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, `{"host":%q,"sni":%q}`, r.Host, r.TLS.ServerName)
+	}))
+
+	newExecutor := func(t *testing.T, opts executor.Opts) *executor.Executor {
+		t.Helper()
+		opts.Cache = c
+		e, err := executor.NewExecutor(&opts)
+		require.Nil(t, err)
+		require.Nil(t, e.LoadFiles())
+		return e
+	}
+	execute := func(t *testing.T, e *executor.Executor, id string) (model.Hit, error) {
+		t.Helper()
+		req, err := e.BuildRequest(id, nil)
+		require.Nil(t, err)
+		return e.Execute(context.Background(), id, req)
+	}
+
+	t.Run("hosts are resolved by DNS by default", func(t *testing.T) {
+		_, err := execute(t, newExecutor(t, executor.Opts{}), "get")
+		require.ErrorContains(t, err, "hit.test")
+	})
+	t.Run("environment resolves the host and port", func(t *testing.T) {
+		hit, err := execute(t, newExecutor(t, executor.Opts{Environment: "staging"}), "get")
+		require.Nil(t, err)
+		require.Equal(t, `{"host":"hit.test:40298","sni":"hit.test"}`,
+			string(hit.Response.Body))
+		require.Equal(t, "127.0.0.1", hit.Network.IPInUse)
+	})
+	t.Run("request resolves the host", func(t *testing.T) {
+		hit, err := execute(t, newExecutor(t, executor.Opts{}), "host-only")
+		require.Nil(t, err)
+		require.Equal(t, `{"host":"hit.test:40298","sni":"hit.test"}`,
+			string(hit.Response.Body))
+	})
+	t.Run("host header is sent", func(t *testing.T) {
+		hit, err := execute(t, newExecutor(t, executor.Opts{}), "host-header")
+		require.Nil(t, err)
+		require.Equal(t, `{"host":"api.example.com","sni":"hit.test"}`,
+			string(hit.Response.Body))
+	})
+	t.Run("resolve flag on the command line", func(t *testing.T) {
+		capture := util.NewStdCapture()
+		defer capture.Cleanup()
+		err := cmd.Run(context.Background(), "test-binary-name",
+			"--resolve", "hit.test:40298:127.0.0.1", "@get")
+		capture.Stop()
+		require.Nil(t, err)
+		require.Contains(t, string(capture.Stdout()), `"sni": "hit.test"`)
+	})
+	t.Run("invalid resolve flag", func(t *testing.T) {
+		capture := util.NewStdCapture()
+		defer capture.Cleanup()
+		err := cmd.Run(context.Background(), "test-binary-name",
+			"--resolve", "hit.test", "@get")
+		capture.Stop()
+		require.ErrorContains(t, err, "expected 'host:port:address'")
+	})
+}
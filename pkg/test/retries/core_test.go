@@ -0,0 +1,166 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hbagdi/hit/pkg/cache"
+	"github.com/hbagdi/hit/pkg/cmd"
+	"github.com/hbagdi/hit/pkg/db"
+	"github.com/hbagdi/hit/pkg/executor"
+	"github.com/hbagdi/hit/pkg/log"
+	"github.com/hbagdi/hit/pkg/model"
+	"github.com/hbagdi/hit/pkg/test/util"
+	"github.com/stretchr/testify/require"
+)
+
+var c cache.Cache
+
+func init() {
+	store, err := db.NewStore(context.Background(),
+		db.StoreOpts{Logger: log.Logger})
+	if err != nil {
+		panic(fmt.Errorf("init test db: %v", err))
+	}
+	c = cache.GetDBCache(store)
+}
+
+// handler responds based on the number of requests received for a path.
+func handler() http.Handler {
+	var (
+		mu    sync.Mutex
+		count = map[string]int{}
+	)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		count[r.URL.Path]++
+		n := count[r.URL.Path]
+		mu.Unlock()
+
+		switch r.URL.Path {
+		case "/flaky", "/flaky-cli":
+			if n <= 2 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+		case "/unavailable":
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		case "/error":
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		case "/retry-after":
+			if n == 1 {
+				w.Header().Set("Retry-After", "1")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+		case "/slow-once", "/slow-post", "/slow-post-retried":
+			if n == 1 {
+				select {
+				case <-r.Context().Done():
+				case <-time.After(time.Second):
+				}
+				return
+			}
+		}
+		_, _ = fmt.Fprintf(w, `{"attempt":%d}`, n)
+	})
+}
+
+func TestRetries(t *testing.T) {
+	util.StartServer(t, "127.0.0.1:40299", handler())
+
+	e, err := executor.NewExecutor(&executor.Opts{Cache: c})
+	require.Nil(t, err)
+	require.Nil(t, e.LoadFiles())
+
+	execute := func(t *testing.T, id string) (model.Hit, error) {
+		t.Helper()
+		req, err := e.BuildRequest(id, nil)
+		require.Nil(t, err)
+		return e.Execute(context.Background(), id, req)
+	}
+	codes := func(attempts []model.Attempt) []int {
+		var res []int
+		for _, a := range attempts {
+			res = append(res, a.Code)
+		}
+		return res
+	}
+
+	t.Run("retryable responses are retried with backoff", func(t *testing.T) {
+		hit, err := execute(t, "flaky")
+		require.Nil(t, err)
+		require.Equal(t, `{"attempt":3}`, string(hit.Response.Body))
+		require.Equal(t, []int{503, 503, 200}, codes(hit.Attempts))
+		require.GreaterOrEqual(t, hit.Attempts[0].Delay, 5*time.Millisecond)
+		require.LessOrEqual(t, hit.Attempts[0].Delay, 10*time.Millisecond)
+		require.GreaterOrEqual(t, hit.Attempts[1].Delay, 10*time.Millisecond)
+		require.LessOrEqual(t, hit.Attempts[1].Delay, 20*time.Millisecond)
+		require.Zero(t, hit.Attempts[2].Delay)
+	})
+	t.Run("last response is kept once attempts are exhausted", func(t *testing.T) {
+		hit, err := execute(t, "exhausted")
+		require.Nil(t, err)
+		require.Equal(t, http.StatusServiceUnavailable, hit.Response.Code)
+		require.Equal(t, []int{503, 503}, codes(hit.Attempts))
+	})
+	t.Run("other responses are not retried", func(t *testing.T) {
+		hit, err := execute(t, "not-retried")
+		require.Nil(t, err)
+		require.Equal(t, []int{500}, codes(hit.Attempts))
+	})
+	t.Run("Retry-After is honored", func(t *testing.T) {
+		hit, err := execute(t, "retry-after")
+		require.Nil(t, err)
+		require.Equal(t, []int{429, 200}, codes(hit.Attempts))
+		require.Equal(t, time.Second, hit.Attempts[0].Delay)
+	})
+	t.Run("timed out attempts are retried", func(t *testing.T) {
+		hit, err := execute(t, "slow-once")
+		require.Nil(t, err)
+		require.Equal(t, `{"attempt":2}`, string(hit.Response.Body))
+		require.Len(t, hit.Attempts, 2)
+		require.Contains(t, hit.Attempts[0].Error, "context deadline exceeded")
+	})
+	t.Run("timed out requests which are not idempotent are not retried", func(t *testing.T) {
+		_, err := execute(t, "slow-post")
+		require.ErrorContains(t, err, "context deadline exceeded")
+		require.NotContains(t, err.Error(), "attempts")
+
+		hit, err := execute(t, "slow-post-retried")
+		require.Nil(t, err)
+		require.Equal(t, `{"attempt":2}`, string(hit.Response.Body))
+	})
+	t.Run("failures to connect are retried for every method", func(t *testing.T) {
+		_, err := execute(t, "refused-post")
+		require.ErrorContains(t, err, "connection refused")
+		require.ErrorContains(t, err, "(after 3 attempts)")
+	})
+	t.Run("connection errors are retried", func(t *testing.T) {
+		_, err := execute(t, "refused")
+		require.ErrorContains(t, err, "connection refused")
+		require.ErrorContains(t, err, "(after 3 attempts)")
+	})
+	t.Run("retries of connection errors are disabled", func(t *testing.T) {
+		_, err := execute(t, "refused-not-retried")
+		require.ErrorContains(t, err, "connection refused")
+		require.NotContains(t, err.Error(), "attempts")
+	})
+	t.Run("max attempts flag on the command line", func(t *testing.T) {
+		capture := util.NewStdCapture()
+		defer capture.Cleanup()
+		err := cmd.Run(context.Background(), "test-binary-name",
+			"--max-attempts", "2", "@flaky-cli")
+		capture.Stop()
+		require.Nil(t, err)
+		out := string(capture.Stdout())
+		require.Contains(t, out, "attempt 1: 503 in ")
+		require.Contains(t, out, "503 Service Unavailable")
+	})
+}
@@ -0,0 +1,181 @@
+package core
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hbagdi/hit/pkg/cache"
+	"github.com/hbagdi/hit/pkg/cmd"
+	"github.com/hbagdi/hit/pkg/db"
+	"github.com/hbagdi/hit/pkg/executor"
+	"github.com/hbagdi/hit/pkg/log"
+	"github.com/hbagdi/hit/pkg/test/util"
+	"github.com/stretchr/testify/require"
+)
+
+var c cache.Cache
+
+func init() {
+	store, err := db.NewStore(context.Background(),
+		db.StoreOpts{Logger: log.Logger})
+	if err != nil {
+		panic(fmt.Errorf("init test db: %v", err))
+	}
+	c = cache.GetDBCache(store)
+}
+
+const key = "hmac-key"
+
+func hmacHex(message string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(message))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hmac", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		timestamp := r.Header.Get("X-Timestamp")
+		expected := hmacHex(r.Method + "\n" + r.URL.EscapedPath() + "\n" +
+			timestamp + "\n" + string(body))
+		if r.Header.Get("X-Signature") != expected {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = fmt.Fprint(w, timestamp)
+	})
+	mux.HandleFunc("/hmac-custom/items", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodySum := sha256.Sum256(body)
+		message := r.Method + " " + r.Host + r.URL.EscapedPath() + "?" +
+			r.URL.RawQuery + " " + r.Header.Get("X-Date") + " " +
+			hex.EncodeToString(bodySum[:])
+		mac := hmac.New(sha512.New, []byte(key))
+		mac.Write([]byte(message))
+		expected := "HMAC " + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+		if r.Header.Get("Authorization") != expected {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = fmt.Fprint(w, r.Header.Get("X-Date"))
+	})
+	mux.HandleFunc("/aws/items/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, "%v\n%v\n%v", r.Header.Get("Authorization"),
+			r.Header.Get("X-Amz-Date"), r.Header.Get("X-Amz-Security-Token"))
+	})
+	var timestamps []string
+	mux.HandleFunc("/retried", func(w http.ResponseWriter, r *http.Request) {
+		timestamps = append(timestamps, r.Header.Get("X-Timestamp"))
+		if len(timestamps) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = fmt.Fprint(w, strings.Join(timestamps, "\n"))
+	})
+	mux.HandleFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, r.Header.Get("X-Signature"))
+	})
+	return mux
+}
+
+func TestSign(t *testing.T) {
+	util.StartServer(t, "127.0.0.1:40311", handler())
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY")
+	t.Setenv("AWS_SESSION_TOKEN", "session-token")
+	t.Setenv("AWS_REGION", "eu-west-1")
+
+	execute := func(t *testing.T, id string) (int, string, error) {
+		t.Helper()
+		e, err := executor.NewExecutor(&executor.Opts{Cache: c})
+		require.Nil(t, err)
+		require.Nil(t, e.LoadFiles())
+		req, err := e.BuildRequest(id, &executor.RequestOpts{
+			Params: []string{"@" + id, key},
+		})
+		if err != nil {
+			return 0, "", err
+		}
+		hit, err := e.Execute(context.Background(), id, req)
+		require.Nil(t, err)
+		return hit.Response.Code, string(hit.Response.Body), nil
+	}
+
+	t.Run("hmac signature with the default template", func(t *testing.T) {
+		before := time.Now().Unix()
+		code, body, err := execute(t, "hmac")
+		require.Nil(t, err)
+		require.Equal(t, http.StatusOK, code)
+		timestamp, err := strconv.ParseInt(body, 10, 64)
+		require.Nil(t, err)
+		require.GreaterOrEqual(t, timestamp, before)
+	})
+	t.Run("hmac signature with a custom scheme", func(t *testing.T) {
+		code, body, err := execute(t, "hmac-custom")
+		require.Nil(t, err)
+		require.Equal(t, http.StatusOK, code)
+		_, err = time.Parse(time.RFC3339, body)
+		require.Nil(t, err)
+	})
+	t.Run("aws signature with credentials from the environment", func(t *testing.T) {
+		code, body, err := execute(t, "aws")
+		require.Nil(t, err)
+		require.Equal(t, http.StatusOK, code)
+		lines := strings.Split(body, "\n")
+		require.Len(t, lines, 3)
+		date, err := time.Parse("20060102T150405Z", lines[1])
+		require.Nil(t, err)
+		require.Regexp(t, "^AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/"+
+			date.Format("20060102")+"/eu-west-1/execute-api/aws4_request, "+
+			"SignedHeaders=content-type;host;x-amz-date;x-amz-security-token, "+
+			"Signature=[0-9a-f]{64}$", lines[0])
+		require.Equal(t, "session-token", lines[2])
+	})
+	t.Run("every attempt is signed when it is sent", func(t *testing.T) {
+		code, body, err := execute(t, "retried")
+		require.Nil(t, err)
+		require.Equal(t, http.StatusOK, code)
+		timestamps := strings.Split(body, "\n")
+		require.Len(t, timestamps, 2)
+		first, err := strconv.ParseInt(timestamps[0], 10, 64)
+		require.Nil(t, err)
+		second, err := strconv.ParseInt(timestamps[1], 10, 64)
+		require.Nil(t, err)
+		require.Greater(t, second, first)
+	})
+	t.Run("signing is disabled with none", func(t *testing.T) {
+		code, body, err := execute(t, "unsigned")
+		require.Nil(t, err)
+		require.Equal(t, http.StatusOK, code)
+		require.Empty(t, body)
+	})
+	t.Run("invalid algorithm fails", func(t *testing.T) {
+		_, _, err := execute(t, "invalid-algorithm")
+		require.EqualError(t, err, "failed to build request: invalid hmac "+
+			"algorithm 'md5': only 'sha256', 'sha512' or 'sha1' is supported")
+	})
+	t.Run("aws credentials are masked when printed", func(t *testing.T) {
+		capture := util.NewStdCapture()
+		defer capture.Cleanup()
+		err := cmd.Run(context.Background(), "test-binary-name", "@aws")
+		capture.Stop()
+		require.Nil(t, err)
+		out := string(capture.Stdout())
+		out = out[:strings.Index(out, "\nHTTP/1.1 ")]
+		require.Contains(t, out, "Authorization: AWS4-HMAC-SHA256 *****")
+		require.Contains(t, out, "X-Amz-Security-Token: *****")
+		require.NotContains(t, out, "session-token")
+	})
+}
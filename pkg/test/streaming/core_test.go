@@ -0,0 +1,230 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hbagdi/hit/pkg/cache"
+	"github.com/hbagdi/hit/pkg/cmd"
+	"github.com/hbagdi/hit/pkg/db"
+	"github.com/hbagdi/hit/pkg/executor"
+	"github.com/hbagdi/hit/pkg/log"
+	"github.com/hbagdi/hit/pkg/model"
+	"github.com/hbagdi/hit/pkg/test/util"
+	"github.com/stretchr/testify/require"
+)
+
+var c cache.Cache
+
+func init() {
+	store, err := db.NewStore(context.Background(),
+		db.StoreOpts{Logger: log.Logger})
+	if err != nil {
+		panic(fmt.Errorf("init test db: %v", err))
+	}
+	c = cache.GetDBCache(store)
+}
+
+const events = `: stream of nodes
+id: 1
+event: created
+data: {"id":1}
+
+id: 2
+data: first line
+data: second line
+
+event: deleted
+data: {"id":1}
+
+`
+
+// brokenRequests counts the requests of the broken stream.
+var brokenRequests atomic.Int32
+
+func handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.(http.Flusher).Flush()
+		for _, b := range []byte(events) {
+			_, _ = w.Write([]byte{b})
+			if b == '\n' {
+				w.(http.Flusher).Flush()
+				time.Sleep(5 * time.Millisecond)
+			}
+		}
+	})
+	mux.HandleFunc("/infinite", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+		for i := 1; ; i++ {
+			if _, err := fmt.Fprintf(w, "data: %d\n\n", i); err != nil {
+				return
+			}
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(5 * time.Millisecond):
+			}
+		}
+	})
+	mux.HandleFunc("/chunks", func(w http.ResponseWriter, r *http.Request) {
+		for i := 1; i <= 3; i++ {
+			_, _ = fmt.Fprintf(w, "chunk %d\n", i)
+			w.(http.Flusher).Flush()
+			time.Sleep(5 * time.Millisecond)
+		}
+	})
+	mux.HandleFunc("/broken", func(w http.ResponseWriter, r *http.Request) {
+		brokenRequests.Add(1)
+		_, _ = fmt.Fprint(w, "chunk 1\n")
+		w.(http.Flusher).Flush()
+		// the connection is closed before the end of the body
+		panic(http.ErrAbortHandler)
+	})
+	mux.HandleFunc("/slow-headers", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	})
+	return mux
+}
+
+// recorder records streamed responses and calls onEvent with every event.
+type recorder struct {
+	starts  []model.Hit
+	chunks  []string
+	events  []model.Event
+	onEvent func(model.Event)
+}
+
+func (r *recorder) StreamStart(hit model.Hit) error {
+	r.starts = append(r.starts, hit)
+	return nil
+}
+
+func (r *recorder) StreamChunk(chunk []byte) error {
+	r.chunks = append(r.chunks, string(chunk))
+	return nil
+}
+
+func (r *recorder) StreamEvent(event model.Event) error {
+	r.events = append(r.events, event)
+	if r.onEvent != nil {
+		r.onEvent(event)
+	}
+	return nil
+}
+
+func (r *recorder) StreamMessage(model.Message) error {
+	return nil
+}
+
+func TestStreaming(t *testing.T) {
+	util.StartServer(t, "127.0.0.1:40301", handler())
+
+	setup := func(t *testing.T) (*executor.Executor, *recorder) {
+		t.Helper()
+		r := &recorder{}
+		e, err := executor.NewExecutor(&executor.Opts{Cache: c, Stream: r})
+		require.Nil(t, err)
+		require.Nil(t, e.LoadFiles())
+		return e, r
+	}
+	execute := func(ctx context.Context, t *testing.T, e *executor.Executor,
+		id string,
+	) (model.Hit, error) {
+		t.Helper()
+		req, err := e.BuildRequest(id, nil)
+		require.Nil(t, err)
+		return e.Execute(ctx, id, req)
+	}
+
+	t.Run("server-sent events are parsed", func(t *testing.T) {
+		e, r := setup(t)
+		hit, err := execute(context.Background(), t, e, "events")
+		require.Nil(t, err)
+		require.Len(t, r.starts, 1)
+		require.Equal(t, http.StatusOK, r.starts[0].Response.Code)
+		require.Empty(t, r.starts[0].Response.Body)
+		require.Equal(t, []model.Event{
+			{ID: "1", Type: "created", Data: `{"id":1}`},
+			{ID: "2", Data: "first line\nsecond line"},
+			{ID: "2", Type: "deleted", Data: `{"id":1}`},
+		}, r.events)
+		require.Equal(t, events, string(hit.Response.Body))
+	})
+	t.Run("stream is closed after the maximum number of events", func(t *testing.T) {
+		e, r := setup(t)
+		hit, err := execute(context.Background(), t, e, "infinite")
+		require.Nil(t, err)
+		require.Len(t, r.events, 3)
+		require.Equal(t, "data: 1\n\ndata: 2\n\ndata: 3\n\n", string(hit.Response.Body))
+	})
+	t.Run("interrupted stream keeps the captured portion", func(t *testing.T) {
+		e, r := setup(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		r.onEvent = func(event model.Event) {
+			if event.Data == "2" {
+				cancel()
+			}
+		}
+		hit, err := execute(ctx, t, e, "interrupted")
+		require.Nil(t, err)
+		require.Equal(t, "data: 1\n\ndata: 2\n\n", string(hit.Response.Body))
+		require.Greater(t, hit.Latency.Total, time.Duration(0))
+	})
+	t.Run("chunks are streamed", func(t *testing.T) {
+		e, r := setup(t)
+		hit, err := execute(context.Background(), t, e, "chunks")
+		require.Nil(t, err)
+		require.Equal(t, []string{"chunk 1\n", "chunk 2\n", "chunk 3\n"}, r.chunks)
+		require.Equal(t, "chunk 1\nchunk 2\nchunk 3\n", string(hit.Response.Body))
+	})
+	t.Run("timeout applies to the response headers", func(t *testing.T) {
+		e, r := setup(t)
+		_, err := execute(context.Background(), t, e, "slow-headers")
+		require.ErrorContains(t, err, "timeout awaiting response headers")
+		require.Empty(t, r.starts)
+
+		hit, err := execute(context.Background(), t, e, "long-stream")
+		require.Nil(t, err)
+		require.Equal(t, events, string(hit.Response.Body))
+	})
+	t.Run("stream failing partway is not retried", func(t *testing.T) {
+		e, r := setup(t)
+		_, err := execute(context.Background(), t, e, "broken")
+		require.ErrorContains(t, err, "read stream: unexpected EOF")
+		require.NotContains(t, err.Error(), "attempts")
+		require.Len(t, r.starts, 1)
+		require.Equal(t, []string{"chunk 1\n"}, r.chunks)
+		require.Equal(t, int32(1), brokenRequests.Load())
+	})
+	t.Run("streaming is disabled per request", func(t *testing.T) {
+		e, r := setup(t)
+		hit, err := execute(context.Background(), t, e, "buffered")
+		require.Nil(t, err)
+		require.Empty(t, r.starts)
+		require.Equal(t, events, string(hit.Response.Body))
+	})
+	t.Run("events are printed", func(t *testing.T) {
+		capture := util.NewStdCapture()
+		defer capture.Cleanup()
+		err := cmd.Run(context.Background(), "test-binary-name",
+			"--max-events", "2", "@events")
+		capture.Stop()
+		require.Nil(t, err)
+		out := string(capture.Stdout())
+		require.Contains(t, out, "event: created, id: 1\n{\n  \"id\": 1\n}\n\n")
+		require.Contains(t, out, "id: 2\nfirst line\nsecond line\n\n")
+		require.NotContains(t, out, "deleted")
+		require.Contains(t, out, "ttfb: ")
+	})
+}
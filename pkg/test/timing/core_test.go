@@ -0,0 +1,66 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hbagdi/hit/pkg/cache"
+	"github.com/hbagdi/hit/pkg/cmd"
+	"github.com/hbagdi/hit/pkg/db"
+	"github.com/hbagdi/hit/pkg/executor"
+	"github.com/hbagdi/hit/pkg/log"
+	"github.com/hbagdi/hit/pkg/test/util"
+	"github.com/stretchr/testify/require"
+)
+
+var c cache.Cache
+
+func init() {
+	store, err := db.NewStore(context.Background(),
+		db.StoreOpts{Logger: log.Logger})
+	if err != nil {
+		panic(fmt.Errorf("init test db: %v", err))
+	}
+	c = cache.GetDBCache(store)
+}
+
+const serverDelay = 20 * time.Millisecond
+
+func TestTiming(t *testing.T) {
+	util.StartServer(t, "127.0.0.1:40292", http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(serverDelay)
+			_, _ = w.Write([]byte(`{}`))
+		}))
+
+	e, err := executor.NewExecutor(&executor.Opts{Cache: c})
+	require.Nil(t, err)
+	require.Nil(t, e.LoadFiles())
+
+	t.Run("latency and network details are recorded", func(t *testing.T) {
+		req, err := e.BuildRequest("slow", nil)
+		require.Nil(t, err)
+		hit, err := e.Execute(context.Background(), "slow", req)
+		require.Nil(t, err)
+
+		require.Greater(t, hit.Latency.TCPConnection, time.Duration(0))
+		require.Zero(t, hit.Latency.TLSConnection)
+		require.GreaterOrEqual(t, hit.Latency.TTFB, serverDelay)
+		require.GreaterOrEqual(t, hit.Latency.Total, hit.Latency.TTFB)
+		require.Equal(t, "127.0.0.1", hit.Network.IPInUse)
+		require.Equal(t, 40292, hit.Network.PortInUse)
+	})
+	t.Run("timing summary is printed", func(t *testing.T) {
+		capture := util.NewStdCapture()
+		defer capture.Cleanup()
+		err := cmd.Run(context.Background(), "test-binary-name", "@slow")
+		capture.Stop()
+		require.Nil(t, err)
+		out := string(capture.Stdout())
+		require.Contains(t, out, "ttfb: ")
+		require.Contains(t, out, "(127.0.0.1:40292)")
+	})
+}
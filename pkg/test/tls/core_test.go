@@ -0,0 +1,104 @@
+package core
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hbagdi/hit/pkg/cache"
+	"github.com/hbagdi/hit/pkg/cmd"
+	"github.com/hbagdi/hit/pkg/db"
+	"github.com/hbagdi/hit/pkg/executor"
+	"github.com/hbagdi/hit/pkg/log"
+	"github.com/hbagdi/hit/pkg/model"
+	"github.com/hbagdi/hit/pkg/test/util"
+	"github.com/stretchr/testify/require"
+)
+
+var c cache.Cache
+
+func init() {
+	store, err := db.NewStore(context.Background(),
+		db.StoreOpts{Logger: log.Logger})
+	if err != nil {
+		panic(fmt.Errorf("init test db: %v", err))
+	}
+	c = cache.GetDBCache(store)
+}
+
+// serverConfig returns a TLS configuration requiring client certificates
+// signed by the test CA.
+func serverConfig(t *testing.T) *tls.Config {
+	cert, err := tls.LoadX509KeyPair("testdata/server.pem", "testdata/server-key.pem")
+	require.Nil(t, err)
+	ca, err := os.ReadFile("testdata/ca.pem")
+	require.Nil(t, err)
+	pool := x509.NewCertPool()
+	require.True(t, pool.AppendCertsFromPEM(ca))
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+		MinVersion:   tls.VersionTLS12,
+	}
+}
+
+func TestTLS(t *testing.T) {
+	util.StartTLSServer(t, "127.0.0.1:40293", serverConfig(t), http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprintf(w, `{"client":%q}`,
+				r.TLS.PeerCertificates[0].Subject.CommonName)
+		}))
+
+	e, err := executor.NewExecutor(&executor.Opts{Cache: c})
+	require.Nil(t, err)
+	require.Nil(t, e.LoadFiles())
+
+	execute := func(t *testing.T, id string) (model.Hit, error) {
+		t.Helper()
+		req, err := e.BuildRequest(id, nil)
+		require.Nil(t, err)
+		return e.Execute(context.Background(), id, req)
+	}
+
+	t.Run("client certificate is presented to the server", func(t *testing.T) {
+		hit, err := execute(t, "mtls")
+		require.Nil(t, err)
+		require.Equal(t, http.StatusOK, hit.Response.Code)
+		require.Equal(t, `{"client":"hit client"}`, string(hit.Response.Body))
+		require.Equal(t, "TLS 1.3", hit.TLS.Version)
+		require.NotEmpty(t, hit.TLS.CipherSuite)
+		require.Greater(t, hit.Latency.TLSConnection, time.Duration(0))
+	})
+	t.Run("server signed by an unknown authority fails", func(t *testing.T) {
+		_, err := execute(t, "untrusted")
+		require.ErrorContains(t, err, "certificate signed by unknown authority")
+	})
+	t.Run("server name is overridden", func(t *testing.T) {
+		_, err := execute(t, "server-name")
+		require.ErrorContains(t, err, "not other.test")
+	})
+	t.Run("verification is skipped when insecure", func(t *testing.T) {
+		hit, err := execute(t, "insecure")
+		require.Nil(t, err)
+		require.Equal(t, http.StatusOK, hit.Response.Code)
+	})
+	t.Run("invalid minimum version fails", func(t *testing.T) {
+		_, err := execute(t, "invalid-min-version")
+		require.ErrorContains(t, err, "invalid TLS minVersion '2.0'")
+	})
+	t.Run("insecure flag on the command line", func(t *testing.T) {
+		capture := util.NewStdCapture()
+		defer capture.Cleanup()
+		err := cmd.Run(context.Background(), "test-binary-name",
+			"--insecure", "@untrusted")
+		capture.Stop()
+		require.Nil(t, err)
+		require.Contains(t, string(capture.Stdout()), "TLS 1.3, ")
+	})
+}
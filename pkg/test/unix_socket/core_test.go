@@ -0,0 +1,114 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/hbagdi/hit/pkg/cache"
+	"github.com/hbagdi/hit/pkg/cmd"
+	"github.com/hbagdi/hit/pkg/db"
+	"github.com/hbagdi/hit/pkg/executor"
+	"github.com/hbagdi/hit/pkg/log"
+	"github.com/hbagdi/hit/pkg/model"
+	"github.com/hbagdi/hit/pkg/test/util"
+	"github.com/stretchr/testify/require"
+)
+
+const socketPath = "/tmp/hit-test-unix.sock"
+
+var c cache.Cache
+
+func init() {
+	store, err := db.NewStore(context.Background(),
+		db.StoreOpts{Logger: log.Logger})
+	if err != nil {
+		panic(fmt.Errorf("init test db: %v", err))
+	}
+	c = cache.GetDBCache(store)
+}
+
+func startUnixServer(t *testing.T, handler http.Handler) {
+	t.Helper()
+	_ = os.Remove(socketPath)
+	listener, err := net.Listen("unix", socketPath)
+	require.Nil(t, err)
+	server := httptest.NewUnstartedServer(handler)
+	server.Listener.Close()
+	server.Listener = listener
+	server.Start()
+	t.Cleanup(server.Close)
+}
+
+func TestUnixSocket(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.41/info", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/v1.41/info/", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, `{"path":%q,"query":%q,"host":%q}`,
+			r.URL.Path, r.URL.RawQuery, r.Host)
+	})
+	startUnixServer(t, mux)
+
+	execute := func(t *testing.T, e *executor.Executor, id string) (model.Hit, error) {
+		t.Helper()
+		req, err := e.BuildRequest(id, nil)
+		if err != nil {
+			return model.Hit{}, err
+		}
+		return e.Execute(context.Background(), id, req)
+	}
+
+	e, err := executor.NewExecutor(&executor.Opts{Cache: c})
+	require.Nil(t, err)
+	require.Nil(t, e.LoadFiles())
+
+	t.Run("request base URL on a Unix socket", func(t *testing.T) {
+		hit, err := execute(t, e, "info")
+		require.Nil(t, err)
+		require.Equal(t, http.StatusOK, hit.Response.Code)
+		require.Equal(t, `{"path":"/v1.41/info/","query":"","host":"localhost"}`,
+			string(hit.Response.Body))
+		require.Equal(t, "http+unix", hit.Request.Scheme)
+		require.Equal(t, socketPath, hit.Request.Host)
+		require.Equal(t, "localhost", hit.Request.Header.Get("host"))
+		require.Equal(t, []model.Redirect{
+			{
+				URL:      "http+unix://%2Ftmp%2Fhit-test-unix.sock/v1.41/info",
+				Code:     http.StatusMovedPermanently,
+				Location: "http+unix://%2Ftmp%2Fhit-test-unix.sock/v1.41/info/",
+			},
+		}, hit.Redirects)
+	})
+	t.Run("socket path is required", func(t *testing.T) {
+		_, err := execute(t, e, "missing-socket-path")
+		require.ErrorContains(t, err, "expected the percent-encoded path "+
+			"of a Unix socket as the host")
+	})
+	t.Run("environment base URL on a Unix socket", func(t *testing.T) {
+		e, err := executor.NewExecutor(&executor.Opts{
+			Cache:       c,
+			Environment: "unix",
+		})
+		require.Nil(t, err)
+		require.Nil(t, e.LoadFiles())
+		hit, err := execute(t, e, "ping")
+		require.Nil(t, err)
+		require.Equal(t, `{"path":"/_ping","query":"verbose=true","host":"localhost"}`,
+			string(hit.Response.Body))
+	})
+	t.Run("command line", func(t *testing.T) {
+		capture := util.NewStdCapture()
+		defer capture.Cleanup()
+		err := cmd.Run(context.Background(), "test-binary-name",
+			"--env", "unix", "@ping")
+		capture.Stop()
+		require.Nil(t, err)
+		require.Contains(t, string(capture.Stdout()), `"path": "/_ping"`)
+	})
+}
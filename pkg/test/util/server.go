@@ -0,0 +1,44 @@
+package util
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// StartServer starts an HTTP server for handler listening on addr. The
+// server is closed once the test finishes.
+func StartServer(t *testing.T, addr string, handler http.Handler) *httptest.Server {
+	t.Helper()
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatalf("listen on %v: %v", addr, err)
+	}
+	server := httptest.NewUnstartedServer(handler)
+	server.Listener.Close()
+	server.Listener = listener
+	server.Start()
+	t.Cleanup(server.Close)
+	return server
+}
+
+// StartTLSServer starts an HTTPS server for handler listening on addr with
+// config. The server is closed once the test finishes.
+func StartTLSServer(t *testing.T, addr string, config *tls.Config,
+	handler http.Handler,
+) *httptest.Server {
+	t.Helper()
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatalf("listen on %v: %v", addr, err)
+	}
+	server := httptest.NewUnstartedServer(handler)
+	server.Listener.Close()
+	server.Listener = listener
+	server.TLS = config
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
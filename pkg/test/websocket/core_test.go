@@ -0,0 +1,251 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/hbagdi/hit/pkg/cache"
+	"github.com/hbagdi/hit/pkg/cmd"
+	"github.com/hbagdi/hit/pkg/db"
+	"github.com/hbagdi/hit/pkg/executor"
+	"github.com/hbagdi/hit/pkg/log"
+	"github.com/hbagdi/hit/pkg/model"
+	"github.com/hbagdi/hit/pkg/test/util"
+	"github.com/stretchr/testify/require"
+)
+
+var c cache.Cache
+
+func init() {
+	store, err := db.NewStore(context.Background(),
+		db.StoreOpts{Logger: log.Logger})
+	if err != nil {
+		panic(fmt.Errorf("init test db: %v", err))
+	}
+	c = cache.GetDBCache(store)
+}
+
+func handler() http.Handler {
+	var upgrader websocket.Upgrader
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"token":"secret-token"}`))
+	})
+	mux.HandleFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.WriteMessage(messageType, data); err != nil {
+				return
+			}
+		}
+	})
+	mux.HandleFunc("/greet", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_ = conn.WriteMessage(websocket.TextMessage, []byte("hello "+r.UserAgent()))
+		_ = conn.WriteMessage(websocket.BinaryMessage, []byte{0xff, 0x00})
+		_ = conn.WriteMessage(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, "bye"))
+		_, _, _ = conn.ReadMessage()
+	})
+	mux.HandleFunc("/silent", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_ = conn.WriteMessage(websocket.TextMessage, []byte("hello"))
+		// nothing is sent until the client goes away
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+	mux.HandleFunc("/reject", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+	mux.HandleFunc("/ticker", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		// the close frame of the client is read by a reader
+		go func() {
+			for {
+				if _, _, err := conn.NextReader(); err != nil {
+					return
+				}
+			}
+		}()
+		for i := 1; ; i++ {
+			if err := conn.WriteMessage(websocket.TextMessage,
+				[]byte(fmt.Sprint(i))); err != nil {
+				return
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	})
+	return mux
+}
+
+// recorder records the messages of WebSocket sessions and calls onMessage
+// with every message.
+type recorder struct {
+	starts    []model.Hit
+	messages  []model.Message
+	onMessage func(model.Message)
+}
+
+func (r *recorder) StreamStart(hit model.Hit) error {
+	r.starts = append(r.starts, hit)
+	return nil
+}
+
+func (r *recorder) StreamChunk([]byte) error {
+	return nil
+}
+
+func (r *recorder) StreamEvent(model.Event) error {
+	return nil
+}
+
+func (r *recorder) StreamMessage(message model.Message) error {
+	r.messages = append(r.messages, message)
+	if r.onMessage != nil {
+		r.onMessage(message)
+	}
+	return nil
+}
+
+func TestWebSocket(t *testing.T) {
+	util.StartServer(t, "127.0.0.1:40303", handler())
+
+	setup := func(t *testing.T) (*executor.Executor, *recorder) {
+		t.Helper()
+		r := &recorder{}
+		e, err := executor.NewExecutor(&executor.Opts{Cache: c, Stream: r})
+		require.Nil(t, err)
+		require.Nil(t, e.LoadFiles())
+		return e, r
+	}
+	execute := func(ctx context.Context, t *testing.T, e *executor.Executor,
+		id string,
+	) (model.Hit, error) {
+		t.Helper()
+		req, err := e.BuildRequest(id, nil)
+		require.Nil(t, err)
+		return e.Execute(ctx, id, req)
+	}
+	data := func(messages []model.Message) []string {
+		var res []string
+		for _, m := range messages {
+			prefix := "< "
+			if m.Sent {
+				prefix = "> "
+			}
+			res = append(res, prefix+string(m.Data))
+		}
+		return res
+	}
+
+	t.Run("messages of the body are sent with references resolved", func(t *testing.T) {
+		e, r := setup(t)
+		_, err := execute(context.Background(), t, e, "login")
+		require.Nil(t, err)
+
+		hit, err := execute(context.Background(), t, e, "subscribe")
+		require.Nil(t, err)
+		require.Equal(t, model.HitKindWebSocket, hit.Kind)
+		require.Equal(t, http.StatusSwitchingProtocols, hit.Response.Code)
+		require.Len(t, r.starts, 1)
+		require.Empty(t, r.starts[0].Messages)
+		expected := []string{
+			`> {"token":"secret-token","type":"subscribe"}`,
+			`> {"type":"ping"}`,
+			`< {"token":"secret-token","type":"subscribe"}`,
+			`< {"type":"ping"}`,
+		}
+		require.Equal(t, expected, data(hit.Messages))
+		require.ElementsMatch(t, expected, data(r.messages))
+		for _, m := range hit.Messages {
+			require.False(t, m.Time.IsZero())
+		}
+		require.Equal(t, "127.0.0.1", hit.Network.IPInUse)
+	})
+	t.Run("every line of a body is a message", func(t *testing.T) {
+		e, _ := setup(t)
+		hit, err := execute(context.Background(), t, e, "echo-lines")
+		require.Nil(t, err)
+		require.Equal(t, []string{"> hello", "> world", "< hello", "< world"},
+			data(hit.Messages))
+	})
+	t.Run("session ends when the server closes it", func(t *testing.T) {
+		e, _ := setup(t)
+		hit, err := execute(context.Background(), t, e, "greet")
+		require.Nil(t, err)
+		require.Len(t, hit.Messages, 2)
+		require.Regexp(t, "^hello hit/", string(hit.Messages[0].Data))
+		require.False(t, hit.Messages[0].Binary)
+		require.True(t, hit.Messages[1].Binary)
+		require.Equal(t, []byte{0xff, 0x00}, hit.Messages[1].Data)
+	})
+	t.Run("session ends once the server is silent for the timeout", func(t *testing.T) {
+		e, _ := setup(t)
+		start := time.Now()
+		hit, err := execute(context.Background(), t, e, "silent")
+		require.Nil(t, err)
+		require.Equal(t, []string{"< hello"}, data(hit.Messages))
+		require.Less(t, time.Since(start), 5*time.Second)
+	})
+	t.Run("rejected handshake fails", func(t *testing.T) {
+		e, r := setup(t)
+		_, err := execute(context.Background(), t, e, "reject")
+		require.EqualError(t, err,
+			"open WebSocket session: websocket: bad handshake (403 Forbidden)")
+		require.Empty(t, r.starts)
+	})
+	t.Run("interrupted session keeps the received messages", func(t *testing.T) {
+		e, r := setup(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		r.onMessage = func(message model.Message) {
+			if string(message.Data) == "3" {
+				cancel()
+			}
+		}
+		hit, err := execute(ctx, t, e, "ticker")
+		require.Nil(t, err)
+		require.Equal(t, []string{"< 1", "< 2", "< 3"}, data(hit.Messages))
+	})
+	t.Run("messages are printed with timestamps", func(t *testing.T) {
+		capture := util.NewStdCapture()
+		defer capture.Cleanup()
+		err := cmd.Run(context.Background(), "test-binary-name", "@echo-lines")
+		capture.Stop()
+		require.Nil(t, err)
+		out := string(capture.Stdout())
+		require.Contains(t, out, "HTTP/1.1 101 Switching Protocols")
+		require.Regexp(t, regexp.MustCompile(`(?m)^\d\d:\d\d:\d\d\.\d{3} > hello$`), out)
+		require.Regexp(t, regexp.MustCompile(`(?m)^\d\d:\d\d:\d\d\.\d{3} < world$`), out)
+		require.Contains(t, out, "total: ")
+	})
+}